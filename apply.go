@@ -0,0 +1,182 @@
+package dbmigrator
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	ErrForceRequired   error = errors.New("the operation requires the force option")
+	ErrVersionNotFound error = errors.New("version not found in the migration file")
+
+	ErrVersionAlreadyApplied error = errors.New("the version is already applied")
+)
+
+// WithForce allows the operations, which bypass the migration
+// history, eg. re-applying an already applied version.
+func WithForce() EngineOptFunc {
+	return func(e *engine) {
+		e.force = true
+	}
+}
+
+// ApplySpecificVersion runs the commands of the exact version with the given
+// direction, regardless whether it is already applied or not. The migrations
+// table is left untouched, since it is an explicit override. It can only be
+// called, if the engine was created with WithForce.
+func (e *engine) ApplySpecificVersion(version string, dir direction) error {
+	if !e.force {
+		return ErrForceRequired
+	}
+
+	sv := newSemver(version)
+	if sv == nil {
+		return ErrBadVersioning
+	}
+
+	if err := validateDirection(dir); err != nil {
+		return err
+	}
+
+	defer e.startStats()()
+
+	release, err := e.prepare()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	commands, err := e.getCommands()
+	if err != nil {
+		return err
+	}
+
+	versionCommands := filterVersionCommands(commands, sv, dir)
+	if len(versionCommands) == 0 {
+		return ErrVersionNotFound
+	}
+
+	return e.execute(context.Background(), versionCommands, runHooks{}, nil)
+}
+
+// ProcessVersion runs only the commands of the exact version with the direction
+// of the engine, regardless of the versions between the current and the given
+// one. In up direction, the version is recorded as applied, and it fails with
+// ErrVersionAlreadyApplied, if it is not greater than the current version,
+// since the history only stores the last version of each run. With WithForce,
+// such a version is applied again, but it is not recorded, so the current
+// version never moves backwards. In down direction the migration history
+// is left untouched.
+func (e *engine) ProcessVersion(version string) error {
+	sv := newSemver(version)
+	if sv == nil {
+		return ErrBadVersioning
+	}
+
+	dir := e.dir
+	if dir == "" {
+		dir = DirectionUp
+	}
+
+	defer e.startStats()()
+
+	release, err := e.prepare()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	commands, err := e.getCommands()
+	if err != nil {
+		return err
+	}
+
+	versionCommands := filterVersionCommands(commands, sv, dir)
+	if len(versionCommands) == 0 {
+		return ErrVersionNotFound
+	}
+
+	if dir == DirectionDown {
+		return e.execute(context.Background(), versionCommands, runHooks{}, nil)
+	}
+
+	currentVersion, err := e.GetCurrentVersion()
+	if err != nil {
+		return err
+	}
+
+	if currentVersion != nil && !sv.GreaterThan(currentVersion) {
+		if !e.force {
+			return ErrVersionAlreadyApplied
+		}
+
+		return e.execute(context.Background(), versionCommands, runHooks{}, nil)
+	}
+
+	batchId, err := newBatchId()
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+
+	return e.execute(context.Background(), versionCommands, runHooks{}, func() error {
+		description := getVersionDescription(sv, commands)
+
+		return e.repositories.Migrations.Insert(sv.ToString(), description, batchId, time.Since(start).Milliseconds())
+	})
+}
+
+// ApplyMigrations works like Process, but instead of reading the migration
+// file, the commands of the given version are built from the given slices,
+// where each item is a single statement, eg. in tests.
+func (e *engine) ApplyMigrations(version string, up, down []string) error {
+	sv := newSemver(version)
+	if sv == nil {
+		return ErrBadVersioning
+	}
+
+	commands := make([]Command, 0, len(up)+len(down))
+
+	for _, stmts := range []struct {
+		dir     direction
+		queries []string
+	}{
+		{dir: DirectionUp, queries: up},
+		{dir: DirectionDown, queries: down},
+	} {
+		for _, query := range stmts.queries {
+			query = strings.TrimSpace(query)
+			if query == "" {
+				continue
+			}
+
+			cmd, err := newCommand(e.db, query, sv, stmts.dir)
+			if err != nil {
+				return err
+			}
+
+			commands = append(commands, cmd)
+		}
+	}
+
+	return e.process(context.Background(), func() ([]Command, error) {
+		return commands, nil
+	}, e.dir, e.targetVersion, runHooks{})
+}
+
+// filterVersionCommands returns the commands with
+// the exact version and the given direction.
+func filterVersionCommands(commands []Command, version Semver, dir direction) []Command {
+	filtered := make([]Command, 0)
+
+	for _, c := range commands {
+		if c.Semver().Equals(version) && c.GetDirection() == dir {
+			filtered = append(filtered, c)
+		}
+	}
+
+	return filtered
+}
@@ -0,0 +1,267 @@
+package dbmigrator
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/balazskvancz/dbmigrator/models"
+	"github.com/balazskvancz/dbmigrator/repositories"
+)
+
+func TestFilterVersionCommands(t *testing.T) {
+	var (
+		c1 = mustNewCommand(nil, "", newSemver("1.0.0"), DirectionUp)
+		c2 = mustNewCommand(nil, "", newSemver("1.1.0"), DirectionUp)
+		c3 = mustNewCommand(nil, "", newSemver("1.1.0"), DirectionDown)
+		c4 = mustNewCommand(nil, "", newSemver("1.1.0"), DirectionUp)
+	)
+
+	commands := []Command{c1, c2, c3, c4}
+
+	type testCase struct {
+		name     string
+		version  Semver
+		dir      direction
+		expected []Command
+	}
+
+	tt := []testCase{
+		{
+			name:     "returns the up commands of the version",
+			version:  newSemver("1.1.0"),
+			dir:      DirectionUp,
+			expected: []Command{c2, c4},
+		},
+		{
+			name:     "returns the down commands of the version",
+			version:  newSemver("1.1.0"),
+			dir:      DirectionDown,
+			expected: []Command{c3},
+		},
+		{
+			name:     "returns empty slice in case of unknown version",
+			version:  newSemver("2.0.0"),
+			dir:      DirectionUp,
+			expected: []Command{},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			got := filterVersionCommands(commands, tc.version, tc.dir)
+
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected commands: %v; got: %v\n", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestApplySpecificVersionWithoutForce(t *testing.T) {
+	e := &engine{}
+
+	if err := e.ApplySpecificVersion("1.0.0", DirectionUp); !errors.Is(err, ErrForceRequired) {
+		t.Errorf("expected error: %v; got error: %v\n", ErrForceRequired, err)
+	}
+}
+
+func TestProcessVersion(t *testing.T) {
+	type testCase struct {
+		name    string
+		version string
+		dir     direction
+		force   bool
+		latest  *models.Migration
+
+		expectedError    error
+		expectedInserted []string
+	}
+
+	content := `#v1.0.0
+#[UP]
+CREATE TABLE foo (id INT);
+#[DOWN]
+DROP TABLE foo;
+#v1.1.0
+ALTER TABLE foo ADD COLUMN bar INT;
+#v2.0.0
+ALTER TABLE foo ADD COLUMN baz INT;
+#v3.0.0
+ALTER TABLE foo ADD COLUMN qux INT;
+`
+
+	path := filepath.Join(t.TempDir(), "migrations.sql")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("could not write the migration file: %v\n", err)
+	}
+
+	tt := []testCase{
+		{
+			name:          "returns error in case of invalid version",
+			version:       "foo",
+			expectedError: ErrBadVersioning,
+		},
+		{
+			name:          "returns error in case of unknown version",
+			version:       "4.0.0",
+			expectedError: ErrVersionNotFound,
+		},
+		{
+			name:          "returns error, if the version is already applied",
+			version:       "1.0.0",
+			latest:        &models.Migration{Version: "1.0.0"},
+			expectedError: ErrVersionAlreadyApplied,
+		},
+		{
+			name:          "returns error, if the version is lower than the current one",
+			version:       "1.1.0",
+			latest:        &models.Migration{Version: "2.0.0"},
+			expectedError: ErrVersionAlreadyApplied,
+		},
+		{
+			name:             "runs and records the version regardless of the versions between",
+			version:          "2.0.0",
+			latest:           &models.Migration{Version: "1.0.0"},
+			expectedInserted: []string{"2.0.0"},
+		},
+		{
+			name:             "runs and records the version without history",
+			version:          "1.0.0",
+			expectedInserted: []string{"1.0.0"},
+		},
+		{
+			name:             "reapplies the version with force without recording it",
+			version:          "1.0.0",
+			force:            true,
+			latest:           &models.Migration{Version: "1.1.0"},
+			expectedInserted: nil,
+		},
+		{
+			name:    "does not record the version in down direction",
+			version: "1.0.0",
+			dir:     DirectionDown,
+			latest:  &models.Migration{Version: "1.0.0"},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &mockMigrationsRepository{doesExists: true, latest: tc.latest}
+
+			e := &engine{
+				conf:         &Config{MigrationsFilePath: path},
+				db:           &mockDatabase{},
+				dir:          tc.dir,
+				force:        tc.force,
+				repositories: &repositories.Repositories{Migrations: repo},
+			}
+
+			if err := e.ProcessVersion(tc.version); !errors.Is(err, tc.expectedError) {
+				t.Fatalf("expected error: %v; got error: %v\n", tc.expectedError, err)
+			}
+
+			if !reflect.DeepEqual(repo.inserted, tc.expectedInserted) {
+				t.Errorf("expected inserted versions: %v; got: %v\n", tc.expectedInserted, repo.inserted)
+			}
+		})
+	}
+}
+
+func TestProcessVersionAfterMultiVersionRun(t *testing.T) {
+	content := `#v1.0.0
+CREATE TABLE foo (id INT);
+#v2.0.0
+ALTER TABLE foo ADD COLUMN bar INT;
+#v3.0.0
+ALTER TABLE foo ADD COLUMN baz INT;
+`
+
+	path := filepath.Join(t.TempDir(), "migrations.sql")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("could not write the migration file: %v\n", err)
+	}
+
+	repo := &mockMigrationsRepository{doesExists: true}
+
+	e := &engine{
+		conf:         &Config{MigrationsFilePath: path},
+		db:           &mockDatabase{},
+		dir:          DirectionUp,
+		repositories: &repositories.Repositories{Migrations: repo},
+	}
+
+	if err := e.Process(); err != nil {
+		t.Fatalf("expected error: <nil>; got error: %v\n", err)
+	}
+
+	// Only the last version of the run is stored, 2.0.0 is an intermediate step.
+	if !reflect.DeepEqual(repo.inserted, []string{"3.0.0"}) {
+		t.Fatalf("expected inserted versions: %v; got: %v\n", []string{"3.0.0"}, repo.inserted)
+	}
+
+	repo.latest = &models.Migration{Version: "3.0.0"}
+
+	if err := e.ProcessVersion("2.0.0"); !errors.Is(err, ErrVersionAlreadyApplied) {
+		t.Errorf("expected error: %v; got error: %v\n", ErrVersionAlreadyApplied, err)
+	}
+
+	if !reflect.DeepEqual(repo.inserted, []string{"3.0.0"}) {
+		t.Errorf("expected the current version not to move backwards; inserted: %v\n", repo.inserted)
+	}
+}
+
+func TestApplyMigrations(t *testing.T) {
+	type testCase struct {
+		name    string
+		version string
+		latest  *models.Migration
+
+		expectedError    error
+		expectedInserted []string
+	}
+
+	tt := []testCase{
+		{
+			name:          "returns error in case of invalid version",
+			version:       "foo",
+			expectedError: ErrBadVersioning,
+		},
+		{
+			name:          "returns error, if the version is already applied",
+			version:       "1.0.0",
+			latest:        &models.Migration{Version: "1.0.0"},
+			expectedError: ErrNothingToRun,
+		},
+		{
+			name:             "runs and records the pending version",
+			version:          "1.1.0",
+			latest:           &models.Migration{Version: "1.0.0"},
+			expectedInserted: []string{"1.1.0"},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &mockMigrationsRepository{doesExists: true, latest: tc.latest}
+
+			e := &engine{
+				conf:         &Config{},
+				db:           &mockDatabase{},
+				dir:          DirectionUp,
+				repositories: &repositories.Repositories{Migrations: repo},
+			}
+
+			err := e.ApplyMigrations(tc.version, []string{"CREATE TABLE foo (id INT);", " "}, []string{"DROP TABLE foo;"})
+			if !errors.Is(err, tc.expectedError) {
+				t.Fatalf("expected error: %v; got error: %v\n", tc.expectedError, err)
+			}
+
+			if !reflect.DeepEqual(repo.inserted, tc.expectedInserted) {
+				t.Errorf("expected inserted versions: %v; got: %v\n", tc.expectedInserted, repo.inserted)
+			}
+		})
+	}
+}
@@ -0,0 +1,101 @@
+package dbmigrator
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+
+	"github.com/balazskvancz/dbmigrator/models"
+)
+
+var (
+	ErrNoBatch error = errors.New("the latest migration has no batch id")
+)
+
+// newBatchId returns a random (version 4) UUID, which
+// identifies the migrations stored by a single run.
+func newBatchId() (string, error) {
+	b := make([]byte, 16)
+
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+// UndoLastBatch undoes the most recent run: the DOWN commands of every
+// version applied by it run in descending order – or in case of a rollback
+// run, the UP commands of every version rolled back by it –, then the stored
+// records of the run are deleted, so the version stored before it becomes
+// the current one again.
+func (e *engine) UndoLastBatch() error {
+	defer e.startStats()()
+
+	release, err := e.prepare()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	history, err := e.GetHistory()
+	if err != nil {
+		return err
+	}
+
+	if len(history) == 0 {
+		return ErrNothingToRun
+	}
+
+	batchId := history[len(history)-1].BatchId
+	if batchId == "" {
+		return ErrNoBatch
+	}
+
+	currentVersion, err := e.GetCurrentVersion()
+	if err != nil {
+		return err
+	}
+
+	commands, err := e.getCommands()
+	if err != nil {
+		return err
+	}
+
+	target := getVersionBeforeBatch(history, batchId)
+
+	// A rollback run stored a lower version than the one before it,
+	// so undoing it means applying the rolled back versions again.
+	dir := DirectionDown
+	if target.GreaterThan(currentVersion) {
+		dir = DirectionUp
+	}
+
+	// The commands can be empty, eg. if the batch stored the same
+	// version as the previous one, but the records still have to go.
+	undoCommands := selectCommands(currentVersion, commands, dir, target)
+
+	return e.execute(context.Background(), undoCommands, runHooks{}, func() error {
+		return e.repositories.Migrations.DeleteBatch(batchId)
+	})
+}
+
+// getVersionBeforeBatch returns the version of the latest migration,
+// which was stored before the given batch, or bottomVersion.
+func getVersionBeforeBatch(history []*models.Migration, batchId string) Semver {
+	for i := len(history) - 1; i >= 0; i-- {
+		if history[i].BatchId == batchId {
+			continue
+		}
+
+		if sv := newSemver(history[i].Version); sv != nil {
+			return sv
+		}
+	}
+
+	return bottomVersion
+}
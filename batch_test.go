@@ -0,0 +1,196 @@
+package dbmigrator
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/balazskvancz/dbmigrator/database"
+	"github.com/balazskvancz/dbmigrator/models"
+	"github.com/balazskvancz/dbmigrator/repositories"
+)
+
+func TestNewBatchId(t *testing.T) {
+	uuidRegexp := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+	id, err := newBatchId()
+	if err != nil {
+		t.Fatalf("expected error: <nil>; got error: %v\n", err)
+	}
+
+	if !uuidRegexp.MatchString(id) {
+		t.Errorf("expected version 4 uuid; got: %s\n", id)
+	}
+}
+
+func TestGetVersionBeforeBatch(t *testing.T) {
+	type testCase struct {
+		name     string
+		history  []*models.Migration
+		batchId  string
+		expected Semver
+	}
+
+	tt := []testCase{
+		{
+			name: "returns bottomVersion, if there is nothing before the batch",
+			history: []*models.Migration{
+				{Version: "1.0.0", BatchId: "a"},
+			},
+			batchId:  "a",
+			expected: bottomVersion,
+		},
+		{
+			name: "returns the version stored before the batch",
+			history: []*models.Migration{
+				{Version: "1.0.0", BatchId: "a"},
+				{Version: "1.1.0", BatchId: "b"},
+				{Version: "1.3.0", BatchId: "c"},
+			},
+			batchId:  "c",
+			expected: newSemver("1.1.0"),
+		},
+		{
+			name: "returns the version of legacy records without batch id",
+			history: []*models.Migration{
+				{Version: "1.0.0"},
+				{Version: "1.1.0", BatchId: "a"},
+			},
+			batchId:  "a",
+			expected: newSemver("1.0.0"),
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			got := getVersionBeforeBatch(tc.history, tc.batchId)
+
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected version: %v; got: %v\n", tc.expected, got)
+			}
+		})
+	}
+}
+
+type mockRecordingDatabase struct {
+	// The queries fail with the stored error.
+	failures map[string]error
+
+	executed []string
+
+	database.Database
+}
+
+func (md *mockRecordingDatabase) Exec(query string, _ ...any) (sql.Result, error) {
+	md.executed = append(md.executed, query)
+
+	return nil, md.failures[query]
+}
+
+func (md *mockRecordingDatabase) ExecMulti(queries []string) error {
+	for i, query := range queries {
+		if _, err := md.Exec(query); err != nil {
+			return &database.ExecMultiError{Index: i, Err: err}
+		}
+	}
+
+	return nil
+}
+
+func TestUndoLastBatch(t *testing.T) {
+	type testCase struct {
+		name    string
+		history []*models.Migration
+
+		expectedError        error
+		expectedExecuted     []string
+		expectedDeletedBatch string
+	}
+
+	content := `#v1.0.0
+#[UP]
+CREATE TABLE foo (id INT);
+#[DOWN]
+DROP TABLE foo;
+#v2.0.0
+#[UP]
+CREATE TABLE bar (id INT);
+#[DOWN]
+DROP TABLE bar;
+#v3.0.0
+#[UP]
+CREATE TABLE baz (id INT);
+#[DOWN]
+DROP TABLE baz;
+`
+
+	path := filepath.Join(t.TempDir(), "migrations.sql")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("could not write the migration file: %v\n", err)
+	}
+
+	tt := []testCase{
+		{
+			name:          "returns error without history",
+			history:       []*models.Migration{},
+			expectedError: ErrNothingToRun,
+		},
+		{
+			name:          "returns error, if the latest migration has no batch id",
+			history:       []*models.Migration{{Version: "1.0.0"}},
+			expectedError: ErrNoBatch,
+		},
+		{
+			name: "rolls back the versions applied by the batch",
+			history: []*models.Migration{
+				{Version: "1.0.0", BatchId: "a"},
+				{Version: "3.0.0", BatchId: "b"},
+			},
+			expectedExecuted:     []string{"DROP TABLE baz;", "DROP TABLE bar;"},
+			expectedDeletedBatch: "b",
+		},
+		{
+			name: "applies the versions rolled back by the batch",
+			history: []*models.Migration{
+				{Version: "3.0.0", BatchId: "a"},
+				{Version: "1.0.0", BatchId: "b"},
+			},
+			expectedExecuted:     []string{"CREATE TABLE bar (id INT);", "CREATE TABLE baz (id INT);"},
+			expectedDeletedBatch: "b",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &mockMigrationsRepository{doesExists: true, all: tc.history}
+
+			if len(tc.history) > 0 {
+				repo.latest = tc.history[len(tc.history)-1]
+			}
+
+			db := &mockRecordingDatabase{}
+
+			e := &engine{
+				conf:         &Config{MigrationsFilePath: path},
+				db:           db,
+				repositories: &repositories.Repositories{Migrations: repo},
+			}
+
+			if err := e.UndoLastBatch(); !errors.Is(err, tc.expectedError) {
+				t.Fatalf("expected error: %v; got error: %v\n", tc.expectedError, err)
+			}
+
+			if !reflect.DeepEqual(db.executed, tc.expectedExecuted) {
+				t.Errorf("expected executed: %v; got: %v\n", tc.expectedExecuted, db.executed)
+			}
+
+			if repo.deletedBatch != tc.expectedDeletedBatch {
+				t.Errorf("expected deleted batch: %q; got: %q\n", tc.expectedDeletedBatch, repo.deletedBatch)
+			}
+		})
+	}
+}
@@ -1,14 +1,23 @@
 package dbmigrator
 
 import (
+	"errors"
+
 	"github.com/balazskvancz/dbmigrator/database"
 )
 
+var (
+	ErrInvalidDirection error = errors.New("direction must be either `up` or `down`")
+)
+
 type command struct {
-	db      database.Database
-	query   string
-	version Semver
-	dir     direction
+	db          database.Database
+	query       string
+	version     Semver
+	dir         direction
+	description string
+	parallel    bool
+	tags        []string
 }
 
 type Command interface {
@@ -16,22 +25,65 @@ type Command interface {
 	ShouldRun(Semver, direction, Semver) bool
 	Semver() Semver
 	GetDirection() direction
+	GetQuery() string
+	GetDescription() string
+	IsParallel() bool
+	GetTags() []string
+	Clone(...database.Database) Command
 }
 
-func newCommand(db database.Database, query string, semver Semver, dir ...direction) Command {
-	// By default, every commands direction is up.
-	direction := DirectionUp
-
-	if len(dir) == 1 {
-		direction = dir[0]
+// newCommand creates a new command with the given direction,
+// which must be either up or down.
+func newCommand(db database.Database, query string, semver Semver, dir direction) (Command, error) {
+	if err := validateDirection(dir); err != nil {
+		return nil, err
 	}
 
 	return &command{
 		db:      db,
 		query:   query,
 		version: semver,
-		dir:     direction,
+		dir:     dir,
+	}, nil
+}
+
+// newCommandDefault creates a new command with up direction,
+// which is the default direction of every command.
+func newCommandDefault(db database.Database, query string, semver Semver) Command {
+	return &command{
+		db:      db,
+		query:   query,
+		version: semver,
+		dir:     DirectionUp,
+	}
+}
+
+// validateDirection returns ErrInvalidDirection, if the
+// given direction is neither up nor down.
+func validateDirection(d direction) error {
+	if d != DirectionUp && d != DirectionDown {
+		return ErrInvalidDirection
 	}
+
+	return nil
+}
+
+// Clone returns a copy of the command. If a database is given, the copy
+// runs against it, eg. a no-op database in dry-run mode, otherwise
+// it shares the database of the original command. The tags are copied,
+// so they can be modified independently of the original.
+func (c *command) Clone(db ...database.Database) Command {
+	clone := *c
+
+	if c.tags != nil {
+		clone.tags = append(make([]string, 0, len(c.tags)), c.tags...)
+	}
+
+	if len(db) == 1 {
+		clone.db = db[0]
+	}
+
+	return &clone
 }
 
 // Run executes the stored query.
@@ -68,3 +120,64 @@ func (c *command) Semver() Semver { return c.version }
 
 // GetDirection returns the command' direction.
 func (c *command) GetDirection() direction { return c.dir }
+
+// GetQuery returns the command's query.
+func (c *command) GetQuery() string { return c.query }
+
+// GetDescription returns the description of the command's version.
+func (c *command) GetDescription() string { return c.description }
+
+// IsParallel returns whether the command's version can run in parallel.
+func (c *command) IsParallel() bool { return c.parallel }
+
+// GetTags returns the tags of the command's version.
+func (c *command) GetTags() []string { return c.tags }
+
+// Commands represents a list of commands, eg. the result of ParseLines.
+type Commands []Command
+
+// VersionGroup holds the commands of a single version.
+type VersionGroup struct {
+	Version  Semver
+	Commands []Command
+}
+
+// GroupByVersion returns the commands grouped by their versions, keeping
+// the order of the commands within each version. The keys are the results
+// of Semver.ToString.
+func (cs Commands) GroupByVersion() map[string][]Command {
+	groups := make(map[string][]Command)
+
+	for _, c := range cs {
+		key := c.Semver().ToString()
+
+		groups[key] = append(groups[key], c)
+	}
+
+	return groups
+}
+
+// OrderedGroups works like GroupByVersion, but returns the groups
+// in the order of the first appearance of their versions.
+func (cs Commands) OrderedGroups() []VersionGroup {
+	var (
+		groups  = make([]VersionGroup, 0)
+		indexes = make(map[string]int)
+	)
+
+	for _, c := range cs {
+		key := c.Semver().ToString()
+
+		i, ok := indexes[key]
+		if !ok {
+			i = len(groups)
+			indexes[key] = i
+
+			groups = append(groups, VersionGroup{Version: c.Semver()})
+		}
+
+		groups[i].Commands = append(groups[i].Commands, c)
+	}
+
+	return groups
+}
@@ -1,8 +1,10 @@
 package dbmigrator
 
 import (
+	"context"
 	"database/sql"
 	"errors"
+	"reflect"
 	"testing"
 
 	"github.com/balazskvancz/dbmigrator/database"
@@ -18,12 +20,74 @@ func (md *mockDatabase) Exec(query string, _ ...any) (sql.Result, error) {
 	return nil, md.execError
 }
 
+func (md *mockDatabase) ExecContext(_ context.Context, query string, values ...any) (sql.Result, error) {
+	return md.Exec(query, values...)
+}
+
 func newMockDatabase(execError error) database.Database {
 	return &mockDatabase{
 		execError: execError,
 	}
 }
 
+// mustNewCommand is a test helper around newCommand,
+// which panics in case of invalid direction.
+func mustNewCommand(db database.Database, query string, semver Semver, dir direction) Command {
+	c, err := newCommand(db, query, semver, dir)
+	if err != nil {
+		panic(err)
+	}
+
+	return c
+}
+
+func TestNewCommand(t *testing.T) {
+	type testCase struct {
+		name          string
+		dir           direction
+		expectedError error
+	}
+
+	tt := []testCase{
+		{
+			name:          "returns <nil> error in case of up direction",
+			dir:           DirectionUp,
+			expectedError: nil,
+		},
+		{
+			name:          "returns <nil> error in case of down direction",
+			dir:           DirectionDown,
+			expectedError: nil,
+		},
+		{
+			name:          "returns error in case of unknown direction",
+			dir:           "sideways",
+			expectedError: ErrInvalidDirection,
+		},
+		{
+			name:          "returns error in case of missing direction",
+			dir:           "",
+			expectedError: ErrInvalidDirection,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := newCommand(nil, "", newSemver("1.0.0"), tc.dir)
+
+			if !errors.Is(err, tc.expectedError) {
+				t.Errorf("expected error: %v; got error: %v\n", tc.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestNewCommandDefault(t *testing.T) {
+	if dir := newCommandDefault(nil, "", newSemver("1.0.0")).GetDirection(); dir != DirectionUp {
+		t.Errorf("expected direction: %s; got: %s\n", DirectionUp, dir)
+	}
+}
+
 func TestRun(t *testing.T) {
 	type testCase struct {
 		name          string
@@ -75,7 +139,7 @@ func TestShouldRun(t *testing.T) {
 		{
 			name: "the compared version is older = run (up)",
 			c: &command{
-				version: newSemver("2.1.1."),
+				version: newSemver("2.1.1"),
 			},
 			cmp:       newSemver("1.1.1"),
 			dir:       DirectionUp,
@@ -84,7 +148,7 @@ func TestShouldRun(t *testing.T) {
 		{
 			name: "the compared version is older = no run (down)",
 			c: &command{
-				version: newSemver("2.1.1."),
+				version: newSemver("2.1.1"),
 			},
 			cmp:       newSemver("1.1.1"),
 			dir:       DirectionDown,
@@ -93,7 +157,7 @@ func TestShouldRun(t *testing.T) {
 		{
 			name: "the compared version is newer = no run (up)",
 			c: &command{
-				version: newSemver("1.1.1."),
+				version: newSemver("1.1.1"),
 			},
 			cmp:       newSemver("1.2.1"),
 			dir:       DirectionUp,
@@ -102,7 +166,7 @@ func TestShouldRun(t *testing.T) {
 		{
 			name: "the compared version is newer = no run (down)",
 			c: &command{
-				version: newSemver("1.1.1."),
+				version: newSemver("1.1.1"),
 			},
 			cmp:       newSemver("1.2.1"),
 			dir:       DirectionDown,
@@ -112,7 +176,7 @@ func TestShouldRun(t *testing.T) {
 		{
 			name: "the compared version is the same = no run (up)",
 			c: &command{
-				version: newSemver("1.1.1."),
+				version: newSemver("1.1.1"),
 			},
 			cmp:       newSemver("1.1.1"),
 			dir:       DirectionUp,
@@ -121,7 +185,7 @@ func TestShouldRun(t *testing.T) {
 		{
 			name: "the compared version is the same = run (down)",
 			c: &command{
-				version: newSemver("1.1.1."),
+				version: newSemver("1.1.1"),
 			},
 			cmp:       newSemver("1.1.1"),
 			dir:       DirectionDown,
@@ -131,7 +195,7 @@ func TestShouldRun(t *testing.T) {
 		{
 			name: "the compared version is higher than the target = no run (up)",
 			c: &command{
-				version: newSemver("1.0.1."),
+				version: newSemver("1.0.1"),
 			},
 			cmp:       newSemver("1.1.1"),
 			dir:       DirectionUp,
@@ -141,7 +205,7 @@ func TestShouldRun(t *testing.T) {
 		{
 			name: "the compared version is higher than the target = no run (up) 2",
 			c: &command{
-				version: newSemver("1.2.1."),
+				version: newSemver("1.2.1"),
 			},
 			cmp:       newSemver("1.1.1"),
 			dir:       DirectionUp,
@@ -151,7 +215,7 @@ func TestShouldRun(t *testing.T) {
 		{
 			name: "the compared version is lower than the target = run (up)",
 			c: &command{
-				version: newSemver("1.2.1."),
+				version: newSemver("1.2.1"),
 			},
 			cmp:       newSemver("1.1.1"),
 			dir:       DirectionUp,
@@ -161,7 +225,7 @@ func TestShouldRun(t *testing.T) {
 		{
 			name: "the compared version is lower than the target = no run (down)",
 			c: &command{
-				version: newSemver("1.2.1."),
+				version: newSemver("1.2.1"),
 			},
 			cmp:       newSemver("1.4.1"),
 			dir:       DirectionDown,
@@ -171,7 +235,7 @@ func TestShouldRun(t *testing.T) {
 		{
 			name: "the compared version is higher than the target = run (down)",
 			c: &command{
-				version: newSemver("1.2.1."),
+				version: newSemver("1.2.1"),
 			},
 			cmp:       newSemver("1.4.1"),
 			dir:       DirectionDown,
@@ -190,3 +254,67 @@ func TestShouldRun(t *testing.T) {
 		})
 	}
 }
+
+func TestClone(t *testing.T) {
+	var (
+		failingDb = newMockDatabase(errors.New("thrown db error"))
+		noopDb    = newMockDatabase(nil)
+
+		original = &command{
+			db:          failingDb,
+			query:       "SELECT 1;",
+			version:     newSemver("1.0.0"),
+			dir:         DirectionDown,
+			description: "foo",
+			tags:        []string{"service-a"},
+		}
+	)
+
+	clone := original.Clone()
+
+	if !reflect.DeepEqual(clone, Command(original)) || clone == Command(original) {
+		t.Errorf("expected an equal copy of the command; got: %v\n", clone)
+	}
+
+	if err := original.Clone(noopDb).Run(); err != nil {
+		t.Errorf("expected the replaced database to run; got error: %v\n", err)
+	}
+
+	if original.db != failingDb {
+		t.Errorf("expected the original command to keep its database\n")
+	}
+
+	clone.GetTags()[0] = "service-b"
+
+	if tags := original.GetTags(); tags[0] != "service-a" {
+		t.Errorf("expected the original command to keep its tags; got: %v\n", tags)
+	}
+}
+
+func TestGroupByVersion(t *testing.T) {
+	var (
+		c1 = mustNewCommand(nil, "", newSemver("1.1.0"), DirectionUp)
+		c2 = mustNewCommand(nil, "", newSemver("1.0.0"), DirectionUp)
+		c3 = mustNewCommand(nil, "", newSemver("1.1.0"), DirectionDown)
+	)
+
+	commands := Commands{c1, c2, c3}
+
+	expectedMap := map[string][]Command{
+		"1.1.0": {c1, c3},
+		"1.0.0": {c2},
+	}
+
+	if got := commands.GroupByVersion(); !reflect.DeepEqual(got, expectedMap) {
+		t.Errorf("expected groups: %v; got: %v\n", expectedMap, got)
+	}
+
+	expectedGroups := []VersionGroup{
+		{Version: c1.Semver(), Commands: []Command{c1, c3}},
+		{Version: c2.Semver(), Commands: []Command{c2}},
+	}
+
+	if got := commands.OrderedGroups(); !reflect.DeepEqual(got, expectedGroups) {
+		t.Errorf("expected ordered groups: %v; got: %v\n", expectedGroups, got)
+	}
+}
@@ -2,8 +2,11 @@ package dbmigrator
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 )
 
 type Config struct {
@@ -15,7 +18,96 @@ type Config struct {
 	DriverName          string `json:"driverName"`
 	MigrationsTableName string `json:"migrationsTableName"`
 	MigrationsFilePath  string `json:"migrationsFilePath"`
-	WithTransaction     bool   `json:"withTransaction"`
+
+	// WithTransaction makes the commands run inside a transaction. The zero
+	// value means non-transactional. In the environment it is parsed by
+	// strconv.ParseBool, eg. "true" or "1".
+	WithTransaction bool `json:"withTransaction"`
+
+	// MigrationsTableSchema qualifies the migrations table as
+	// schema.table, eg. in PostgreSQL with multiple schemas.
+	MigrationsTableSchema string `json:"migrationsTableSchema"`
+
+	// ConnectTimeout bounds the initial connection attempt. It is given as a
+	// duration string, eg. "5s", in JSON nanoseconds are accepted as well.
+	ConnectTimeout time.Duration `json:"connectTimeout"`
+
+	// PingTimeout bounds the connectivity check after connecting without
+	// ConnectTimeout, which defaults to 5s. It is given the same way as
+	// ConnectTimeout.
+	PingTimeout time.Duration `json:"pingTimeout"`
+
+	// DSNOptions are the driver specific parameters appended to the DSN,
+	// eg. parseTime=true. In the environment: "key=val,key2=val2".
+	DSNOptions map[string]string `json:"dsnOptions"`
+
+	// LogLevel is the verbosity of the built-in loggers, one of
+	// "debug", "info", "warn" or "error". Defaults to "info".
+	LogLevel string `json:"logLevel"`
+
+	// ApplicationName tags the connections for the monitoring tools. It is
+	// sent as application_name in case of postgres, and as the program_name
+	// connection attribute in case of mysql (go-sql-driver/mysql 1.8+).
+	ApplicationName string `json:"applicationName"`
+}
+
+// UnmarshalJSON decodes the config, where the timeouts are given either
+// as duration strings, eg. "5s", or as nanoseconds.
+func (c *Config) UnmarshalJSON(data []byte) error {
+	type plainConfig Config
+
+	aux := struct {
+		*plainConfig
+		ConnectTimeout json.RawMessage `json:"connectTimeout"`
+		PingTimeout    json.RawMessage `json:"pingTimeout"`
+	}{
+		plainConfig: (*plainConfig)(c),
+	}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	connectTimeout, err := parseJsonDuration(aux.ConnectTimeout)
+	if err != nil {
+		return fmt.Errorf("invalid connectTimeout: %w", err)
+	}
+
+	pingTimeout, err := parseJsonDuration(aux.PingTimeout)
+	if err != nil {
+		return fmt.Errorf("invalid pingTimeout: %w", err)
+	}
+
+	c.ConnectTimeout = connectTimeout
+	c.PingTimeout = pingTimeout
+
+	return nil
+}
+
+// parseJsonDuration parses the duration given either as
+// a duration string or as nanoseconds. Without value it returns zero.
+func parseJsonDuration(raw json.RawMessage) (time.Duration, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return 0, nil
+	}
+
+	var str string
+
+	if err := json.Unmarshal(raw, &str); err == nil {
+		if str == "" {
+			return 0, nil
+		}
+
+		return time.ParseDuration(str)
+	}
+
+	var ns int64
+
+	if err := json.Unmarshal(raw, &ns); err != nil {
+		return 0, err
+	}
+
+	return time.Duration(ns), nil
 }
 
 func loadJsonConfig(path string) (*Config, error) {
@@ -34,30 +126,172 @@ func loadJsonConfig(path string) (*Config, error) {
 
 func loadFromEnv() (*Config, error) {
 	var (
-		host                = os.Getenv("HOST")
-		port                = os.Getenv("PORT")
-		database            = os.Getenv("DATABASE")
-		username            = os.Getenv("USERNAME")
-		password            = os.Getenv("PASSWORD")
-		drivername          = os.Getenv("DRIVER_NAME")
-		migrationsTableName = os.Getenv("MIGRATIONS_TABLE_NAME")
-		migrationsFilePath  = os.Getenv("MIGRATIONS_FILE_PATH")
-		withTransactionEnv  = os.Getenv("WITH_TRANSACTION")
+		host                  = os.Getenv("HOST")
+		port                  = os.Getenv("PORT")
+		database              = os.Getenv("DATABASE")
+		username              = os.Getenv("USERNAME")
+		password              = os.Getenv("PASSWORD")
+		drivername            = os.Getenv("DRIVER_NAME")
+		migrationsTableName   = os.Getenv("MIGRATIONS_TABLE_NAME")
+		migrationsTableSchema = os.Getenv("MIGRATIONS_TABLE_SCHEMA")
+		migrationsFilePath    = os.Getenv("MIGRATIONS_FILE_PATH")
+		withTransactionEnv    = os.Getenv("WITH_TRANSACTION")
+		connectTimeoutEnv     = os.Getenv("CONNECT_TIMEOUT")
+		pingTimeoutEnv        = os.Getenv("PING_TIMEOUT")
+		dsnOptionsEnv         = os.Getenv("DSN_OPTIONS")
+		logLevel              = os.Getenv("LOG_LEVEL")
+		applicationName       = os.Getenv("APPLICATION_NAME")
 	)
 
 	cPort, _ := strconv.Atoi(port)
 
-	withTransaction := len(withTransactionEnv) > 0
+	var connectTimeout time.Duration
+
+	if connectTimeoutEnv != "" {
+		d, err := time.ParseDuration(connectTimeoutEnv)
+		if err != nil {
+			return nil, err
+		}
+
+		connectTimeout = d
+	}
+
+	var pingTimeout time.Duration
+
+	if pingTimeoutEnv != "" {
+		d, err := time.ParseDuration(pingTimeoutEnv)
+		if err != nil {
+			return nil, err
+		}
+
+		pingTimeout = d
+	}
+
+	dsnOptions, err := parseDSNOptions(dsnOptionsEnv)
+	if err != nil {
+		return nil, err
+	}
+
+	var withTransaction bool
+
+	if withTransactionEnv != "" {
+		b, err := strconv.ParseBool(withTransactionEnv)
+		if err != nil {
+			return nil, fmt.Errorf("invalid WITH_TRANSACTION: %w", err)
+		}
+
+		withTransaction = b
+	}
 
 	return &Config{
-		Host:                host,
-		Port:                cPort,
-		Database:            database,
-		Username:            username,
-		Password:            password,
-		DriverName:          drivername,
-		MigrationsTableName: migrationsTableName,
-		MigrationsFilePath:  migrationsFilePath,
-		WithTransaction:     withTransaction,
+		Host:                  host,
+		Port:                  cPort,
+		Database:              database,
+		Username:              username,
+		Password:              password,
+		DriverName:            drivername,
+		MigrationsTableName:   migrationsTableName,
+		MigrationsTableSchema: migrationsTableSchema,
+		MigrationsFilePath:    migrationsFilePath,
+		WithTransaction:       withTransaction,
+		ConnectTimeout:        connectTimeout,
+		PingTimeout:           pingTimeout,
+		DSNOptions:            dsnOptions,
+		LogLevel:              logLevel,
+		ApplicationName:       applicationName,
 	}, nil
 }
+
+// parseDSNOptions parses the comma separated key=value pairs.
+func parseDSNOptions(str string) (map[string]string, error) {
+	if str == "" {
+		return nil, nil
+	}
+
+	options := make(map[string]string)
+
+	for _, pair := range strings.Split(str, ",") {
+		key, value, ok := strings.Cut(pair, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("malformed dsn option: %q", pair)
+		}
+
+		options[key] = value
+	}
+
+	return options, nil
+}
+
+// MergeFrom applies every non-zero field of other onto the config.
+// Zero-value fields of other never overwrite the receiver, so
+// eg. WithTransaction can only be switched on by merging.
+func (c *Config) MergeFrom(other *Config) {
+	if other == nil {
+		return
+	}
+
+	if other.Host != "" {
+		c.Host = other.Host
+	}
+
+	if other.Port != 0 {
+		c.Port = other.Port
+	}
+
+	if other.Database != "" {
+		c.Database = other.Database
+	}
+
+	if other.Username != "" {
+		c.Username = other.Username
+	}
+
+	if other.Password != "" {
+		c.Password = other.Password
+	}
+
+	if other.DriverName != "" {
+		c.DriverName = other.DriverName
+	}
+
+	if other.MigrationsTableName != "" {
+		c.MigrationsTableName = other.MigrationsTableName
+	}
+
+	if other.MigrationsTableSchema != "" {
+		c.MigrationsTableSchema = other.MigrationsTableSchema
+	}
+
+	if other.MigrationsFilePath != "" {
+		c.MigrationsFilePath = other.MigrationsFilePath
+	}
+
+	if other.WithTransaction {
+		c.WithTransaction = other.WithTransaction
+	}
+
+	if other.ConnectTimeout != 0 {
+		c.ConnectTimeout = other.ConnectTimeout
+	}
+
+	if other.PingTimeout != 0 {
+		c.PingTimeout = other.PingTimeout
+	}
+
+	if other.LogLevel != "" {
+		c.LogLevel = other.LogLevel
+	}
+
+	if other.ApplicationName != "" {
+		c.ApplicationName = other.ApplicationName
+	}
+
+	// The options are merged key by key.
+	for key, value := range other.DSNOptions {
+		if c.DSNOptions == nil {
+			c.DSNOptions = make(map[string]string)
+		}
+
+		c.DSNOptions[key] = value
+	}
+}
@@ -0,0 +1,249 @@
+package dbmigrator
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestMergeFrom(t *testing.T) {
+	type testCase struct {
+		name     string
+		base     *Config
+		other    *Config
+		expected *Config
+	}
+
+	tt := []testCase{
+		{
+			name:     "nothing changes in case of <nil>",
+			base:     &Config{Host: "localhost", Port: 3306},
+			other:    nil,
+			expected: &Config{Host: "localhost", Port: 3306},
+		},
+		{
+			name: "zero values do not overwrite the base",
+			base: &Config{
+				Host:            "localhost",
+				Port:            3306,
+				Password:        "secret",
+				WithTransaction: true,
+			},
+			other: &Config{},
+			expected: &Config{
+				Host:            "localhost",
+				Port:            3306,
+				Password:        "secret",
+				WithTransaction: true,
+			},
+		},
+		{
+			name: "non-zero values overwrite the base",
+			base: &Config{
+				Host:     "localhost",
+				Port:     3306,
+				Database: "foo",
+				Password: "secret",
+			},
+			other: &Config{
+				Port:            3307,
+				Password:        "top-secret",
+				WithTransaction: true,
+			},
+			expected: &Config{
+				Host:            "localhost",
+				Port:            3307,
+				Database:        "foo",
+				Password:        "top-secret",
+				WithTransaction: true,
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.base.MergeFrom(tc.other)
+
+			if !reflect.DeepEqual(tc.base, tc.expected) {
+				t.Errorf("expected config: %+v; got: %+v\n", tc.expected, tc.base)
+			}
+		})
+	}
+}
+
+func TestLoadFromEnvWithTransaction(t *testing.T) {
+	type testCase struct {
+		name        string
+		value       string
+		expected    bool
+		expectError bool
+	}
+
+	tt := []testCase{
+		{
+			name:     "defaults to non-transactional",
+			value:    "",
+			expected: false,
+		},
+		{
+			name:     "parses true",
+			value:    "true",
+			expected: true,
+		},
+		{
+			name:     "parses false",
+			value:    "false",
+			expected: false,
+		},
+		{
+			name:        "returns error in case of invalid value",
+			value:       "yes please",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("WITH_TRANSACTION", tc.value)
+
+			conf, err := loadFromEnv()
+			if (err != nil) != tc.expectError {
+				t.Fatalf("expected error: %t; got error: %v\n", tc.expectError, err)
+			}
+
+			if err != nil {
+				return
+			}
+
+			if conf.WithTransaction != tc.expected {
+				t.Errorf("expected WithTransaction: %t; got: %t\n", tc.expected, conf.WithTransaction)
+			}
+		})
+	}
+}
+
+func TestLoadFromEnvWithPingTimeout(t *testing.T) {
+	type testCase struct {
+		name        string
+		value       string
+		expected    time.Duration
+		expectError bool
+	}
+
+	tt := []testCase{
+		{
+			name:     "leaves zero without value",
+			value:    "",
+			expected: 0,
+		},
+		{
+			name:     "parses the duration",
+			value:    "2s",
+			expected: 2 * time.Second,
+		},
+		{
+			name:        "returns error in case of invalid value",
+			value:       "soon",
+			expectError: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("PING_TIMEOUT", tc.value)
+
+			conf, err := loadFromEnv()
+			if (err != nil) != tc.expectError {
+				t.Fatalf("expected error: %t; got error: %v\n", tc.expectError, err)
+			}
+
+			if err != nil {
+				return
+			}
+
+			if conf.PingTimeout != tc.expected {
+				t.Errorf("expected PingTimeout: %v; got: %v\n", tc.expected, conf.PingTimeout)
+			}
+		})
+	}
+}
+
+func TestConfigUnmarshalJSON(t *testing.T) {
+	type testCase struct {
+		name                   string
+		input                  string
+		expectedConnectTimeout time.Duration
+		expectedPingTimeout    time.Duration
+		expectError            bool
+	}
+
+	tt := []testCase{
+		{
+			name:  "leaves zero without value",
+			input: `{"host": "localhost"}`,
+		},
+		{
+			name:                   "parses the duration strings",
+			input:                  `{"connectTimeout": "5s", "pingTimeout": "500ms"}`,
+			expectedConnectTimeout: 5 * time.Second,
+			expectedPingTimeout:    500 * time.Millisecond,
+		},
+		{
+			name:                   "parses the nanoseconds",
+			input:                  `{"connectTimeout": 2000000000, "pingTimeout": null}`,
+			expectedConnectTimeout: 2 * time.Second,
+		},
+		{
+			name:        "returns error in case of invalid duration",
+			input:       `{"pingTimeout": "soon"}`,
+			expectError: true,
+		},
+		{
+			name:        "returns error in case of invalid type",
+			input:       `{"connectTimeout": true}`,
+			expectError: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			conf := &Config{}
+
+			err := json.Unmarshal([]byte(tc.input), conf)
+			if (err != nil) != tc.expectError {
+				t.Fatalf("expected error: %t; got error: %v\n", tc.expectError, err)
+			}
+
+			if err != nil {
+				return
+			}
+
+			if conf.ConnectTimeout != tc.expectedConnectTimeout {
+				t.Errorf("expected ConnectTimeout: %v; got: %v\n", tc.expectedConnectTimeout, conf.ConnectTimeout)
+			}
+
+			if conf.PingTimeout != tc.expectedPingTimeout {
+				t.Errorf("expected PingTimeout: %v; got: %v\n", tc.expectedPingTimeout, conf.PingTimeout)
+			}
+		})
+	}
+}
+
+func TestConfigUnmarshalJSONKeepsOtherFields(t *testing.T) {
+	conf := &Config{}
+
+	if err := json.Unmarshal([]byte(`{"host": "localhost", "port": 5432, "dsnOptions": {"sslmode": "disable"}}`), conf); err != nil {
+		t.Fatalf("expected error: <nil>; got error: %v\n", err)
+	}
+
+	expected := &Config{
+		Host:       "localhost",
+		Port:       5432,
+		DSNOptions: map[string]string{"sslmode": "disable"},
+	}
+
+	if !reflect.DeepEqual(conf, expected) {
+		t.Errorf("expected config: %+v; got: %+v\n", expected, conf)
+	}
+}
@@ -3,23 +3,44 @@ package database
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"errors"
 	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
 )
 
 var (
+	// ErrTransactionLostOnReconnect is returned, if the connection dropped
+	// during a transaction. The connection is restored, but the statements
+	// of the transaction are lost, so the run can not go on.
+	ErrTransactionLostOnReconnect error = errors.New("the transaction was lost due to reconnection")
+
 	errTxIsNil error = errors.New("no transaction to commit")
 )
 
 type Database interface {
 	Exec(string, ...any) (sql.Result, error)
+	ExecContext(context.Context, string, ...any) (sql.Result, error)
+	ExecMulti([]string) error
 	Query(string, ...any) (*sql.Rows, error)
 	QueryRow(string, ...any) *sql.Row
 	GetDatabaseName() string
+	GetDriverName() string
 	Connect() error
+	IsConnected() bool
+	Ping() error
 	Close()
+	HealthCheck() (HealthStatus, error)
+	Schema() ([]TableSchema, error)
+	SetConnMaxLifetime(time.Duration)
+	SetConnMaxIdleTime(time.Duration)
 
 	StartTransaction() error
+	StartTransactionContext(context.Context) error
 	Commit() error
 	Rollback() error
 }
@@ -30,10 +51,38 @@ type database struct {
 	ctx  context.Context
 	conf DatabaseConfig
 	tx   *sql.Tx
+
+	// Guards the lazy connection.
+	mu          sync.Mutex
+	lazyConnect bool
+
+	// Held for reading while a statement runs on the pool, so
+	// reconnect closes the replaced pool only after them.
+	poolMu sync.RWMutex
+
+	// The pool settings given before connecting.
+	connMaxLifetime time.Duration
+	connMaxIdleTime time.Duration
+}
+
+type DatabaseOptFunc func(*database)
+
+// WithLazyConnect makes New skip connecting to the database,
+// which is deferred to the first actual query. It makes possible
+// to construct a database, eg. in unit tests, without a live server.
+func WithLazyConnect() DatabaseOptFunc {
+	return func(d *database) {
+		d.lazyConnect = true
+	}
 }
 
 const (
-	defaultDriverName string = "mysql"
+	defaultPingTimeout time.Duration = 5 * time.Second
+
+	defaultDriverName  string = "mysql"
+	mysqlDriverName    string = "mysql"
+	postgresDriverName string = "postgres"
+	pgxDriverName      string = "pgx"
 )
 
 type DatabaseConfig struct {
@@ -43,10 +92,23 @@ type DatabaseConfig struct {
 	Database string
 	Username string
 	Password string
+
+	// ConnectTimeout bounds the connection attempt, if non-zero.
+	ConnectTimeout time.Duration
+
+	// PingTimeout bounds the connectivity check of New without
+	// ConnectTimeout, defaultPingTimeout is used, if it is zero.
+	PingTimeout time.Duration
+
+	// DSNOptions are the driver specific parameters, eg. parseTime=true.
+	DSNOptions map[string]string
+
+	// ApplicationName tags the connections, if non-empty.
+	ApplicationName string
 }
 
 // New returns a new instance of Database based upon the given config.
-func New(ctx context.Context, c DatabaseConfig) (Database, error) {
+func New(ctx context.Context, c DatabaseConfig, opts ...DatabaseOptFunc) (Database, error) {
 	if c.Driver == "" {
 		c.Driver = defaultDriverName
 	}
@@ -56,8 +118,31 @@ func New(ctx context.Context, c DatabaseConfig) (Database, error) {
 		conf: c,
 	}
 
-	if err := db.Connect(); err != nil {
-		return nil, err
+	for _, o := range opts {
+		o(db)
+	}
+
+	if !db.lazyConnect {
+		if err := db.Connect(); err != nil {
+			return nil, err
+		}
+
+		// With connect timeout, Connect has already pinged the database.
+		if c.ConnectTimeout == 0 {
+			if err := db.pingWithTimeout(); err != nil {
+				db.Close()
+
+				return nil, err
+			}
+		}
+	}
+
+	if c.Driver == mysqlDriverName {
+		return &MySQLDatabase{database: db}, nil
+	}
+
+	if c.Driver == postgresDriverName || c.Driver == pgxDriverName {
+		return &PostgresDatabase{database: db}, nil
 	}
 
 	return db, nil
@@ -67,33 +152,284 @@ func New(ctx context.Context, c DatabaseConfig) (Database, error) {
 func (d *database) Connect() error {
 	c := d.conf
 
-	source := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s", c.Username, c.Password, c.Host, c.Port, c.Database)
-
-	sqlDb, err := sql.Open(d.conf.Driver, source)
+	sqlDb, err := sql.Open(d.conf.Driver, buildDSN(c))
 	if err != nil {
 		return err
 	}
 
+	// Without timeout, the connection is opened lazily.
+	if c.ConnectTimeout > 0 {
+		ctx, cancel := context.WithTimeout(d.ctx, c.ConnectTimeout)
+		defer cancel()
+
+		if err := sqlDb.PingContext(ctx); err != nil {
+			sqlDb.Close()
+
+			return err
+		}
+	}
+
+	if d.connMaxLifetime > 0 {
+		sqlDb.SetConnMaxLifetime(d.connMaxLifetime)
+	}
+
+	if d.connMaxIdleTime > 0 {
+		sqlDb.SetConnMaxIdleTime(d.connMaxIdleTime)
+	}
+
 	d.DB = sqlDb
 
 	return nil
 }
 
+// getDB returns the connection pool, after connecting to the database,
+// if it was not done yet due to lazy connection. The pool is replaced
+// by reconnecting, so it must not be read without holding the lock.
+func (d *database) getDB() (*sql.DB, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.DB == nil {
+		if err := d.Connect(); err != nil {
+			return nil, err
+		}
+	}
+
+	return d.DB, nil
+}
+
+// IsConnected returns whether the connection pool is opened.
+func (d *database) IsConnected() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.DB != nil
+}
+
+// buildDSN creates the data source name based upon the given config.
+// The driver specific options are appended as query parameters.
+func buildDSN(c DatabaseConfig) string {
+	params := url.Values{}
+
+	isPostgres := c.Driver == postgresDriverName || c.Driver == pgxDriverName
+
+	if c.ApplicationName != "" {
+		if isPostgres {
+			params.Set("application_name", c.ApplicationName)
+		} else {
+			params.Set("connectionAttributes", "program_name:"+c.ApplicationName)
+		}
+	}
+
+	if c.ConnectTimeout > 0 {
+		if isPostgres {
+			// Postgres expects whole seconds, so it is rounded up
+			// to not turn a sub-second timeout into no timeout.
+			params.Set("connect_timeout", strconv.Itoa(int(math.Ceil(c.ConnectTimeout.Seconds()))))
+		} else {
+			params.Set("timeout", c.ConnectTimeout.String())
+		}
+	}
+
+	// The explicitly given options take precedence.
+	for key, value := range c.DSNOptions {
+		params.Set(key, value)
+	}
+
+	// Both lib/pq and pgx expect an URL, instead of the mysql format.
+	if isPostgres {
+		source := url.URL{
+			Scheme: "postgres",
+			User:   url.UserPassword(c.Username, c.Password),
+			Host:   fmt.Sprintf("%s:%d", c.Host, c.Port),
+			Path:   "/" + c.Database,
+			// Encode sorts the parameters by key, so the result is deterministic.
+			RawQuery: params.Encode(),
+		}
+
+		return source.String()
+	}
+
+	source := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s", c.Username, c.Password, c.Host, c.Port, c.Database)
+
+	if len(params) == 0 {
+		return source
+	}
+
+	// Encode sorts the parameters by key, so the result is deterministic.
+	return source + "?" + params.Encode()
+}
+
 // GetDatabaseName returns the name of the connected database.
 func (d *database) GetDatabaseName() string {
 	return d.conf.Database
 }
 
-// Close closes the database connection.
-func (d *database) Close() { d.DB.Close() }
+// GetDriverName returns the name of the used driver.
+func (d *database) GetDriverName() string {
+	return d.conf.Driver
+}
+
+// Ping verifies, that the database is reachable.
+func (d *database) Ping() error {
+	db, err := d.getDB()
+	if err != nil {
+		return err
+	}
+
+	return db.PingContext(d.ctx)
+}
+
+// pingWithTimeout verifies, that the database responds
+// within the ping timeout given by the config.
+func (d *database) pingWithTimeout() error {
+	timeout := d.conf.PingTimeout
+	if timeout == 0 {
+		timeout = defaultPingTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(d.ctx, timeout)
+	defer cancel()
+
+	return d.DB.PingContext(ctx)
+}
+
+// Close closes the database connection, if it was opened.
+func (d *database) Close() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.DB != nil {
+		d.DB.Close()
+	}
+}
+
+// SetConnMaxLifetime sets the maximum amount of time
+// a connection of the pool may be reused.
+func (d *database) SetConnMaxLifetime(dur time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.connMaxLifetime = dur
+
+	if d.DB != nil {
+		d.DB.SetConnMaxLifetime(dur)
+	}
+}
+
+// SetConnMaxIdleTime sets the maximum amount of time
+// a connection of the pool may be idle.
+func (d *database) SetConnMaxIdleTime(dur time.Duration) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.connMaxIdleTime = dur
+
+	if d.DB != nil {
+		d.DB.SetConnMaxIdleTime(dur)
+	}
+}
 
 // Exec executes the given command with the associated values.
-// It is executed via the opened transaction, if there is any.
+// It works like ExecContext with the background context.
 func (d *database) Exec(query string, values ...any) (sql.Result, error) {
+	return d.ExecContext(context.Background(), query, values...)
+}
+
+// ExecContext executes the given command with the associated values,
+// which is cancelled by the driver, once the context is done.
+// It is executed via the opened transaction, if there is any.
+// Without transaction, database/sql itself retries the command on
+// a new connection, if the driver reports the used one as broken.
+// The transaction is bound to its connection, so if it is dropped,
+// the transaction is released, the pool is reconnected and
+// ErrTransactionLostOnReconnect is returned.
+func (d *database) ExecContext(ctx context.Context, query string, values ...any) (sql.Result, error) {
 	if d.tx != nil {
-		return d.tx.Exec(query, values...)
+		res, err := d.tx.ExecContext(ctx, query, values...)
+		if !isConnectionLost(err) {
+			return res, err
+		}
+
+		// Rolling back returns the broken connection to the pool,
+		// which discards it. It fails, but there is nothing to do.
+		d.tx.Rollback()
+		d.tx = nil
+
+		if err := d.reconnect(); err != nil {
+			return nil, err
+		}
+
+		return nil, ErrTransactionLostOnReconnect
 	}
-	return d.DB.Exec(query, values...)
+
+	d.poolMu.RLock()
+	defer d.poolMu.RUnlock()
+
+	db, err := d.getDB()
+	if err != nil {
+		return nil, err
+	}
+
+	return db.ExecContext(ctx, query, values...)
+}
+
+// isConnectionLost returns whether the error means, that the
+// connection of the statement is dropped or already closed.
+func isConnectionLost(err error) bool {
+	return errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone)
+}
+
+// reconnect replaces the connection pool with a new one. The replaced pool
+// is closed once the statements running on it, eg. by parallel versions,
+// are finished.
+func (d *database) reconnect() error {
+	d.mu.Lock()
+
+	old := d.DB
+
+	if err := d.Connect(); err != nil {
+		d.mu.Unlock()
+
+		return err
+	}
+
+	d.mu.Unlock()
+
+	if old != nil {
+		d.poolMu.Lock()
+		defer d.poolMu.Unlock()
+
+		old.Close()
+	}
+
+	return nil
+}
+
+// ExecMultiError is returned by ExecMulti, if one of the queries failed.
+// The queries before Index were executed successfully.
+type ExecMultiError struct {
+	Index int
+	Err   error
+}
+
+func (e *ExecMultiError) Error() string {
+	return fmt.Sprintf("query %d failed: %v", e.Index, e.Err)
+}
+
+func (e *ExecMultiError) Unwrap() error { return e.Err }
+
+// ExecMulti executes the given queries one after another, and stops at the
+// first failing one with *ExecMultiError. For safety, every query is executed
+// on its own, since not every driver supports multiple statements at once.
+func (d *database) ExecMulti(queries []string) error {
+	for i, query := range queries {
+		if _, err := d.Exec(query); err != nil {
+			return &ExecMultiError{Index: i, Err: err}
+		}
+	}
+
+	return nil
 }
 
 // Query implements query, done via the started opened transaction,
@@ -102,7 +438,13 @@ func (d *database) Query(query string, values ...any) (*sql.Rows, error) {
 	if d.tx != nil {
 		return d.tx.Query(query, values...)
 	}
-	return d.DB.Query(query, values...)
+
+	db, err := d.getDB()
+	if err != nil {
+		return nil, err
+	}
+
+	return db.Query(query, values...)
 }
 
 // QueryRow implements a single row query, done via the started opened transaction,
@@ -111,12 +453,34 @@ func (d *database) QueryRow(query string, values ...any) *sql.Row {
 	if d.tx != nil {
 		return d.tx.QueryRow(query, values...)
 	}
-	return d.DB.QueryRow(query, values...)
+
+	// The error of the connection is reported by Scan. The failed
+	// row does not refer to the pool, so it can be closed right away.
+	db, err := d.getDB()
+	if err != nil {
+		failing := sql.OpenDB(failingConnector{err: err})
+		defer failing.Close()
+
+		return failing.QueryRow(query, values...)
+	}
+
+	return db.QueryRow(query, values...)
 }
 
-// StartTransaction tries to start a transaction on the given database connection.
+// StartTransaction tries to start a transaction on the given database connection,
+// using the context given at construction time.
 func (d *database) StartTransaction() error {
-	tx, err := d.DB.BeginTx(d.ctx, nil)
+	return d.StartTransactionContext(d.ctx)
+}
+
+// StartTransactionContext tries to start a transaction bound to the given context.
+func (d *database) StartTransactionContext(ctx context.Context) error {
+	db, err := d.getDB()
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
@@ -131,7 +495,13 @@ func (d *database) Commit() error {
 	if d.tx == nil {
 		return errTxIsNil
 	}
-	return d.tx.Commit()
+
+	// The transaction is finished either way, so the
+	// next statements must not be executed via it.
+	tx := d.tx
+	d.tx = nil
+
+	return tx.Commit()
 }
 
 // Rollback rolls back all the executed SQL queries in the given transaction.
@@ -139,5 +509,23 @@ func (d *database) Rollback() error {
 	if d.tx == nil {
 		return errTxIsNil
 	}
-	return d.tx.Rollback()
+
+	tx := d.tx
+	d.tx = nil
+
+	return tx.Rollback()
+}
+
+// failingConnector is a connector, which always fails with the given
+// error. It is used to report the connection error via *sql.Row.
+type failingConnector struct {
+	err error
 }
+
+func (c failingConnector) Connect(context.Context) (driver.Conn, error) { return nil, c.err }
+
+func (c failingConnector) Driver() driver.Driver { return failingDriver(c) }
+
+type failingDriver failingConnector
+
+func (d failingDriver) Open(string) (driver.Conn, error) { return nil, d.err }
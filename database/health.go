@@ -0,0 +1,42 @@
+package database
+
+import "time"
+
+// HealthStatus describes the state of the database connection.
+type HealthStatus struct {
+	Connected         bool
+	LatencyMs         int64
+	ServerVersion     string
+	TransactionActive bool
+}
+
+// HealthCheck executes a lightweight query to measure the latency,
+// then queries the version of the server. The returned status is
+// filled as far as it was possible, even in case of error.
+func (d *database) HealthCheck() (HealthStatus, error) {
+	status := HealthStatus{
+		TransactionActive: d.tx != nil,
+	}
+
+	db, err := d.getDB()
+	if err != nil {
+		return status, err
+	}
+
+	start := time.Now()
+
+	var one int
+
+	if err := db.QueryRowContext(d.ctx, "SELECT 1").Scan(&one); err != nil {
+		return status, err
+	}
+
+	status.Connected = true
+	status.LatencyMs = time.Since(start).Milliseconds()
+
+	if err := db.QueryRowContext(d.ctx, "SELECT version()").Scan(&status.ServerVersion); err != nil {
+		return status, err
+	}
+
+	return status, nil
+}
@@ -0,0 +1,122 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"testing"
+)
+
+// healthDriver is a driver, which answers the queries of the health check.
+type healthDriver struct{}
+
+type healthConn struct{}
+
+// valueRows is a single row with a single column.
+type valueRows struct {
+	value driver.Value
+	done  bool
+}
+
+func (healthDriver) Open(string) (driver.Conn, error) { return healthConn{}, nil }
+
+func (healthConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+
+func (healthConn) Close() error { return nil }
+
+func (c healthConn) Begin() (driver.Tx, error) { return c, nil }
+
+func (healthConn) Commit() error { return nil }
+
+func (healthConn) Rollback() error { return nil }
+
+func (healthConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	switch query {
+	case "SELECT 1":
+		return &valueRows{value: int64(1)}, nil
+	case "SELECT version()":
+		return &valueRows{value: "8.0.36"}, nil
+	}
+
+	return nil, errors.New("unexpected query: " + query)
+}
+
+func (r *valueRows) Columns() []string { return []string{"value"} }
+
+func (r *valueRows) Close() error { return nil }
+
+func (r *valueRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+
+	dest[0] = r.value
+	r.done = true
+
+	return nil
+}
+
+func init() {
+	sql.Register("dbmigrator-health", healthDriver{})
+}
+
+func TestHealthCheck(t *testing.T) {
+	type testCase struct {
+		name            string
+		driver          string
+		withTransaction bool
+		expected        HealthStatus
+		expectError     bool
+	}
+
+	tt := []testCase{
+		{
+			name:     "returns the status of the connected database",
+			driver:   "dbmigrator-health",
+			expected: HealthStatus{Connected: true, ServerVersion: "8.0.36"},
+		},
+		{
+			name:            "reports the active transaction",
+			driver:          "dbmigrator-health",
+			withTransaction: true,
+			expected:        HealthStatus{Connected: true, ServerVersion: "8.0.36", TransactionActive: true},
+		},
+		{
+			name:        "returns error in case of unavailable database",
+			driver:      "unregistered",
+			expected:    HealthStatus{Connected: false},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			db, err := New(context.Background(), DatabaseConfig{Driver: tc.driver}, WithLazyConnect())
+			if err != nil {
+				t.Fatalf("expected error: <nil>; got error: %v\n", err)
+			}
+			defer db.Close()
+
+			if tc.withTransaction {
+				if err := db.StartTransaction(); err != nil {
+					t.Fatalf("expected error: <nil>; got error: %v\n", err)
+				}
+				defer db.Rollback()
+			}
+
+			status, err := db.HealthCheck()
+			if (err != nil) != tc.expectError {
+				t.Fatalf("expected health check error: %v; got error: %v\n", tc.expectError, err)
+			}
+
+			// The latency depends on the machine, so only the rest is compared.
+			status.LatencyMs = 0
+
+			if status != tc.expected {
+				t.Errorf("expected status: %+v; got: %+v\n", tc.expected, status)
+			}
+		})
+	}
+}
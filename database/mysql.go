@@ -0,0 +1,119 @@
+package database
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"time"
+)
+
+// The longest name accepted by GET_LOCK since MySQL 5.7.
+const maxLockNameLength int = 64
+
+var (
+	ErrLockNotAcquired error = errors.New("could not acquire the migration lock")
+	errLockNotHeld     error = errors.New("migration lock is not held")
+)
+
+// Locker is implemented by the databases, which
+// support locking the migration process.
+type Locker interface {
+	Lock(string, time.Duration) error
+	Unlock(string) error
+}
+
+// MySQLDatabase is the MySQL specific database, which supports
+// advisory locking via GET_LOCK and RELEASE_LOCK.
+type MySQLDatabase struct {
+	*database
+
+	// Named locks belong to the session, so the lock
+	// must be acquired and released on the same connection.
+	lockConn *sql.Conn
+}
+
+var (
+	_ Database = (*MySQLDatabase)(nil)
+	_ Locker   = (*MySQLDatabase)(nil)
+)
+
+// Lock tries to acquire the named lock, waiting at most for the given timeout.
+// The timeout is rounded up to whole seconds, the name is shortened, if it is
+// longer than the allowed 64 characters.
+func (d *MySQLDatabase) Lock(name string, timeout time.Duration) error {
+	db, err := d.getDB()
+	if err != nil {
+		return err
+	}
+
+	conn, err := db.Conn(d.ctx)
+	if err != nil {
+		return err
+	}
+
+	var acquired sql.NullInt64
+
+	row := conn.QueryRowContext(d.ctx, "SELECT GET_LOCK(?, ?)", getMySQLLockName(name), getLockTimeoutSeconds(timeout))
+	if err := row.Scan(&acquired); err != nil {
+		conn.Close()
+
+		return err
+	}
+
+	// 0 means, that someone else holds the lock,
+	// NULL means, that an error occurred.
+	if !acquired.Valid || acquired.Int64 != 1 {
+		conn.Close()
+
+		return ErrLockNotAcquired
+	}
+
+	d.lockConn = conn
+
+	return nil
+}
+
+// Unlock releases the named lock and the connection holding it.
+func (d *MySQLDatabase) Unlock(name string) error {
+	if d.lockConn == nil {
+		return errLockNotHeld
+	}
+
+	defer func() {
+		d.lockConn.Close()
+		d.lockConn = nil
+	}()
+
+	_, err := d.lockConn.ExecContext(d.ctx, "SELECT RELEASE_LOCK(?)", getMySQLLockName(name))
+
+	return err
+}
+
+// getMySQLLockName returns the given name, if it is accepted by GET_LOCK,
+// otherwise its beginning followed by the FNV-1a hash of the whole name,
+// so the different long names remain different.
+func getMySQLLockName(name string) string {
+	if len(name) <= maxLockNameLength {
+		return name
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(name))
+
+	hash := fmt.Sprintf(".%016x", h.Sum64())
+
+	return name[:maxLockNameLength-len(hash)] + hash
+}
+
+// getLockTimeoutSeconds returns the timeout in whole seconds, rounded up,
+// since a sub-second timeout must not turn into not waiting at all.
+// The negative timeout is 0, since GET_LOCK would wait forever.
+func getLockTimeoutSeconds(timeout time.Duration) int {
+	if timeout <= 0 {
+		return 0
+	}
+
+	return int(math.Ceil(timeout.Seconds()))
+}
@@ -0,0 +1,146 @@
+package database
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"time"
+)
+
+// The SQLSTATE of exceeding the lock_timeout.
+const lockNotAvailableState string = "55P03"
+
+// PostgresDatabase is the PostgreSQL specific database, which supports
+// advisory locking via pg_advisory_lock and pg_advisory_unlock.
+type PostgresDatabase struct {
+	*database
+
+	// Advisory locks belong to the session, so the lock
+	// must be acquired and released on the same connection.
+	lockConn *sql.Conn
+}
+
+var (
+	_ Database = (*PostgresDatabase)(nil)
+	_ Locker   = (*PostgresDatabase)(nil)
+)
+
+// Lock acquires the advisory lock, whose key is derived from the given name.
+// It waits at most for the given timeout, which is set as the lock_timeout
+// of the session. Without timeout it does not wait at all.
+func (d *PostgresDatabase) Lock(name string, timeout time.Duration) error {
+	db, err := d.getDB()
+	if err != nil {
+		return err
+	}
+
+	conn, err := db.Conn(d.ctx)
+	if err != nil {
+		return err
+	}
+
+	key := getAdvisoryLockKey(name)
+
+	if timeout <= 0 {
+		var acquired bool
+
+		if err := conn.QueryRowContext(d.ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&acquired); err != nil {
+			conn.Close()
+
+			return err
+		}
+
+		if !acquired {
+			conn.Close()
+
+			return ErrLockNotAcquired
+		}
+
+		d.lockConn = conn
+
+		return nil
+	}
+
+	// SET does not support placeholders. The timeout is rounded up,
+	// since 0 would mean waiting without limit.
+	timeoutMs := (timeout + time.Millisecond - 1) / time.Millisecond
+
+	if _, err := conn.ExecContext(d.ctx, fmt.Sprintf("SET lock_timeout = %d", timeoutMs)); err != nil {
+		conn.Close()
+
+		return err
+	}
+
+	_, lockErr := conn.ExecContext(d.ctx, "SELECT pg_advisory_lock($1)", key)
+
+	// The setting belongs to the session, so it must not be
+	// left on the connection, which goes back to the pool.
+	if _, err := conn.ExecContext(d.ctx, "RESET lock_timeout"); err != nil {
+		// Discarding the connection ends the session, which
+		// releases the lock as well, if it was acquired.
+		discardConn(conn)
+
+		return err
+	}
+
+	if lockErr != nil {
+		conn.Close()
+
+		if isLockTimeoutError(lockErr) {
+			return ErrLockNotAcquired
+		}
+
+		return lockErr
+	}
+
+	d.lockConn = conn
+
+	return nil
+}
+
+// Unlock releases the advisory lock and the connection holding it.
+func (d *PostgresDatabase) Unlock(name string) error {
+	if d.lockConn == nil {
+		return errLockNotHeld
+	}
+
+	defer func() {
+		d.lockConn.Close()
+		d.lockConn = nil
+	}()
+
+	_, err := d.lockConn.ExecContext(d.ctx, "SELECT pg_advisory_unlock($1)", getAdvisoryLockKey(name))
+
+	return err
+}
+
+// isLockTimeoutError returns whether the error is caused by exceeding the
+// lock_timeout, ie. SQLSTATE 55P03. Both lib/pq and pgx expose the code
+// by SQLState, but older lib/pq versions only mention it in the message.
+func isLockTimeoutError(err error) bool {
+	var stateErr interface{ SQLState() string }
+
+	if errors.As(err, &stateErr) {
+		return stateErr.SQLState() == lockNotAvailableState
+	}
+
+	return strings.Contains(err.Error(), "lock timeout")
+}
+
+// discardConn closes the connection, so it is not reused by the pool.
+func discardConn(conn *sql.Conn) {
+	conn.Raw(func(any) error { return driver.ErrBadConn })
+	conn.Close()
+}
+
+// getAdvisoryLockKey returns the FNV-1a hash of the given
+// name, since advisory locks are identified by a bigint.
+func getAdvisoryLockKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+
+	return int64(h.Sum64())
+}
@@ -0,0 +1,279 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// advisoryLockDriver is a driver, which emulates the advisory
+// locks and the lock_timeout setting of the postgres sessions.
+type advisoryLockDriver struct {
+	mu         sync.Mutex
+	held       map[int64]*advisoryLockConn
+	conns      []*advisoryLockConn
+	statements []string
+}
+
+type advisoryLockConn struct {
+	d           *advisoryLockDriver
+	lockTimeout string
+}
+
+// lockTimeoutError mimics the error of the drivers,
+// when the lock_timeout of the session is exceeded.
+type lockTimeoutError struct{}
+
+func (lockTimeoutError) Error() string { return "canceling statement due to lock timeout" }
+
+func (lockTimeoutError) SQLState() string { return lockNotAvailableState }
+
+func (d *advisoryLockDriver) Open(string) (driver.Conn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	c := &advisoryLockConn{d: d}
+	d.conns = append(d.conns, c)
+
+	return c, nil
+}
+
+func (d *advisoryLockDriver) reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.held = make(map[int64]*advisoryLockConn)
+	d.conns = nil
+	d.statements = nil
+}
+
+func (c *advisoryLockConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+
+// Close ends the session, which releases its locks.
+func (c *advisoryLockConn) Close() error {
+	c.d.mu.Lock()
+	defer c.d.mu.Unlock()
+
+	for key, holder := range c.d.held {
+		if holder == c {
+			delete(c.d.held, key)
+		}
+	}
+
+	return nil
+}
+
+func (c *advisoryLockConn) Begin() (driver.Tx, error) { return nil, errors.New("not supported") }
+
+func (c *advisoryLockConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.d.mu.Lock()
+	defer c.d.mu.Unlock()
+
+	c.d.statements = append(c.d.statements, query)
+
+	switch {
+	case strings.HasPrefix(query, "SET lock_timeout = "):
+		c.lockTimeout = strings.TrimPrefix(query, "SET lock_timeout = ")
+	case query == "RESET lock_timeout":
+		c.lockTimeout = ""
+	case query == "SELECT pg_advisory_lock($1)":
+		key := args[0].Value.(int64)
+
+		if holder, ok := c.d.held[key]; ok && holder != c {
+			if c.lockTimeout == "" {
+				return nil, errors.New("would wait without limit")
+			}
+
+			return nil, lockTimeoutError{}
+		}
+
+		c.d.held[key] = c
+	case query == "SELECT pg_advisory_unlock($1)":
+		delete(c.d.held, args[0].Value.(int64))
+	default:
+		return nil, errors.New("unexpected statement: " + query)
+	}
+
+	return driver.RowsAffected(0), nil
+}
+
+func (c *advisoryLockConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.d.mu.Lock()
+	defer c.d.mu.Unlock()
+
+	c.d.statements = append(c.d.statements, query)
+
+	if query != "SELECT pg_try_advisory_lock($1)" {
+		return nil, errors.New("unexpected query: " + query)
+	}
+
+	key := args[0].Value.(int64)
+
+	if holder, ok := c.d.held[key]; ok && holder != c {
+		return &boolRows{value: false}, nil
+	}
+
+	c.d.held[key] = c
+
+	return &boolRows{value: true}, nil
+}
+
+// boolRows is a single row with a single boolean column.
+type boolRows struct {
+	value bool
+	done  bool
+}
+
+func (r *boolRows) Columns() []string { return []string{"acquired"} }
+
+func (r *boolRows) Close() error { return nil }
+
+func (r *boolRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+
+	dest[0] = r.value
+	r.done = true
+
+	return nil
+}
+
+var advisoryLock = &advisoryLockDriver{}
+
+func init() {
+	sql.Register("dbmigrator-advisorylock", advisoryLock)
+}
+
+func newAdvisoryLockDatabase(t *testing.T) *PostgresDatabase {
+	t.Helper()
+
+	db, err := New(context.Background(), DatabaseConfig{Driver: "dbmigrator-advisorylock"})
+	if err != nil {
+		t.Fatalf("expected error: <nil>; got error: %v\n", err)
+	}
+
+	t.Cleanup(db.Close)
+
+	return &PostgresDatabase{database: db.(*database)}
+}
+
+func TestPostgresLock(t *testing.T) {
+	type testCase struct {
+		name          string
+		heldByOther   bool
+		timeout       time.Duration
+		expectedQuery string
+		expectedError error
+	}
+
+	tt := []testCase{
+		{
+			name:          "acquires the free lock with the lock timeout",
+			heldByOther:   false,
+			timeout:       100 * time.Millisecond,
+			expectedQuery: "SET lock_timeout = 100",
+			expectedError: nil,
+		},
+		{
+			name:          "returns error, if the lock is held longer than the timeout",
+			heldByOther:   true,
+			timeout:       100 * time.Millisecond,
+			expectedQuery: "SELECT pg_advisory_lock($1)",
+			expectedError: ErrLockNotAcquired,
+		},
+		{
+			name:          "rounds up the sub-millisecond timeout",
+			heldByOther:   true,
+			timeout:       500 * time.Microsecond,
+			expectedQuery: "SET lock_timeout = 1",
+			expectedError: ErrLockNotAcquired,
+		},
+		{
+			name:          "does not wait without timeout",
+			heldByOther:   true,
+			timeout:       0,
+			expectedQuery: "SELECT pg_try_advisory_lock($1)",
+			expectedError: ErrLockNotAcquired,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			advisoryLock.reset()
+
+			if tc.heldByOther {
+				other := newAdvisoryLockDatabase(t)
+
+				if err := other.Lock("foo", 0); err != nil {
+					t.Fatalf("expected error: <nil>; got error: %v\n", err)
+				}
+				defer other.Unlock("foo")
+			}
+
+			db := newAdvisoryLockDatabase(t)
+
+			err := db.Lock("foo", tc.timeout)
+			if !errors.Is(err, tc.expectedError) {
+				t.Fatalf("expected error: %v; got error: %v\n", tc.expectedError, err)
+			}
+
+			advisoryLock.mu.Lock()
+			defer advisoryLock.mu.Unlock()
+
+			found := false
+
+			for _, s := range advisoryLock.statements {
+				if s == tc.expectedQuery {
+					found = true
+				}
+			}
+
+			if !found {
+				t.Errorf("expected statement: %s; got: %v\n", tc.expectedQuery, advisoryLock.statements)
+			}
+
+			// No connection may carry the lock timeout into the later statements.
+			for _, c := range advisoryLock.conns {
+				if c.lockTimeout != "" {
+					t.Errorf("expected the lock timeout to be reset; got: %s\n", c.lockTimeout)
+				}
+			}
+		})
+	}
+}
+
+func TestPostgresUnlock(t *testing.T) {
+	advisoryLock.reset()
+
+	first, second := newAdvisoryLockDatabase(t), newAdvisoryLockDatabase(t)
+
+	if err := first.Unlock("foo"); !errors.Is(err, errLockNotHeld) {
+		t.Errorf("expected error: %v; got error: %v\n", errLockNotHeld, err)
+	}
+
+	if err := first.Lock("foo", time.Second); err != nil {
+		t.Fatalf("expected error: <nil>; got error: %v\n", err)
+	}
+
+	if err := second.Lock("foo", 10*time.Millisecond); !errors.Is(err, ErrLockNotAcquired) {
+		t.Fatalf("expected error: %v; got error: %v\n", ErrLockNotAcquired, err)
+	}
+
+	if err := first.Unlock("foo"); err != nil {
+		t.Fatalf("expected error: <nil>; got error: %v\n", err)
+	}
+
+	// The lock is free again, so the other session can acquire it.
+	if err := second.Lock("foo", 10*time.Millisecond); err != nil {
+		t.Errorf("expected error: <nil>; got error: %v\n", err)
+	}
+}
@@ -0,0 +1,79 @@
+package database
+
+// TableSchema describes a table of the database with its columns.
+type TableSchema struct {
+	Name    string
+	Columns []ColumnSchema
+}
+
+// ColumnSchema describes a column of a table. Nullable
+// is either "YES" or "NO", as in INFORMATION_SCHEMA.
+type ColumnSchema struct {
+	Name     string
+	Type     string
+	Nullable string
+}
+
+// Schema returns the tables of the current database with their
+// columns, both in the order of INFORMATION_SCHEMA.COLUMNS.
+// In case of postgres, only the current schema is returned.
+func (d *database) Schema() ([]TableSchema, error) {
+	query := `
+		SELECT
+			TABLE_NAME,
+			COLUMN_NAME,
+			DATA_TYPE,
+			IS_NULLABLE
+		FROM INFORMATION_SCHEMA.COLUMNS
+		WHERE TABLE_SCHEMA = ?
+		ORDER BY TABLE_NAME, ORDINAL_POSITION
+	`
+
+	if d.conf.Driver == "postgres" || d.conf.Driver == "pgx" {
+		query = `
+			SELECT
+				table_name,
+				column_name,
+				data_type,
+				is_nullable
+			FROM information_schema.columns
+			WHERE table_catalog = $1
+			AND table_schema = current_schema()
+			ORDER BY table_name, ordinal_position
+		`
+	}
+
+	rows, err := d.Query(query, d.conf.Database)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tables := make([]TableSchema, 0)
+
+	for rows.Next() {
+		var (
+			table  string
+			column ColumnSchema
+		)
+
+		if err := rows.Scan(&table, &column.Name, &column.Type, &column.Nullable); err != nil {
+			return nil, err
+		}
+
+		// The rows are ordered by the table, so a new
+		// table starts, whenever the name changes.
+		if len(tables) == 0 || tables[len(tables)-1].Name != table {
+			tables = append(tables, TableSchema{Name: table})
+		}
+
+		last := &tables[len(tables)-1]
+		last.Columns = append(last.Columns, column)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tables, nil
+}
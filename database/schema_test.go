@@ -0,0 +1,92 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+// schemaDriver is a driver, whose queries return the stored columns.
+type schemaDriver struct{}
+
+type schemaConn struct{}
+
+type schemaRows struct {
+	values [][]driver.Value
+}
+
+var schemaColumns = [][]driver.Value{
+	{"bar", "id", "int", "NO"},
+	{"foo", "id", "int", "NO"},
+	{"foo", "name", "varchar", "YES"},
+}
+
+func (schemaDriver) Open(string) (driver.Conn, error) { return &schemaConn{}, nil }
+
+func (c *schemaConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+
+func (c *schemaConn) Close() error { return nil }
+
+func (c *schemaConn) Begin() (driver.Tx, error) { return nil, errors.New("not supported") }
+
+func (c *schemaConn) QueryContext(context.Context, string, []driver.NamedValue) (driver.Rows, error) {
+	return &schemaRows{values: schemaColumns}, nil
+}
+
+func (r *schemaRows) Columns() []string {
+	return []string{"TABLE_NAME", "COLUMN_NAME", "DATA_TYPE", "IS_NULLABLE"}
+}
+
+func (r *schemaRows) Close() error { return nil }
+
+func (r *schemaRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+
+	return nil
+}
+
+func init() {
+	sql.Register("dbmigrator-schema", schemaDriver{})
+}
+
+func TestSchema(t *testing.T) {
+	db, err := New(context.Background(), DatabaseConfig{Driver: "dbmigrator-schema"})
+	if err != nil {
+		t.Fatalf("expected error: <nil>; got error: %v\n", err)
+	}
+	defer db.Close()
+
+	got, err := db.Schema()
+	if err != nil {
+		t.Fatalf("expected error: <nil>; got error: %v\n", err)
+	}
+
+	expected := []TableSchema{
+		{
+			Name: "bar",
+			Columns: []ColumnSchema{
+				{Name: "id", Type: "int", Nullable: "NO"},
+			},
+		},
+		{
+			Name: "foo",
+			Columns: []ColumnSchema{
+				{Name: "id", Type: "int", Nullable: "NO"},
+				{Name: "name", Type: "varchar", Nullable: "YES"},
+			},
+		},
+	}
+
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected schema: %v; got: %v\n", expected, got)
+	}
+}
@@ -0,0 +1,48 @@
+package dbmigrator
+
+// MigrationPreview is a command, which would run by the next Migrate.
+type MigrationPreview struct {
+	Version     string    `json:"version"`
+	Direction   direction `json:"direction"`
+	SQL         string    `json:"sql"`
+	Description string    `json:"description"`
+}
+
+// DryRunWithOutput returns the commands, which would run by the next
+// Migrate in execution order, without executing or logging anything.
+// The direction and the target version are given by the options of the engine.
+func (e *engine) DryRunWithOutput() ([]MigrationPreview, error) {
+	currentVersion, err := e.GetCurrentVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	if currentVersion == nil {
+		currentVersion = bottomVersion
+	}
+
+	commands, err := e.getCommands()
+	if err != nil {
+		return nil, err
+	}
+
+	dir := resolveDirection(currentVersion, e.dir, e.targetVersion)
+
+	return newMigrationPreviews(selectCommands(currentVersion, commands, dir, e.targetVersion)), nil
+}
+
+// newMigrationPreviews creates the previews of the given commands.
+func newMigrationPreviews(commands []Command) []MigrationPreview {
+	previews := make([]MigrationPreview, 0, len(commands))
+
+	for _, c := range commands {
+		previews = append(previews, MigrationPreview{
+			Version:     c.Semver().ToString(),
+			Direction:   c.GetDirection(),
+			SQL:         c.GetQuery(),
+			Description: c.GetDescription(),
+		})
+	}
+
+	return previews
+}
@@ -0,0 +1,90 @@
+package dbmigrator
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/balazskvancz/dbmigrator/models"
+	"github.com/balazskvancz/dbmigrator/repositories"
+)
+
+func TestDryRunWithOutput(t *testing.T) {
+	type testCase struct {
+		name          string
+		latest        *models.Migration
+		dir           direction
+		targetVersion Semver
+
+		expected []MigrationPreview
+	}
+
+	content := `#v1.0.0
+#[DESC] creation of foo
+#[UP]
+CREATE TABLE foo (id INT);
+#[DOWN]
+DROP TABLE foo;
+#v1.1.0
+#[UP]
+ALTER TABLE foo ADD COLUMN bar INT;
+#[DOWN]
+ALTER TABLE foo DROP COLUMN bar;
+`
+
+	path := filepath.Join(t.TempDir(), "migrations.sql")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("could not write the migration file: %v\n", err)
+	}
+
+	tt := []testCase{
+		{
+			name:   "returns every up command without history",
+			latest: nil,
+			dir:    DirectionUp,
+			expected: []MigrationPreview{
+				{Version: "1.0.0", Direction: DirectionUp, SQL: "CREATE TABLE foo (id INT);", Description: "creation of foo"},
+				{Version: "1.1.0", Direction: DirectionUp, SQL: "ALTER TABLE foo ADD COLUMN bar INT;"},
+			},
+		},
+		{
+			name:     "returns empty slice, if everything is applied",
+			latest:   &models.Migration{Version: "1.1.0"},
+			dir:      DirectionUp,
+			expected: []MigrationPreview{},
+		},
+		{
+			name:          "returns the down commands in descending order in case of lower target",
+			latest:        &models.Migration{Version: "1.1.0"},
+			dir:           DirectionUp,
+			targetVersion: bottomVersion,
+			expected: []MigrationPreview{
+				{Version: "1.1.0", Direction: DirectionDown, SQL: "ALTER TABLE foo DROP COLUMN bar;"},
+				{Version: "1.0.0", Direction: DirectionDown, SQL: "DROP TABLE foo;", Description: "creation of foo"},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			e := &engine{
+				conf:          &Config{MigrationsFilePath: path},
+				dir:           tc.dir,
+				targetVersion: tc.targetVersion,
+				repositories: &repositories.Repositories{
+					Migrations: &mockMigrationsRepository{doesExists: true, latest: tc.latest},
+				},
+			}
+
+			got, err := e.DryRunWithOutput()
+			if err != nil {
+				t.Fatalf("expected error: <nil>; got error: %v\n", err)
+			}
+
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected previews: %v; got: %v\n", tc.expected, got)
+			}
+		})
+	}
+}
@@ -1,14 +1,18 @@
 package dbmigrator
 
 import (
-	"bufio"
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	"os"
+	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/balazskvancz/dbmigrator/database"
+	"github.com/balazskvancz/dbmigrator/models"
 	"github.com/balazskvancz/dbmigrator/repositories"
 )
 
@@ -22,6 +26,13 @@ const (
 
 	upCommand   string = "#[UP]"
 	downCommand string = "#[DOWN]"
+	descCommand string = "#[DESC]"
+
+	envCommandPrefix string = "#[ENV"
+	envCommandSuffix string = "]"
+	envSeparator     string = ","
+
+	environmentEnvKey string = "MIGRATOR_ENV"
 
 	DirectionUp   direction = "up"
 	DirectionDown direction = "down"
@@ -30,39 +41,170 @@ const (
 var (
 	ErrBadVersioning      error = errors.New("versions must follow `#vX.X.X` format")
 	ErrConfigIsNil        error = errors.New("given config is <nil>")
+	ErrDatabaseIsNil      error = errors.New("given database is <nil>")
 	ErrInvalidLastVersion error = errors.New("invalid latest stored version")
 	ErrNoFilePath         error = errors.New("missing migrations file path")
 	ErrNothingToRun       error = errors.New("no command to run")
+	ErrLockNotSupported   error = errors.New("the database does not support locking")
+	ErrDatabaseReadOnly   error = errors.New("the database is in read-only mode")
+	ErrUnsupportedDriver  error = errors.New("the operation is not supported by the driver")
+
+	ErrMigrationsTableNotExists error = errors.New("the migrations table does not exist")
+
+	// ErrLockNotAcquired is returned, if another process holds the migration lock.
+	ErrLockNotAcquired error = database.ErrLockNotAcquired
+
+	// ErrTransactionLostOnReconnect is returned, if the connection
+	// dropped during a transaction, so its statements were lost.
+	ErrTransactionLostOnReconnect error = database.ErrTransactionLostOnReconnect
 )
 
 // Basic semver, which holds the minimum version.
 var bottomVersion Semver = newSemver("0.0.0")
 
+// ErrorAction tells the engine how to proceed after a failed command.
+type ErrorAction int
+
+const (
+	// ErrorActionContinue skips the failed command and runs the next one.
+	ErrorActionContinue ErrorAction = iota
+	// ErrorActionStop stops the execution and returns the error.
+	ErrorActionStop
+	// ErrorActionRetry runs the failed command again.
+	ErrorActionRetry
+)
+
+// ErrorHandlerFunc decides what should happen after the given command failed.
+type ErrorHandlerFunc func(Command, error) ErrorAction
+
 type Logger interface {
 	Info(string)
 	Error(string)
 }
 
 type engine struct {
-	logger Logger
+	loggerMu sync.RWMutex
+	logger   Logger
 
 	conf          *Config
+	dbConf        database.DatabaseConfig
 	repositories  *repositories.Repositories
 	db            database.Database
 	dir           direction
 	targetVersion Semver
+
+	databaseName        string
+	migrationsTableName string
+	versionValidator    func(Semver) error
+	onError             ErrorHandlerFunc
+
+	withLock    bool
+	lockTimeout time.Duration
+
+	postgresAdvisoryLock bool
+
+	migrationTimeout time.Duration
+
+	allowSnapshots bool
+
+	withReadonlyCheck bool
+
+	environment string
+
+	withSchemaDump bool
+	force          bool
+
+	parallelVersions int
+
+	captureVersionComments bool
+
+	statsMu sync.Mutex
+	stats   EngineStats
+
+	beforeProcess []func() error
+	afterProcess  []func(error)
+
+	lazyConnect bool
+
+	strictMode bool
+
+	// The custom markers of the migration file, empty means the default.
+	versionPrefix string
+	upMarker      string
+	downMarker    string
+
+	tagFilter []string
+
+	s3Client S3GetterAPI
+
+	commandLogLevel string
+
+	allowFresh bool
+
+	testDatabasePrefix string
 }
 
 type EngineOptFunc func(*engine)
 
 type Engine interface {
 	SetupDatabase() error
-	GetLines() ([]string, error)
-	ParseLines([]string) ([]Command, error)
+	GetLines() (MigrationLines, error)
+	GetLinesContext(context.Context) (MigrationLines, error)
+	ParseLines(MigrationLines) ([]Command, error)
+	ParseYAMLLines([]byte) ([]Command, error)
 	CloseDatabase()
+	SetLogger(Logger)
+	Migrate(context.Context) error
 	Process() error
+	ProcessWithReader(io.Reader) error
 	ProcessWithDirection(direction) error
 	ProcessWithTargetVersion(string) error
+	ProcessUpgrade() error
+	ProcessDowngrade(int) error
+	ProcessUntilError(bool) ([]MigrationResult, error)
+	ProcessFresh() error
+	TestMigration(context.Context) error
+	ProcessVersion(string) error
+	GetMigrationFile() (*MigrationFile, error)
+	ProcessWithMigrationFile(*MigrationFile) error
+	GetUpgradePath(string, string) ([]Semver, error)
+	ApplyMigrations(string, []string, []string) error
+	GetMigrationTableName() string
+	MigrateAndClose(context.Context) error
+	Snapshot() ([]byte, error)
+	RestoreSnapshot([]byte) error
+	ProcessWithCallback(func(string, direction, error)) error
+	ExecuteCommands([]Command) error
+	GetRecentHistory(int) ([]*models.Migration, error)
+	GetMigrationFileStats() (MigrationFileStats, error)
+	GetCommandCount() (int, error)
+	GetHistory() ([]*models.Migration, error)
+	GetAppliedAt(string) (*time.Time, error)
+	GetAppliedVersions() ([]Semver, error)
+	ListAppliedVersions() ([]string, error)
+	SquashVersions(string, string, string) error
+	GetCurrentVersion() (Semver, error)
+	Verify() error
+	Rollback(int) error
+	RollbackTo(string) error
+	UndoLastBatch() error
+	GetFileVersion() (Semver, error)
+	DumpSchema(io.Writer) error
+	ClearMigrationHistory() error
+	ForceMigrationsTableRecreate() error
+	ListVersions() ([]Semver, error)
+	GetCommandsForVersion(string) ([]Command, error)
+	GetPendingVersionCount() (int, error)
+	GetNextVersion() (Semver, error)
+	EstimateMigrationTime() (time.Duration, error)
+	ApplySpecificVersion(string, direction) error
+	DatabaseHealth() (database.HealthStatus, error)
+	ValidateConnection() error
+	WatchAndMigrate(context.Context, time.Duration) error
+	ExportToJSON() ([]byte, error)
+	DryRunWithOutput() ([]MigrationPreview, error)
+	Explain() string
+	Stats() EngineStats
 }
 
 var (
@@ -93,6 +235,153 @@ func WithTargetVersion(v string) EngineOptFunc {
 	}
 }
 
+// WithDatabaseName overrides the database name of the config,
+// so the same config can be used for multiple databases.
+func WithDatabaseName(name string) EngineOptFunc {
+	return func(e *engine) {
+		e.databaseName = name
+	}
+}
+
+// WithMigrationsTableName overrides the migrations table name of the config.
+func WithMigrationsTableName(name string) EngineOptFunc {
+	return func(e *engine) {
+		e.migrationsTableName = name
+	}
+}
+
+// WithEnvironment sets the current environment, which decides which
+// `#[ENV name]` blocks are included. It overrides MIGRATOR_ENV.
+func WithEnvironment(env string) EngineOptFunc {
+	return func(e *engine) {
+		e.environment = env
+	}
+}
+
+// WithVersionValidator registers a custom validator, which is called
+// for every version marker read during parsing.
+func WithVersionValidator(fn func(Semver) error) EngineOptFunc {
+	return func(e *engine) {
+		e.versionValidator = fn
+	}
+}
+
+// WithOnError registers the given handler, which is called whenever
+// a command fails. The handler is called again after each failed retry,
+// so it is up to the handler to limit the number of retries.
+func WithOnError(fn ErrorHandlerFunc) EngineOptFunc {
+	return func(e *engine) {
+		e.onError = fn
+	}
+}
+
+// WithLock makes the engine acquire a database level lock before processing,
+// so only one migrator can run at a time. It waits at most for the given
+// timeout, if the lock is held by someone else.
+func WithLock(timeout time.Duration) EngineOptFunc {
+	return func(e *engine) {
+		e.withLock = true
+		e.lockTimeout = timeout
+	}
+}
+
+// WithPostgresAdvisoryLock works like WithLock, but the lock is a PostgreSQL
+// advisory lock, whose key is derived from the migrations table name. The
+// given milliseconds are set as the lock_timeout of the locking session.
+func WithPostgresAdvisoryLock(lockTimeoutMs int) EngineOptFunc {
+	return func(e *engine) {
+		e.withLock = true
+		e.lockTimeout = time.Duration(lockTimeoutMs) * time.Millisecond
+		e.postgresAdvisoryLock = true
+	}
+}
+
+// WithReadonlyCheck makes the engine refuse processing,
+// if the database is a read-only replica.
+func WithReadonlyCheck() EngineOptFunc {
+	return func(e *engine) {
+		e.withReadonlyCheck = true
+	}
+}
+
+// WithCaptureVersionComments makes the parser use the block comment
+// right before a version marker as the description of the version.
+func WithCaptureVersionComments() EngineOptFunc {
+	return func(e *engine) {
+		e.captureVersionComments = true
+	}
+}
+
+// WithBeforeProcess registers a function, which is called at the start
+// of every run, before acquiring the lock. If it returns an error,
+// the run is aborted. Multiple functions are called in registration order.
+func WithBeforeProcess(fn func() error) EngineOptFunc {
+	return func(e *engine) {
+		e.beforeProcess = append(e.beforeProcess, fn)
+	}
+}
+
+// WithAfterProcess registers a function, which is called at the end of
+// every run with its result, regardless of success or failure.
+// Multiple functions are called in registration order.
+func WithAfterProcess(fn func(err error)) EngineOptFunc {
+	return func(e *engine) {
+		e.afterProcess = append(e.afterProcess, fn)
+	}
+}
+
+// WithLazyConnect makes New skip connecting to the database, which
+// is deferred to the first query. Useful eg. in unit tests.
+func WithLazyConnect() EngineOptFunc {
+	return func(e *engine) {
+		e.lazyConnect = true
+	}
+}
+
+// WithTransaction makes the engine run the commands inside
+// a transaction, regardless of the given config.
+func WithTransaction() EngineOptFunc {
+	return func(e *engine) {
+		e.conf.WithTransaction = true
+	}
+}
+
+// WithTransactionMode is the same as WithTransaction: it sets
+// Config.WithTransaction to true for the engine.
+func WithTransactionMode() EngineOptFunc {
+	return WithTransaction()
+}
+
+// WithNoTransaction makes the engine run the commands without
+// a transaction, regardless of the given config.
+func WithNoTransaction() EngineOptFunc {
+	return func(e *engine) {
+		e.conf.WithTransaction = false
+	}
+}
+
+// WithVersionPrefix sets the prefix of the version markers,
+// which is `#v` by default, eg. `-- version: ` for `-- version: 1.0.0`.
+func WithVersionPrefix(prefix string) EngineOptFunc {
+	return func(e *engine) {
+		e.versionPrefix = prefix
+	}
+}
+
+// WithUpCommand sets the marker of the up commands, which is `#[UP]` by default.
+func WithUpCommand(s string) EngineOptFunc {
+	return func(e *engine) {
+		e.upMarker = s
+	}
+}
+
+// WithDownCommand sets the marker of the down commands, which is `#[DOWN]` by default.
+func WithDownCommand(s string) EngineOptFunc {
+	return func(e *engine) {
+		e.downMarker = s
+	}
+}
+
 // NewFromJsonConfig creates a new instance from the config at the given path.
 func NewFromJsonConfig(path string, opts ...EngineOptFunc) (Engine, error) {
 	config, err := loadJsonConfig(path)
@@ -115,101 +404,243 @@ func NewFromEnv(opts ...EngineOptFunc) (Engine, error) {
 
 // New creates a new instance based upon the given config.
 func New(c *Config, opts ...EngineOptFunc) (Engine, error) {
-	if c == nil {
-		return nil, ErrConfigIsNil
+	e, err := newEngine(c, opts...)
+	if err != nil {
+		return nil, err
 	}
 
-	db, err := database.New(context.Background(), database.DatabaseConfig{
-		Driver:   c.DriverName,
-		Host:     c.Host,
-		Port:     c.Port,
-		Database: c.Database,
-		Username: c.Username,
-		Password: c.Password,
-	})
+	var dbOpts []database.DatabaseOptFunc
+
+	if e.lazyConnect {
+		dbOpts = append(dbOpts, database.WithLazyConnect())
+	}
+
+	db, err := database.New(context.Background(), e.dbConf, dbOpts...)
+	if err != nil {
+		return nil, err
+	}
+
+	e.setDatabase(db)
+
+	return e, nil
+}
+
+// NewWithDatabase works like New, but uses the given database instead of
+// connecting to the one described by the config, eg. in case of an application,
+// which manages its own connection pool. The connection details of the config
+// are still used by the operations needing a separate connection, eg. TestMigration.
+func NewWithDatabase(db database.Database, c *Config, opts ...EngineOptFunc) (Engine, error) {
+	if db == nil {
+		return nil, ErrDatabaseIsNil
+	}
+
+	e, err := newEngine(c, opts...)
 	if err != nil {
 		return nil, err
 	}
 
-	rep := repositories.New(db, c.MigrationsTableName)
+	e.setDatabase(db)
+
+	return e, nil
+}
+
+// newEngine creates a new instance without database
+// based upon the given config and options.
+func newEngine(c *Config, opts ...EngineOptFunc) (*engine, error) {
+	if c == nil {
+		return nil, ErrConfigIsNil
+	}
+
+	// The options may override the config, so a copy is used,
+	// which makes it safe to share a config between engines.
+	conf := *c
 
 	e := &engine{
-		conf:         c,
-		repositories: rep,
-		db:           db,
-		dir:          DirectionUp,
+		conf:        &conf,
+		dir:         DirectionUp,
+		environment: os.Getenv(environmentEnvKey),
 	}
 
+	// The options must be applied before connecting,
+	// since they can override the connection details.
 	for _, o := range opts {
 		o(e)
 	}
 
+	if err := e.setLogLevel(c.LogLevel); err != nil {
+		return nil, err
+	}
+
+	if err := validateCommandLogLevel(e.commandLogLevel); err != nil {
+		return nil, err
+	}
+
+	databaseName := c.Database
+	if e.databaseName != "" {
+		databaseName = e.databaseName
+	}
+
+	e.dbConf = database.DatabaseConfig{
+		Driver:   c.DriverName,
+		Host:     c.Host,
+		Port:     c.Port,
+		Database: databaseName,
+		Username: c.Username,
+		Password: c.Password,
+
+		ConnectTimeout: c.ConnectTimeout,
+		PingTimeout:    c.PingTimeout,
+		DSNOptions:     c.DSNOptions,
+
+		ApplicationName: c.ApplicationName,
+	}
+
 	return e, nil
 }
 
-// ProcessWithDirection is a wrapper to Process. Firstly, it sets
-// the direction, secondly calls Process.
+// setDatabase sets the database of the engine,
+// and creates the repositories upon it.
+func (e *engine) setDatabase(db database.Database) {
+	e.db = db
+
+	migrationsTableName := e.conf.MigrationsTableName
+	if e.migrationsTableName != "" {
+		migrationsTableName = e.migrationsTableName
+	}
+
+	e.repositories = repositories.NewWithSchema(db, migrationsTableName, e.conf.MigrationsTableSchema)
+}
+
+// ProcessWithDirection works like Process,
+// but runs with the given direction.
 func (e *engine) ProcessWithDirection(d direction) error {
-	e.dir = d
+	if err := validateDirection(d); err != nil {
+		return err
+	}
 
-	// Resetting the direction back to default.
-	defer func() {
-		e.dir = DirectionUp
-	}()
+	return e.process(context.Background(), e.getCommands, d, e.targetVersion, runHooks{})
+}
 
-	return e.Process()
+// ProcessUpgrade applies every pending UP command,
+// so the database is upgraded to the latest version of the file.
+func (e *engine) ProcessUpgrade() error {
+	return e.process(context.Background(), e.getCommands, DirectionUp, nil, runHooks{})
 }
 
-// ProcessWithTargetVersion is a wrapper to Process. Firstly, it sets
-// the desired version, secondly calls Process.
+// ProcessWithTargetVersion works like Process,
+// but runs until the given version.
 func (e *engine) ProcessWithTargetVersion(v string) error {
 	sv := newSemver(v)
 	if sv == nil {
 		return ErrBadVersioning
 	}
 
-	e.targetVersion = sv
-
-	// Making sure to reset the version in case
-	// of the engine instance reuse.
-	defer func() {
-		e.targetVersion = nil
-	}()
-
-	return e.Process()
+	return e.process(context.Background(), e.getCommands, e.dir, sv, runHooks{})
 }
 
-// Process acts a bootstrapper and the main worker. It sets up
+// Migrate acts a bootstrapper and the main worker. It sets up
 // the appropiate database table – if it does not exist – reads the
 // migration file, then parses it, then executes the commands that need to run.
+// The direction and the target version are given by the options of the engine.
+// The run stops before executing any command, if the context is done, and
+// in case of transaction, it is bound to the context.
+func (e *engine) Migrate(ctx context.Context) error {
+	return e.process(ctx, func() ([]Command, error) {
+		return e.getCommandsContext(ctx)
+	}, e.dir, e.targetVersion, runHooks{})
+}
+
+// MigrateAndClose works like Migrate, but closes the database
+// afterwards, regardless whether the migration succeeded or not.
+func (e *engine) MigrateAndClose(ctx context.Context) error {
+	defer e.CloseDatabase()
+
+	return e.Migrate(ctx)
+}
+
+// Process works like Migrate with background context.
+// It is kept for backward compatibility.
 func (e *engine) Process() error {
-	if err := e.SetupDatabase(); err != nil {
+	return e.Migrate(context.Background())
+}
+
+// ExecuteCommands runs the given commands, eg. a custom filtered subset of
+// the parsed ones, without the version selection of Migrate. The commands
+// run inside a transaction, if it is enabled by the config, and the failures
+// are handled by the registered error handler. The migrations table is left
+// untouched, so storing the new version is up to the caller.
+func (e *engine) ExecuteCommands(commands []Command) error {
+	defer e.startStats()()
+
+	if len(commands) == 0 {
+		return ErrNothingToRun
+	}
+
+	return e.execute(context.Background(), commands, runHooks{}, nil)
+}
+
+// ProcessWithReader works like Process, but the
+// migrations are read from the given reader.
+func (e *engine) ProcessWithReader(r io.Reader) error {
+	return e.process(context.Background(), func() ([]Command, error) {
+		lines, err := readLines(r)
+		if err != nil {
+			return nil, err
+		}
+
+		return e.ParseLines(lines)
+	}, e.dir, e.targetVersion, runHooks{})
+}
+
+// process is the main worker, which reads the parsed commands by the
+// given function. The direction, the target version and the hooks are
+// passed explicitly, so a run never modifies the state of the engine.
+func (e *engine) process(ctx context.Context, getCommands func() ([]Command, error), dir direction, targetVersion Semver, hooks runHooks) (err error) {
+	defer e.startStats()()
+
+	defer func() {
+		for _, fn := range e.afterProcess {
+			fn(err)
+		}
+	}()
+
+	if err := ctx.Err(); err != nil {
 		return err
 	}
 
-	lines, err := e.GetLines()
+	if e.migrationTimeout > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, e.migrationTimeout)
+		defer cancel()
+	}
+
+	for _, fn := range e.beforeProcess {
+		if err := fn(); err != nil {
+			return err
+		}
+	}
+
+	release, err := e.prepare()
 	if err != nil {
 		return err
 	}
+	defer release()
 
-	commands, err := e.ParseLines(lines)
+	commands, err := getCommands()
 	if err != nil {
 		return err
 	}
 
-	current := e.repositories.Migrations.GetLatest()
-
-	var currentVersion Semver
-
-	if current != nil {
-		latestSemver := newSemver(current.Version)
+	currentVersion, err := e.GetCurrentVersion()
+	if err != nil {
+		return err
+	}
 
-		// In this case the stored latest version is somehow invalid.
-		if latestSemver == nil {
-			return ErrInvalidLastVersion
+	if hooks.resolveTarget != nil {
+		if targetVersion, err = hooks.resolveTarget(currentVersion); err != nil {
+			return err
 		}
-
-		currentVersion = latestSemver
 	}
 
 	if currentVersion == nil {
@@ -220,158 +651,351 @@ func (e *engine) Process() error {
 		e.Info(fmt.Sprintf("-- prestored migration version: %s", currentVersion.ToString()))
 	}
 
-	// If the given target version is smaller than the latest version,
-	// we manually have to set the direction.
-	if e.targetVersion != nil && currentVersion.GreaterThan(e.targetVersion) {
-		e.dir = DirectionDown
-	}
+	dir = resolveDirection(currentVersion, dir, targetVersion)
 
-	filteredCommands := filterCommands(currentVersion, commands, e.dir, e.targetVersion)
+	filteredCommands := selectCommands(currentVersion, commands, dir, targetVersion)
 
 	if len(filteredCommands) == 0 {
 		return ErrNothingToRun
 	}
 
-	if e.conf.WithTransaction {
-		if err := e.db.StartTransaction(); err != nil {
-			return err
-		}
+	// The version which must be saved after the run.
+	newLatestVersion := getNewLatestVersion(currentVersion, commands, dir, targetVersion)
+
+	// It can only happen, if there were no commands at all.
+	if newLatestVersion == nil {
+		return ErrNothingToRun
 	}
 
-	if err := runCommands(e, filteredCommands, e.conf.WithTransaction); err != nil {
-		if e.conf.WithTransaction {
-			if err := e.db.Rollback(); err != nil {
-				return err
-			}
-		}
+	// Preparing, eg. waiting for the lock, could take a while.
+	if err := ctx.Err(); err != nil {
+		return err
+	}
 
+	batchId, err := newBatchId()
+	if err != nil {
 		return err
 	}
 
-	// Then must save the latest version.
-	newLatestVersion := func() Semver {
-		if e.targetVersion != nil {
-			return e.targetVersion
-		}
+	if e.migrationTimeout > 0 {
+		filteredCommands = e.withContextDatabase(ctx, filteredCommands)
+	}
 
-		if e.dir == DirectionUp {
-			return getLatestVersion(commands)
-		}
+	start := time.Now()
 
-		// Else we would have to scan for previous version
-		// compared to the stored one.
-		return getPreviousSemver(currentVersion, commands)
-	}()
+	err = e.execute(ctx, filteredCommands, hooks, func() error {
+		description := getVersionDescription(newLatestVersion, commands)
 
-	if err := e.repositories.Migrations.Insert(newLatestVersion.ToString()); err != nil {
-		// If there was an error during the insertion of
-		// the new latest version, then should a rollback.
-		// However, it is only possible, if the a transaction was started.
-		if e.conf.WithTransaction {
-			if rollbackErr := e.db.Rollback(); rollbackErr != nil {
-				return rollbackErr
-			}
+		return e.repositories.Migrations.Insert(newLatestVersion.ToString(), description, batchId, time.Since(start).Milliseconds())
+	})
+	if err != nil {
+		// The transaction bound to the timed out context is rolled back by
+		// database/sql, so eg. the explicit rollback fails with sql.ErrTxDone.
+		if e.migrationTimeout > 0 && ctx.Err() != nil {
+			return ctx.Err()
 		}
 
 		return err
 	}
 
-	if e.conf.WithTransaction {
-		if err := e.db.Commit(); err != nil {
-			return err
-		}
-	}
+	e.Info(fmt.Sprintf("-- migrated from version %s to %s --", currentVersion.ToString(), newLatestVersion.ToString()))
 
 	return nil
 }
 
-// SetupDatabase tries to setup the database states.
-// Checks, if the migrations table exists, and tries to
-// create if not.
-func (e *engine) SetupDatabase() error {
-	// If the migrations table exists, then we are all set.
-	if e.repositories.Migrations.DoesExists() {
-		return nil
-	}
-	// Otherwise, must create the table.
-	return e.repositories.Migrations.CreateTable()
-}
+// prepare does the preliminary steps of every run: acquires the lock,
+// checks the read-only mode – if they are enabled – and sets up the
+// migrations table. The returned function releases the lock.
+func (e *engine) prepare() (func(), error) {
+	release := func() {}
+
+	if e.withLock {
+		unlock, err := e.lock()
+		if err != nil {
+			return nil, err
+		}
 
-// GetLines returns all the nonempty lines read from the path
-// set at the config.
-func (e *engine) GetLines() ([]string, error) {
-	if e.conf.MigrationsFilePath == "" {
-		return nil, ErrNoFilePath
+		release = unlock
 	}
 
-	f, err := os.Open(e.conf.MigrationsFilePath)
-	if err != nil {
-		return nil, err
+	if e.withReadonlyCheck {
+		if err := e.checkReadOnly(); err != nil {
+			release()
+
+			return nil, err
+		}
 	}
-	defer f.Close()
 
-	var (
-		scanner = bufio.NewScanner(f)
-		lines   = make([]string, 0)
-	)
+	if err := e.SetupDatabase(); err != nil {
+		release()
 
-	for scanner.Scan() {
-		lines = append(lines, scanner.Text())
+		return nil, err
 	}
 
-	return lines, nil
+	return release, nil
 }
 
-// ParseLines creates the version-commands map based upon the reead file.
-// The input represents the read lines splitted by newline.
-func (e *engine) ParseLines(lines []string) ([]Command, error) {
-	if lines == nil {
-		return nil, nil
-	}
+// runHooks are the callbacks of a single run. They are passed along
+// the run instead of being stored by the engine, so the concurrent
+// runs of the same engine do not affect each other.
+type runHooks struct {
+	// onError overrides the registered error handler, if it is not <nil>.
+	onError ErrorHandlerFunc
 
-	var (
-		currentVersion Semver
-		lineStack      = make([]string, 0)
-		commandStack   = make([]Command, 0)
+	// onRun is called for every run of a command, if it is not <nil>.
+	onRun func(Command)
 
-		isInsideMultiLineComment = false
+	// onVersion is called as soon as the commands of a version finished,
+	// with the first error of them, if it is not <nil>.
+	onVersion func(version string, dir direction, err error)
+
+	// resolveTarget replaces the target version, if it is not <nil>. It is
+	// called with the stored current version – <nil> without history –
+	// once the lock is held, so a concurrent run can not make it stale.
+	resolveTarget func(current Semver) (Semver, error)
+}
+
+// execute runs the given commands, then calls the given function – if
+// it is not <nil> –, eg. to save the new version. In case of transaction,
+// both of them run inside the same transaction.
+func (e *engine) execute(ctx context.Context, commands []Command, hooks runHooks, after func() error) error {
+	if e.conf.WithTransaction {
+		if err := e.db.StartTransactionContext(ctx); err != nil {
+			return err
+		}
+
+		e.recordStats(func(s *EngineStats) { s.TransactionUsed = true })
+	}
+
+	if err := e.runCommands(commands, hooks); err != nil {
+		// The lost transaction can not be rolled back anymore.
+		if e.conf.WithTransaction && !errors.Is(err, ErrTransactionLostOnReconnect) {
+			if err := e.db.Rollback(); err != nil {
+				return err
+			}
+
+			e.recordStats(func(s *EngineStats) { s.RolledBack = true })
+		}
+
+		return err
+	}
+
+	if after != nil {
+		if err := after(); err != nil {
+			// If there was an error after the run, eg. during the
+			// insertion of the new latest version, then should a rollback.
+			// However, it is only possible, if the a transaction was started.
+			if e.conf.WithTransaction {
+				if rollbackErr := e.db.Rollback(); rollbackErr != nil {
+					return rollbackErr
+				}
+
+				e.recordStats(func(s *EngineStats) { s.RolledBack = true })
+			}
+
+			return err
+		}
+	}
+
+	if e.conf.WithTransaction {
+		if err := e.db.Commit(); err != nil {
+			return err
+		}
+	}
+
+	e.recordStats(func(s *EngineStats) { s.VersionsApplied = getExecutedVersions(commands) })
+
+	return nil
+}
+
+// lock acquires the migration lock and returns the function releasing it.
+func (e *engine) lock() (func(), error) {
+	locker, ok := e.db.(database.Locker)
+	if !ok {
+		return nil, ErrLockNotSupported
+	}
+
+	name := e.getLockName()
+
+	if e.postgresAdvisoryLock {
+		if _, ok := e.db.(*database.PostgresDatabase); !ok {
+			return nil, ErrLockNotSupported
+		}
+
+		// Advisory locks are scoped to the database already.
+		name = e.repositories.Migrations.GetTableName()
+	}
+
+	if err := locker.Lock(name, e.lockTimeout); err != nil {
+		return nil, err
+	}
+
+	return func() {
+		if err := locker.Unlock(name); err != nil {
+			e.Error(fmt.Sprintf("could not release the migration lock: %v", err))
+		}
+	}, nil
+}
+
+// getLockName returns the name of the migration lock, which is
+// unique for every database and migrations table pair.
+func (e *engine) getLockName() string {
+	return fmt.Sprintf("dbmigrator.%s.%s", e.db.GetDatabaseName(), e.repositories.Migrations.GetTableName())
+}
+
+// checkReadOnly returns ErrDatabaseReadOnly, if the
+// database does not accept any modifications.
+func (e *engine) checkReadOnly() error {
+	var query string
+
+	switch e.db.GetDriverName() {
+	case "mysql":
+		// It is set by super_read_only as well, and exists on MariaDB too.
+		query = "SELECT @@read_only"
+	case "postgres", "pgx":
+		// A server in recovery is a standby, which is read-only.
+		query = "SELECT pg_is_in_recovery()"
+	default:
+		return ErrUnsupportedDriver
+	}
+
+	var readOnly bool
+
+	if err := e.db.QueryRow(query).Scan(&readOnly); err != nil {
+		return err
+	}
+
+	if readOnly {
+		return ErrDatabaseReadOnly
+	}
+
+	return nil
+}
+
+// SetupDatabase tries to setup the database states.
+// Checks, if the migrations table exists, and tries to
+// create if not.
+func (e *engine) SetupDatabase() error {
+	// If the migrations table exists, then we are all set.
+	if e.repositories.Migrations.DoesExists() {
+		return nil
+	}
+	// Otherwise, must create the table.
+	return e.repositories.Migrations.CreateTable()
+}
+
+// GetLines returns all the nonempty lines read from the path set at
+// the config. An `s3://` path is read via the client given by WithS3Client.
+func (e *engine) GetLines() (MigrationLines, error) {
+	return e.GetLinesContext(context.Background())
+}
+
+// GetLinesContext works like GetLines, but stops reading
+// and returns the error of the context, once it is done.
+func (e *engine) GetLinesContext(ctx context.Context) (MigrationLines, error) {
+	f, err := e.openMigrationsFile(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return readLinesContext(ctx, f)
+}
+
+// ParseLines creates the version-commands map based upon the reead file.
+// The input represents the read lines splitted by newline.
+func (e *engine) ParseLines(lines MigrationLines) ([]Command, error) {
+	if lines == nil {
+		return nil, nil
+	}
+
+	var (
+		currentVersion Semver
+		lineStack      = make([]string, 0)
+		commandStack   = make([]Command, 0)
+
+		isInsideMultiLineComment = false
 
 		dir direction = DirectionUp
+
+		// The environments of the current block, <nil> means every environment.
+		envs []string
+
+		// The descriptions of the versions given by the #[DESC] markers.
+		descriptions = make(map[string]string)
+
+		// The versions marked by #[PARALLEL].
+		parallelVersions = make(map[string]bool)
+
+		// The tags of the versions given by the #[TAG] markers.
+		tags = make(map[string][]string)
+
+		// The block comments preceding the version markers.
+		versionComments = &versionCommentCollector{}
+
+		versionPrefix = e.getVersionPrefix()
+		upMarker      = e.getUpMarker()
+		downMarker    = e.getDownMarker()
 	)
 
-	for _, line := range lines {
-		line = strings.TrimSpace(line)
+	for i, rawLine := range lines {
+		var (
+			line       = strings.TrimSpace(rawLine)
+			lineNumber = i + 1
+			column     = strings.Index(rawLine, line) + 1
+		)
+
+		if e.captureVersionComments && !strings.HasPrefix(line, versionPrefix) {
+			versionComments.feed(line)
+		}
 
-		if line == upCommand {
+		if line == upMarker {
 			dir = DirectionUp
 
 			continue
 		}
 
-		if line == downCommand {
+		if line == downMarker {
 			dir = DirectionDown
 
 			continue
 		}
 
-		if !strings.HasPrefix(line, versionProlog) {
-			if idx := strings.Index(line, singleLineComment); idx != -1 {
-				line = line[:idx]
-			}
+		if strings.HasPrefix(line, descCommand) {
+			if currentVersion != nil {
+				desc := strings.TrimSpace(strings.TrimPrefix(line, descCommand))
 
-			if idx := strings.Index(line, multiLineCommentStart); idx != -1 {
-				isInsideMultiLineComment = true
+				descriptions[currentVersion.ToString()] = strings.TrimSpace(descriptions[currentVersion.ToString()] + " " + desc)
 			}
 
-			if idx := strings.Index(line, multiLineCommentEnd); idx != -1 {
-				isInsideMultiLineComment = false
-				continue
+			continue
+		}
+
+		if strings.HasPrefix(line, tagCommand) {
+			if currentVersion != nil {
+				tags[currentVersion.ToString()] = append(tags[currentVersion.ToString()], parseTagCommand(line)...)
 			}
 
-			if isInsideMultiLineComment {
-				continue
+			continue
+		}
+
+		if line == parallelCommand {
+			if currentVersion != nil {
+				parallelVersions[currentVersion.ToString()] = true
 			}
 
+			continue
+		}
+
+		if isEnvCommand(line) {
+			envs = parseEnvCommand(line)
+
+			continue
+		}
+
+		if !strings.HasPrefix(line, versionPrefix) {
+			line, isInsideMultiLineComment = stripComments(line, isInsideMultiLineComment)
+
 			// If currently read line is not empty,
 			// then it is simply pushed to the stack.
 			if line == "" {
@@ -381,10 +1005,15 @@ func (e *engine) ParseLines(lines []string) ([]Command, error) {
 			lineStack = append(lineStack, line)
 
 			if strings.HasSuffix(line, ";") {
-				if currentVersion != nil {
+				if currentVersion != nil && e.matchesEnvironment(envs) {
 					query := strings.Join(lineStack, " ")
 
-					commandStack = append(commandStack, newCommand(e.db, query, currentVersion, dir))
+					cmd, err := newCommand(e.db, query, currentVersion, dir)
+					if err != nil {
+						return nil, newParseError(lineNumber, column, err.Error(), err)
+					}
+
+					commandStack = append(commandStack, cmd)
 				}
 
 				lineStack = lineStack[:0]
@@ -394,10 +1023,10 @@ func (e *engine) ParseLines(lines []string) ([]Command, error) {
 			continue
 		}
 
-		spl := strings.Split(line, versionProlog)
+		spl := strings.Split(line, versionPrefix)
 
 		if len(spl) != 2 {
-			return nil, ErrBadVersioning
+			return nil, newParseError(lineNumber, column, fmt.Sprintf("bad version `%s`", line), ErrBadVersioning)
 		}
 
 		// If there was a version before this iteration
@@ -409,72 +1038,990 @@ func (e *engine) ParseLines(lines []string) ([]Command, error) {
 
 		sv := newSemver(spl[1])
 		if sv == nil {
-			return nil, ErrBadVersioning
+			return nil, newParseError(lineNumber, column, fmt.Sprintf("bad version `%s`", line), ErrBadVersioning)
+		}
+
+		if e.versionValidator != nil {
+			if err := e.versionValidator(sv); err != nil {
+				return nil, newParseError(lineNumber, column, fmt.Sprintf("invalid version `%s`: %v", spl[1], err), err)
+			}
 		}
+
 		currentVersion = sv
 
-		// Setting the direction back to default, whenever a new version is read.
+		if comment := versionComments.take(); comment != "" {
+			descriptions[sv.ToString()] = strings.TrimSpace(descriptions[sv.ToString()] + " " + comment)
+		}
+
+		// Setting the direction and the environments back
+		// to default, whenever a new version is read.
 		dir = DirectionUp
+		envs = nil
+	}
+
+	// The description, the parallel flag and the tags belong to the whole
+	// version, so they are set on the commands preceding the markers as well.
+	for _, c := range commandStack {
+		if cmd, ok := c.(*command); ok {
+			cmd.description = descriptions[c.Semver().ToString()]
+			cmd.parallel = parallelVersions[c.Semver().ToString()]
+			cmd.tags = tags[c.Semver().ToString()]
+		}
+	}
+
+	commandStack = e.filterByTags(commandStack)
+
+	if e.strictMode {
+		if err := checkDownMigrations(commandStack); err != nil {
+			return nil, err
+		}
 	}
 
 	return commandStack, nil
 }
 
-// Info implements the info branch of logging.
-func (e *engine) Info(line string) {
-	if e.logger != nil {
-		e.Info(line)
+// isEnvCommand returns whether the given line is an `#[ENV name]` marker.
+func isEnvCommand(line string) bool {
+	return strings.HasPrefix(line, envCommandPrefix) && strings.HasSuffix(line, envCommandSuffix)
+}
+
+// parseEnvCommand returns the comma separated environments of the
+// given `#[ENV name]` marker. An empty marker returns <nil>.
+func parseEnvCommand(line string) []string {
+	content := strings.TrimSuffix(strings.TrimPrefix(line, envCommandPrefix), envCommandSuffix)
+
+	envs := make([]string, 0)
+
+	for _, env := range strings.Split(content, envSeparator) {
+		if env = strings.TrimSpace(env); env != "" {
+			envs = append(envs, env)
+		}
+	}
+
+	if len(envs) == 0 {
+		return nil
 	}
+
+	return envs
 }
 
-// Error implements the error branch of logging.
-func (e *engine) Error(line string) {
-	if e.logger != nil {
-		e.Error(line)
+// getVersionPrefix returns the prefix of the version markers.
+func (e *engine) getVersionPrefix() string {
+	if e.versionPrefix != "" {
+		return e.versionPrefix
 	}
+
+	return versionProlog
 }
 
-// CloseDatabase closes the database connection.
-func (e *engine) CloseDatabase() { e.db.Close() }
+// getUpMarker returns the marker of the up commands.
+func (e *engine) getUpMarker() string {
+	if e.upMarker != "" {
+		return e.upMarker
+	}
 
-func filterCommands(
-	version Semver,
-	commands []Command,
-	dir direction,
-	targetVersion Semver,
-) []Command {
-	filtered := make([]Command, 0)
+	return upCommand
+}
 
-	for _, c := range commands {
-		if c.GetDirection() != dir {
-			continue
+// getDownMarker returns the marker of the down commands.
+func (e *engine) getDownMarker() string {
+	if e.downMarker != "" {
+		return e.downMarker
+	}
+
+	return downCommand
+}
+
+// matchesEnvironment returns whether the commands of a block with the
+// given environments should be included. Blocks without any environment
+// are always included.
+func (e *engine) matchesEnvironment(envs []string) bool {
+	if envs == nil {
+		return true
+	}
+
+	for _, env := range envs {
+		if env == e.environment {
+			return true
 		}
+	}
 
-		if version == nil || c.ShouldRun(version, dir, targetVersion) {
-			filtered = append(filtered, c)
+	return false
+}
+
+// versionCommentCollector collects the content of the block
+// comment, which is directly followed by a version marker.
+type versionCommentCollector struct {
+	lines   []string
+	inside  bool
+	comment string
+}
+
+// feed processes the next line, which is not a version marker.
+func (c *versionCommentCollector) feed(line string) {
+	if !c.inside {
+		if line == "" {
+			return
+		}
+
+		// Any other content between the comment and
+		// the version marker discards the comment.
+		if !strings.HasPrefix(line, multiLineCommentStart) {
+			c.comment = ""
+
+			return
 		}
+
+		line = strings.TrimPrefix(line, multiLineCommentStart)
+
+		c.inside = true
+		c.lines = c.lines[:0]
+		c.comment = ""
 	}
 
-	return filtered
+	idx := strings.Index(line, multiLineCommentEnd)
+	if idx == -1 {
+		c.appendLine(line)
+
+		return
+	}
+
+	c.appendLine(line[:idx])
+	c.inside = false
+
+	// The comment must be on its own lines.
+	if strings.TrimSpace(line[idx+len(multiLineCommentEnd):]) == "" {
+		c.comment = strings.Join(c.lines, " ")
+	}
 }
 
-func runCommands(logger Logger, commands []Command, withTransaction bool) error {
-	for _, c := range commands {
-		if err := c.Run(); err != nil {
-			// The transaction must stop at the first problem.
-			if withTransaction {
-				return err
+// appendLine stores the given comment line without
+// the leading asterisk of the doc-style comments.
+func (c *versionCommentCollector) appendLine(line string) {
+	line = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(line), "*"))
+
+	if line != "" {
+		c.lines = append(c.lines, line)
+	}
+}
+
+// take returns the collected comment, and resets it.
+func (c *versionCommentCollector) take() string {
+	comment := c.comment
+	c.comment = ""
+
+	return comment
+}
+
+// stripComments removes every comment from the given line. The second
+// parameter tells, whether the line starts inside a multi-line comment,
+// the returned flag tells, whether the line ends inside one.
+func stripComments(line string, isInsideMultiLineComment bool) (string, bool) {
+	var b strings.Builder
+
+	for line != "" {
+		if isInsideMultiLineComment {
+			idx := strings.Index(line, multiLineCommentEnd)
+			if idx == -1 {
+				break
 			}
 
-			logger.Error(fmt.Sprintf("execution error: %v", err))
+			line = line[idx+len(multiLineCommentEnd):]
+			isInsideMultiLineComment = false
+
+			continue
+		}
+
+		var (
+			singleIdx = strings.Index(line, singleLineComment)
+			multiIdx  = strings.Index(line, multiLineCommentStart)
+		)
+
+		// The rest of the line is a single-line comment.
+		if singleIdx != -1 && (multiIdx == -1 || singleIdx < multiIdx) {
+			b.WriteString(line[:singleIdx])
+
+			break
+		}
+
+		if multiIdx == -1 {
+			b.WriteString(line)
+
+			break
 		}
+
+		b.WriteString(line[:multiIdx])
+
+		line = line[multiIdx+len(multiLineCommentStart):]
+		isInsideMultiLineComment = true
+	}
+
+	return strings.TrimSpace(b.String()), isInsideMultiLineComment
+}
+
+// SetLogger attaches the given logger to the engine after its creation,
+// eg. if the logger depends on a request. The level of the config is
+// applied to it, the same way as to the one given by WithLogger.
+// It is safe to call during a run.
+func (e *engine) SetLogger(l Logger) {
+	// The level is validated by the creation of the engine.
+	if e.conf != nil {
+		applyLogLevel(l, e.conf.LogLevel)
+	}
+
+	e.loggerMu.Lock()
+	defer e.loggerMu.Unlock()
+
+	e.logger = l
+}
+
+// getLogger returns the attached logger.
+func (e *engine) getLogger() Logger {
+	e.loggerMu.RLock()
+	defer e.loggerMu.RUnlock()
+
+	return e.logger
+}
+
+// Info implements the info branch of logging.
+func (e *engine) Info(line string) {
+	if l := e.getLogger(); l != nil {
+		l.Info(line)
+	}
+}
+
+// Error implements the error branch of logging.
+func (e *engine) Error(line string) {
+	if l := e.getLogger(); l != nil {
+		l.Error(line)
+	}
+}
+
+// Debug implements the debug branch of logging,
+// if the attached logger supports it.
+func (e *engine) Debug(line string) {
+	if l, ok := e.getLogger().(LeveledLogger); ok {
+		l.Debug(line)
+	}
+}
+
+// Warn implements the warn branch of logging,
+// if the attached logger supports it.
+func (e *engine) Warn(line string) {
+	if l, ok := e.getLogger().(LeveledLogger); ok {
+		l.Warn(line)
+	}
+}
+
+// setLogLevel applies the given level to the attached logger,
+// if it is a built-in one. Empty level leaves the default.
+func (e *engine) setLogLevel(level string) error {
+	if level == "" {
+		return nil
+	}
+
+	if getLogLevel(level) < 0 {
+		return ErrInvalidLogLevel
+	}
+
+	return applyLogLevel(e.getLogger(), level)
+}
+
+// applyLogLevel sets the given level on the logger, if it is a built-in one.
+func applyLogLevel(l Logger, level string) error {
+	if s, ok := l.(levelSetter); ok && level != "" {
+		return s.SetLevel(level)
 	}
 
 	return nil
 }
 
-func getLatestVersion(commands []Command) Semver {
-	var sv Semver = bottomVersion
+// GetRecentHistory returns the latest n stored migrations
+// in descending order.
+func (e *engine) GetRecentHistory(n int) ([]*models.Migration, error) {
+	return e.repositories.Migrations.GetLatestN(n)
+}
+
+// GetMigrationTableName returns the resolved name of the migrations
+// table, qualified with the schema, if it is given by the config.
+func (e *engine) GetMigrationTableName() string {
+	return e.repositories.Migrations.GetTableName()
+}
+
+// GetCurrentVersion returns the latest version stored in
+// the migrations table, or <nil> if there is no history.
+func (e *engine) GetCurrentVersion() (Semver, error) {
+	// Without the migrations table, nothing was applied yet.
+	if !e.repositories.Migrations.DoesExists() {
+		return nil, nil
+	}
+
+	current, err := e.repositories.Migrations.GetLatest()
+	if err != nil {
+		return nil, err
+	}
+
+	if current == nil {
+		return nil, nil
+	}
+
+	sv := newSemver(current.Version)
+
+	// In this case the stored latest version is somehow invalid.
+	if sv == nil {
+		return nil, ErrInvalidLastVersion
+	}
+
+	return sv, nil
+}
+
+// GetHistory returns every stored migration in ascending order.
+func (e *engine) GetHistory() ([]*models.Migration, error) {
+	// Without the migrations table, nothing was applied yet.
+	if !e.repositories.Migrations.DoesExists() {
+		return []*models.Migration{}, nil
+	}
+
+	return e.repositories.Migrations.GetAll()
+}
+
+// GetAppliedAt returns when the given version was stored in the migrations
+// table. Since only the last version of each run is stored, a version applied
+// as an intermediate step resolves to the first stored version above it.
+// If the version was never applied, it returns <nil> without error.
+func (e *engine) GetAppliedAt(version string) (*time.Time, error) {
+	sv := newSemver(version)
+	if sv == nil {
+		return nil, ErrBadVersioning
+	}
+
+	if !e.repositories.Migrations.DoesExists() {
+		return nil, nil
+	}
+
+	migration, err := e.repositories.Migrations.GetByVersion(sv.ToString())
+	if err != nil {
+		return nil, err
+	}
+
+	if migration != nil {
+		return &migration.CreatedAt, nil
+	}
+
+	history, err := e.repositories.Migrations.GetAll()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, m := range history {
+		if stored := newSemver(m.Version); stored != nil && !sv.GreaterThan(stored) {
+			return &m.CreatedAt, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// ForceMigrationsTableRecreate recreates the migrations table with the
+// current schema, keeping the stored rows, eg. after new columns were added
+// to it by an upgrade of the package. It is never called implicitly.
+func (e *engine) ForceMigrationsTableRecreate() error {
+	if !e.repositories.Migrations.DoesExists() {
+		return ErrMigrationsTableNotExists
+	}
+
+	e.Info("-- recreating the migrations table --")
+
+	if err := e.repositories.Migrations.Recreate(func(step string) {
+		e.Info(fmt.Sprintf("-- %s", step))
+	}); err != nil {
+		e.Error(fmt.Sprintf("recreating the migrations table failed: %v", err))
+
+		return err
+	}
+
+	e.Info("-- the migrations table is recreated --")
+
+	return nil
+}
+
+// ClearMigrationHistory removes every stored migration.
+func (e *engine) ClearMigrationHistory() error {
+	return e.repositories.Migrations.DeleteAll()
+}
+
+// ListAppliedVersions returns the versions exactly as they are stored in
+// the migrations table, in the order of their creation.
+func (e *engine) ListAppliedVersions() ([]string, error) {
+	history, err := e.GetHistory()
+	if err != nil {
+		return nil, err
+	}
+
+	versions := make([]string, 0, len(history))
+
+	for _, m := range history {
+		versions = append(versions, m.Version)
+	}
+
+	return versions, nil
+}
+
+// GetAppliedVersions returns the unique versions stored in the
+// migrations table in ascending order.
+func (e *engine) GetAppliedVersions() ([]Semver, error) {
+	history, err := e.GetHistory()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		versions = make([]Semver, 0, len(history))
+		seen     = make(map[string]struct{})
+	)
+
+	for _, m := range history {
+		sv := newSemver(m.Version)
+		if sv == nil {
+			return nil, ErrInvalidLastVersion
+		}
+
+		if _, ok := seen[sv.ToString()]; ok {
+			continue
+		}
+
+		seen[sv.ToString()] = struct{}{}
+		versions = append(versions, sv)
+	}
+
+	sortSemvers(versions)
+
+	return versions, nil
+}
+
+// getCommands reads and parses the migration file. The format
+// is detected by the extension of the file: YAML or the custom one.
+func (e *engine) getCommands() ([]Command, error) {
+	return e.getCommandsContext(context.Background())
+}
+
+// getCommandsContext works like getCommands, but the
+// reading of the lines can be cancelled via the context.
+func (e *engine) getCommandsContext(ctx context.Context) ([]Command, error) {
+	if isYAMLFile(e.conf.MigrationsFilePath) {
+		f, err := e.openMigrationsFile(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		content, err := io.ReadAll(f)
+		if err != nil {
+			return nil, err
+		}
+
+		return e.ParseYAMLLines(content)
+	}
+
+	lines, err := e.GetLinesContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.ParseLines(lines)
+}
+
+// GetFileVersion returns the latest version of the migration
+// file, or <nil> if the file has no commands.
+func (e *engine) GetFileVersion() (Semver, error) {
+	commands, err := e.getCommands()
+	if err != nil {
+		return nil, err
+	}
+
+	return getLatestVersion(commands), nil
+}
+
+// ListVersions returns the unique versions of the migration
+// file in ascending order, without touching the database.
+func (e *engine) ListVersions() ([]Semver, error) {
+	commands, err := e.getCommands()
+	if err != nil {
+		return nil, err
+	}
+
+	return getUniqueVersions(commands, nil), nil
+}
+
+// GetCommandsForVersion returns both the UP and DOWN commands of the given
+// version in file order, without executing them. If the version is not in
+// the file, it returns an empty slice without error.
+func (e *engine) GetCommandsForVersion(version string) ([]Command, error) {
+	sv := newSemver(version)
+	if sv == nil {
+		return nil, ErrBadVersioning
+	}
+
+	commands, err := e.getCommands()
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := make([]Command, 0)
+
+	for _, c := range commands {
+		if c.Semver().Equals(sv) {
+			filtered = append(filtered, c)
+		}
+	}
+
+	return filtered, nil
+}
+
+// GetPendingVersionCount returns the number of versions,
+// whose UP commands have not been applied yet.
+func (e *engine) GetPendingVersionCount() (int, error) {
+	currentVersion, err := e.GetCurrentVersion()
+	if err != nil {
+		return 0, err
+	}
+
+	if currentVersion == nil {
+		currentVersion = bottomVersion
+	}
+
+	commands, err := e.getCommands()
+	if err != nil {
+		return 0, err
+	}
+
+	pending := filterCommands(currentVersion, commands, DirectionUp, nil)
+
+	return len(getUniqueVersions(pending, nil)), nil
+}
+
+// GetCommandCount returns the number of statements in the migration
+// file, regardless of their version and direction. Useful eg. to
+// check that the file was not truncated during the deployment.
+func (e *engine) GetCommandCount() (int, error) {
+	commands, err := e.getCommands()
+	if err != nil {
+		return 0, err
+	}
+
+	return len(commands), nil
+}
+
+// GetNextVersion returns the version, which would be applied next: the
+// lowest pending version in up direction, or the current version in down
+// direction, since that is rolled back. It returns <nil> without error,
+// if there is nothing to apply.
+func (e *engine) GetNextVersion() (Semver, error) {
+	currentVersion, err := e.GetCurrentVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	if e.dir == DirectionDown {
+		return currentVersion, nil
+	}
+
+	if currentVersion == nil {
+		currentVersion = bottomVersion
+	}
+
+	commands, err := e.getCommands()
+	if err != nil {
+		return nil, err
+	}
+
+	pending := getUniqueVersions(filterCommands(currentVersion, commands, DirectionUp, nil), nil)
+	if len(pending) == 0 {
+		return nil, nil
+	}
+
+	return pending[0], nil
+}
+
+// GetMigrationFileStats returns the summary of the migration file
+// without touching the database.
+func (e *engine) GetMigrationFileStats() (MigrationFileStats, error) {
+	commands, err := e.getCommands()
+	if err != nil {
+		return MigrationFileStats{}, err
+	}
+
+	return newMigrationFileStats(commands), nil
+}
+
+// DatabaseHealth returns the health status of the database connection.
+func (e *engine) DatabaseHealth() (database.HealthStatus, error) {
+	return e.db.HealthCheck()
+}
+
+// CloseDatabase closes the database connection.
+func (e *engine) CloseDatabase() { e.db.Close() }
+
+func filterCommands(
+	version Semver,
+	commands []Command,
+	dir direction,
+	targetVersion Semver,
+) []Command {
+	filtered := make([]Command, 0)
+
+	for _, c := range commands {
+		if c.GetDirection() != dir {
+			continue
+		}
+
+		if version == nil || c.ShouldRun(version, dir, targetVersion) {
+			filtered = append(filtered, c)
+		}
+	}
+
+	return filtered
+}
+
+// getNewLatestVersion returns the version, which must be saved after the run.
+func getNewLatestVersion(currentVersion Semver, commands []Command, dir direction, targetVersion Semver) Semver {
+	if targetVersion != nil {
+		return targetVersion
+	}
+
+	if dir == DirectionUp {
+		return getLatestVersion(commands)
+	}
+
+	// Else we would have to scan for previous version
+	// compared to the stored one.
+	return getPreviousSemver(currentVersion, commands)
+}
+
+// resolveDirection returns the direction of the run. If the given target
+// version is smaller than the current version, it must be down.
+func resolveDirection(currentVersion Semver, dir direction, targetVersion Semver) direction {
+	if targetVersion != nil && currentVersion.GreaterThan(targetVersion) {
+		return DirectionDown
+	}
+
+	return dir
+}
+
+// selectCommands returns the commands to run in execution order.
+func selectCommands(currentVersion Semver, commands []Command, dir direction, targetVersion Semver) []Command {
+	filtered := filterCommands(currentVersion, commands, dir, targetVersion)
+
+	// Rolling back multiple versions must start with the latest one.
+	if dir == DirectionDown {
+		sortCommandsDescending(filtered)
+	}
+
+	return filtered
+}
+
+// runCommands runs the commands in parallel, if it is enabled
+// and no transaction is used, otherwise one after another.
+func (e *engine) runCommands(commands []Command, hooks runHooks) error {
+	commands = e.trackCommands(commands, hooks.onRun)
+
+	onError := hooks.onError
+	if onError == nil {
+		onError = e.getErrorHandler()
+	}
+
+	var run commandRunner = runCommands
+	if hooks.onVersion != nil {
+		run = withVersionReports(run, hooks.onVersion)
+	}
+
+	if e.parallelVersions > 1 && !e.conf.WithTransaction {
+		return runCommandsParallel(commands, e.withWarnLogging(onError), e.parallelVersions, run)
+	}
+
+	return run(commands, e.withWarnLogging(onError))
+}
+
+// trackedCommand is a Command, which reports every run of it.
+type trackedCommand struct {
+	Command
+
+	onRun func(Command)
+}
+
+func (c *trackedCommand) Run() error {
+	c.onRun(c.Command)
+
+	return c.Command.Run()
+}
+
+// trackCommands wraps the commands, so every run is counted in the
+// stats, the execution is logged based upon the command log level,
+// and the given function – if it is not <nil> – is called.
+func (e *engine) trackCommands(commands []Command, onRunCommand func(Command)) []Command {
+	var (
+		_, withDebug = e.getLogger().(LeveledLogger)
+
+		commandLogLevel = e.getCommandLogLevel()
+
+		logStatements  = withDebug && commandLogLevel == LogLevelDebug
+		logTransitions = commandLogLevel != CommandLogLevelNone
+
+		// The version of the previously run command.
+		lastVersion Semver
+	)
+
+	onRun := func(c Command) {
+		e.statsMu.Lock()
+		e.stats.CommandsRun++
+
+		isTransition := logTransitions && (lastVersion == nil || !lastVersion.Equals(c.Semver()))
+		if logTransitions {
+			lastVersion = c.Semver()
+		}
+		e.statsMu.Unlock()
+
+		if isTransition {
+			e.Info(fmt.Sprintf("-- running version %s (%s)", c.Semver().ToString(), c.GetDirection()))
+		}
+
+		if logStatements {
+			e.Debug(fmt.Sprintf("-- executing version %s: %s", c.Semver().ToString(), c.GetQuery()))
+		}
+
+		if onRunCommand != nil {
+			onRunCommand(c)
+		}
+	}
+
+	wrapped := make([]Command, len(commands))
+
+	for i, c := range commands {
+		wrapped[i] = &trackedCommand{Command: c, onRun: onRun}
+	}
+
+	return wrapped
+}
+
+// getCommandLogLevel returns the level given by WithCommandLogLevel.
+// Without it, the level of the config is followed: the statements are
+// logged in case of debug level, otherwise only the version transitions.
+func (e *engine) getCommandLogLevel() string {
+	if e.commandLogLevel != "" {
+		return e.commandLogLevel
+	}
+
+	if e.conf != nil && e.conf.LogLevel == LogLevelDebug {
+		return LogLevelDebug
+	}
+
+	return LogLevelInfo
+}
+
+// withWarnLogging wraps the error handler, so the
+// skipped and repeated commands are logged with warn level.
+func (e *engine) withWarnLogging(onError ErrorHandlerFunc) ErrorHandlerFunc {
+	if _, ok := e.getLogger().(LeveledLogger); !ok {
+		return onError
+	}
+
+	return func(c Command, err error) ErrorAction {
+		action := onError(c, err)
+
+		switch action {
+		case ErrorActionContinue:
+			e.Warn(fmt.Sprintf("-- skipping failed command of version %s", c.Semver().ToString()))
+		case ErrorActionRetry:
+			e.Warn(fmt.Sprintf("-- retrying failed command of version %s", c.Semver().ToString()))
+		}
+
+		return action
+	}
+}
+
+// getErrorHandler returns the registered error handler. Without one,
+// the execution stops at the first problem in case of transaction,
+// otherwise the error is logged and the execution continues.
+func (e *engine) getErrorHandler() ErrorHandlerFunc {
+	if e.onError != nil {
+		return e.onError
+	}
+
+	if e.conf.WithTransaction {
+		return func(Command, error) ErrorAction {
+			return ErrorActionStop
+		}
+	}
+
+	return func(_ Command, err error) ErrorAction {
+		e.Error(fmt.Sprintf("execution error: %v", err))
+
+		return ErrorActionContinue
+	}
+}
+
+func runCommands(commands []Command, onError ErrorHandlerFunc) error {
+	for i := 0; i < len(commands); i++ {
+		// The consecutive commands of the same version are executed
+		// by a single ExecMulti call, if it is possible.
+		if batch := getCommandBatch(commands[i:]); len(batch) > 1 {
+			if err := runCommandBatch(batch, onError); err != nil {
+				return err
+			}
+
+			i += len(batch) - 1
+
+			continue
+		}
+
+		c := commands[i]
+
+		for {
+			err := c.Run()
+			if err == nil {
+				break
+			}
+
+			// Neither retrying, nor continuing makes sense without the
+			// transaction, or after the context of the run is done.
+			if isAbortingError(err) {
+				return err
+			}
+
+			action := onError(c, err)
+
+			if action == ErrorActionStop {
+				return err
+			}
+
+			if action == ErrorActionContinue {
+				break
+			}
+		}
+	}
+
+	return nil
+}
+
+// getCommandBatch returns the leading commands, which can be executed
+// together: they have the same version, direction and database.
+func getCommandBatch(commands []Command) []Command {
+	first := unwrapCommand(commands[0])
+	if first == nil || first.db == nil {
+		return nil
+	}
+
+	batch := []Command{commands[0]}
+
+	for _, c := range commands[1:] {
+		cmd := unwrapCommand(c)
+
+		if cmd == nil || cmd.db != first.db || cmd.dir != first.dir || !cmd.version.Equals(first.version) {
+			break
+		}
+
+		batch = append(batch, c)
+	}
+
+	return batch
+}
+
+// runCommandBatch executes the given batch by ExecMulti calls. The error
+// handling is the same as running the commands one after another: the
+// handler gets the failing command, and the execution goes on based upon
+// its action. The commands are reported as run after their execution.
+func runCommandBatch(batch []Command, onError ErrorHandlerFunc) error {
+	var (
+		db   = unwrapCommand(batch[0]).db
+		next = 0
+	)
+
+	for next < len(batch) {
+		queries := make([]string, 0, len(batch)-next)
+
+		for _, c := range batch[next:] {
+			queries = append(queries, c.GetQuery())
+		}
+
+		err := db.ExecMulti(queries)
+		if err == nil {
+			reportRuns(batch[next:])
+
+			return nil
+		}
+
+		// The failed index is relative to the executed queries.
+		failed := next
+
+		var multiErr *database.ExecMultiError
+
+		if errors.As(err, &multiErr) {
+			failed += multiErr.Index
+			err = multiErr.Err
+		}
+
+		reportRuns(batch[next : failed+1])
+
+		if isAbortingError(err) {
+			return err
+		}
+
+		switch onError(batch[failed], err) {
+		case ErrorActionStop:
+			return err
+		case ErrorActionContinue:
+			next = failed + 1
+		default:
+			next = failed
+		}
+	}
+
+	return nil
+}
+
+// unwrapCommand returns the underlying *command, or <nil>.
+func unwrapCommand(c Command) *command {
+	if t, ok := c.(*trackedCommand); ok {
+		c = t.Command
+	}
+
+	cmd, _ := c.(*command)
+
+	return cmd
+}
+
+// reportRuns notifies the tracked commands about their runs.
+func reportRuns(commands []Command) {
+	for _, c := range commands {
+		if t, ok := c.(*trackedCommand); ok {
+			t.onRun(t.Command)
+		}
+	}
+}
+
+// getVersionDescription returns the description of the given version.
+func getVersionDescription(version Semver, commands []Command) string {
+	for _, c := range commands {
+		if c.Semver().Equals(version) && c.GetDescription() != "" {
+			return c.GetDescription()
+		}
+	}
+
+	return ""
+}
+
+// sortCommandsDescending sorts the commands by their versions in descending
+// order, while keeping the order of the commands within the same version.
+func sortCommandsDescending(commands []Command) {
+	sort.SliceStable(commands, func(i, j int) bool {
+		return commands[i].Semver().GreaterThan(commands[j].Semver())
+	})
+}
+
+// getLatestVersion returns the highest version of the given commands,
+// or <nil> in case of empty slice.
+func getLatestVersion(commands []Command) Semver {
+	var sv Semver
 
 	for _, c := range commands {
 		if sv == nil {
@@ -1,11 +1,23 @@
 package dbmigrator
 
 import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
 	"errors"
 	"fmt"
+	"io"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/balazskvancz/dbmigrator/database"
+	"github.com/balazskvancz/dbmigrator/models"
 	"github.com/balazskvancz/dbmigrator/repositories"
 )
 
@@ -13,6 +25,20 @@ type mockMigrationsRepository struct {
 	doesExists  bool
 	createError error
 
+	all      []*models.Migration
+	allError error
+
+	latest      *models.Migration
+	latestError error
+
+	recreateSteps []string
+	recreateError error
+
+	inserted     []string
+	deletedAll   bool
+	deletedBatch string
+	deleteError  error
+
 	repositories.MigrationsRepository
 }
 
@@ -24,6 +50,67 @@ func (mr *mockMigrationsRepository) CreateTable() error {
 	return mr.createError
 }
 
+func (mr *mockMigrationsRepository) GetAll() ([]*models.Migration, error) {
+	return mr.all, mr.allError
+}
+
+func (mr *mockMigrationsRepository) GetLatest() (*models.Migration, error) {
+	return mr.latest, mr.latestError
+}
+
+// GetLatestN returns the last n entities of all in reverse order.
+func (mr *mockMigrationsRepository) GetLatestN(n int) ([]*models.Migration, error) {
+	if n <= 0 {
+		return nil, repositories.ErrInvalidLimit
+	}
+
+	latest := make([]*models.Migration, 0, n)
+
+	for i := len(mr.all) - 1; i >= 0 && len(latest) < n; i-- {
+		latest = append(latest, mr.all[i])
+	}
+
+	return latest, mr.allError
+}
+
+func (mr *mockMigrationsRepository) GetByVersion(version string) (*models.Migration, error) {
+	var found *models.Migration
+
+	for _, m := range mr.all {
+		if m.Version == version {
+			found = m
+		}
+	}
+
+	return found, mr.allError
+}
+
+func (mr *mockMigrationsRepository) Insert(version, _, _ string, _ int64) error {
+	mr.inserted = append(mr.inserted, version)
+
+	return nil
+}
+
+func (mr *mockMigrationsRepository) DeleteAll() error {
+	mr.deletedAll = true
+
+	return mr.deleteError
+}
+
+func (mr *mockMigrationsRepository) DeleteBatch(batchId string) error {
+	mr.deletedBatch = batchId
+
+	return mr.deleteError
+}
+
+func (mr *mockMigrationsRepository) Recreate(onStep func(string)) error {
+	for _, step := range mr.recreateSteps {
+		onStep(step)
+	}
+
+	return mr.recreateError
+}
+
 func newMockRepo(doesExists bool, createError error) *repositories.Repositories {
 	return &repositories.Repositories{
 		Migrations: &mockMigrationsRepository{
@@ -48,17 +135,17 @@ func TestGetLatestVersion(t *testing.T) {
 
 	tt := []testCase{
 		{
-			name:           "returns bottomVersion, in case of empty slice",
+			name:           "returns <nil>, in case of empty slice",
 			commands:       []Command{},
-			expectedSemver: bottomVersion,
+			expectedSemver: nil,
 		},
 
 		{
 			name: "returns the actual latest, in case of non-empty slice",
 			commands: []Command{
-				newCommand(nil, "", ver1),
-				newCommand(nil, "", ver3),
-				newCommand(nil, "", ver2),
+				mustNewCommand(nil, "", ver1, DirectionUp),
+				mustNewCommand(nil, "", ver3, DirectionUp),
+				mustNewCommand(nil, "", ver2, DirectionUp),
 			},
 			expectedSemver: ver3,
 		},
@@ -186,7 +273,7 @@ func TestParseLines(t *testing.T) {
 				");",
 			},
 			expectedCommands: []Command{
-				newCommand(nil, "CREATE TABLE foo ( id INTEGER NOT NULL, PRIMARY KEY (id) );", newSemver("1.1.1")),
+				mustNewCommand(nil, "CREATE TABLE foo ( id INTEGER NOT NULL, PRIMARY KEY (id) );", newSemver("1.1.1"), DirectionUp),
 			},
 			expectedError: nil,
 		},
@@ -211,9 +298,29 @@ func TestParseLines(t *testing.T) {
 				"ALTER TABLE foo DROP COLUMN bar;",
 			},
 			expectedCommands: []Command{
-				newCommand(nil, "CREATE TABLE foo ( id INTEGER NOT NULL, PRIMARY KEY (id) );", newSemver("1")),
-				newCommand(nil, "ALTER TABLE foo ADD COLUMN bar VARCHAR (10) DEFAULT NULL;", newSemver("1")),
-				newCommand(nil, "ALTER TABLE foo DROP COLUMN bar;", newSemver("1.2")),
+				mustNewCommand(nil, "CREATE TABLE foo ( id INTEGER NOT NULL, PRIMARY KEY (id) );", newSemver("1"), DirectionUp),
+				mustNewCommand(nil, "ALTER TABLE foo ADD COLUMN bar VARCHAR (10) DEFAULT NULL;", newSemver("1"), DirectionUp),
+				mustNewCommand(nil, "ALTER TABLE foo DROP COLUMN bar;", newSemver("1.2"), DirectionUp),
+			},
+			expectedError: nil,
+		},
+
+		{
+			name: "returns commands in case of inline multi-line comments",
+			lines: []string{
+				"#v1",
+				"/* inline comment */ ALTER TABLE foo ADD COLUMN bar INT;",
+				"ALTER TABLE foo /* inline comment */ ADD COLUMN baz INT; -- trailing comment",
+				"/* multi-line comment",
+				"still comment */ ALTER TABLE foo DROP COLUMN bar;",
+				"ALTER TABLE foo /* starting comment",
+				"ending comment */ DROP COLUMN baz;",
+			},
+			expectedCommands: []Command{
+				mustNewCommand(nil, "ALTER TABLE foo ADD COLUMN bar INT;", newSemver("1"), DirectionUp),
+				mustNewCommand(nil, "ALTER TABLE foo  ADD COLUMN baz INT;", newSemver("1"), DirectionUp),
+				mustNewCommand(nil, "ALTER TABLE foo DROP COLUMN bar;", newSemver("1"), DirectionUp),
+				mustNewCommand(nil, "ALTER TABLE foo DROP COLUMN baz;", newSemver("1"), DirectionUp),
 			},
 			expectedError: nil,
 		},
@@ -256,14 +363,14 @@ func TestParseLines(t *testing.T) {
 				"DROP TABLE version_2;",
 			},
 			expectedCommands: []Command{
-				newCommand(nil, "CREATE TABLE foo ( id INTEGER NOT NULL, PRIMARY KEY (id) );", newSemver("1"), DirectionUp),
-				newCommand(nil, "ALTER TABLE foo ADD COLUMN bar VARCHAR (10) DEFAULT NULL;", newSemver("1"), DirectionUp),
-				newCommand(nil, "ALTER TABLE DROP foo DROP COLUMN bar;", newSemver("1"), DirectionDown),
-				newCommand(nil, "ALTER TABLE foo DROP COLUMN bar;", newSemver("1.2"), DirectionUp),
-				newCommand(nil, "ALTER TABLE foo ADD COLUMN bar VARCHAR (10) DEFAULT NULL;", newSemver("1.2"), DirectionDown),
-				newCommand(nil, "ALTER TABLE foo ADD COLUMN baz INTEGER NOT NULL;", newSemver("1.2"), DirectionUp),
-				newCommand(nil, "CREATE TABLE version_2 ( id INTEGER NOT NULL );", newSemver("2"), DirectionUp),
-				newCommand(nil, "DROP TABLE version_2;", newSemver("2"), DirectionDown),
+				mustNewCommand(nil, "CREATE TABLE foo ( id INTEGER NOT NULL, PRIMARY KEY (id) );", newSemver("1"), DirectionUp),
+				mustNewCommand(nil, "ALTER TABLE foo ADD COLUMN bar VARCHAR (10) DEFAULT NULL;", newSemver("1"), DirectionUp),
+				mustNewCommand(nil, "ALTER TABLE DROP foo DROP COLUMN bar;", newSemver("1"), DirectionDown),
+				mustNewCommand(nil, "ALTER TABLE foo DROP COLUMN bar;", newSemver("1.2"), DirectionUp),
+				mustNewCommand(nil, "ALTER TABLE foo ADD COLUMN bar VARCHAR (10) DEFAULT NULL;", newSemver("1.2"), DirectionDown),
+				mustNewCommand(nil, "ALTER TABLE foo ADD COLUMN baz INTEGER NOT NULL;", newSemver("1.2"), DirectionUp),
+				mustNewCommand(nil, "CREATE TABLE version_2 ( id INTEGER NOT NULL );", newSemver("2"), DirectionUp),
+				mustNewCommand(nil, "DROP TABLE version_2;", newSemver("2"), DirectionDown),
 			},
 			expectedError: nil,
 		},
@@ -286,6 +393,183 @@ func TestParseLines(t *testing.T) {
 	}
 }
 
+func TestParseLinesWithDescription(t *testing.T) {
+	lines := []string{
+		"#v1",
+		"CREATE TABLE foo (id INTEGER NOT NULL);",
+		"#[DESC] creation of",
+		"#[DESC] table foo",
+		"#[DOWN]",
+		"DROP TABLE foo;",
+		"#v2",
+		"ALTER TABLE foo ADD COLUMN bar INT;",
+	}
+
+	e := &engine{}
+
+	commands, err := e.ParseLines(lines)
+	if err != nil {
+		t.Fatalf("expected error: <nil>; got error: %v\n", err)
+	}
+
+	expected := []string{"creation of table foo", "creation of table foo", ""}
+
+	if len(commands) != len(expected) {
+		t.Fatalf("expected commands: %d; got: %d\n", len(expected), len(commands))
+	}
+
+	for i, c := range commands {
+		if c.GetDescription() != expected[i] {
+			t.Errorf("expected description: %q; got: %q\n", expected[i], c.GetDescription())
+		}
+	}
+
+	if got := getVersionDescription(newSemver("1"), commands); got != expected[0] {
+		t.Errorf("expected version description: %q; got: %q\n", expected[0], got)
+	}
+}
+
+func TestParseLinesWithCustomMarkers(t *testing.T) {
+	e := &engine{}
+
+	WithVersionPrefix("-- version: ")(e)
+	WithUpCommand("-- migrate:up")(e)
+	WithDownCommand("-- migrate:down")(e)
+
+	commands, err := e.ParseLines(MigrationLines{
+		"-- version: 1.0.0",
+		"-- migrate:up",
+		"CREATE TABLE foo (id INTEGER NOT NULL);",
+		"-- migrate:down",
+		"DROP TABLE foo;",
+		"-- version: 1.1.0",
+		"-- Adds bar.",
+		"ALTER TABLE foo ADD COLUMN bar INT;",
+	})
+	if err != nil {
+		t.Fatalf("expected error: <nil>; got error: %v\n", err)
+	}
+
+	expected := []struct {
+		version string
+		dir     direction
+	}{
+		{version: "1.0.0", dir: DirectionUp},
+		{version: "1.0.0", dir: DirectionDown},
+		{version: "1.1.0", dir: DirectionUp},
+	}
+
+	if len(commands) != len(expected) {
+		t.Fatalf("expected commands: %d; got: %d\n", len(expected), len(commands))
+	}
+
+	for i, c := range commands {
+		if c.Semver().ToString() != expected[i].version || c.GetDirection() != expected[i].dir {
+			t.Errorf("expected command: %s %s; got: %s %s\n", expected[i].version, expected[i].dir, c.Semver().ToString(), c.GetDirection())
+		}
+	}
+}
+
+func TestParseLinesWithVersionComments(t *testing.T) {
+	type testCase struct {
+		name     string
+		capture  bool
+		lines    MigrationLines
+		expected []string
+	}
+
+	tt := []testCase{
+		{
+			name:    "captures the block comment before the version",
+			capture: true,
+			lines: MigrationLines{
+				"/*",
+				" * Creation of",
+				" * table foo.",
+				" */",
+				"",
+				"#v1",
+				"CREATE TABLE foo (id INTEGER NOT NULL);",
+				"/* Adds bar. */",
+				"#v2",
+				"ALTER TABLE foo ADD COLUMN bar INT;",
+			},
+			expected: []string{"Creation of table foo.", "Adds bar."},
+		},
+		{
+			name:    "skips the comment, which is followed by a statement",
+			capture: true,
+			lines: MigrationLines{
+				"#v1",
+				"/* Not a version comment. */",
+				"CREATE TABLE foo (id INTEGER NOT NULL);",
+				"#v2",
+				"ALTER TABLE foo ADD COLUMN bar INT;",
+			},
+			expected: []string{"", ""},
+		},
+		{
+			name:    "does not capture anything without the option",
+			capture: false,
+			lines: MigrationLines{
+				"/* Creation of table foo. */",
+				"#v1",
+				"CREATE TABLE foo (id INTEGER NOT NULL);",
+			},
+			expected: []string{""},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			e := &engine{captureVersionComments: tc.capture}
+
+			commands, err := e.ParseLines(tc.lines)
+			if err != nil {
+				t.Fatalf("expected error: <nil>; got error: %v\n", err)
+			}
+
+			if len(commands) != len(tc.expected) {
+				t.Fatalf("expected commands: %d; got: %d\n", len(tc.expected), len(commands))
+			}
+
+			for i, c := range commands {
+				if c.GetDescription() != tc.expected[i] {
+					t.Errorf("expected description: %q; got: %q\n", tc.expected[i], c.GetDescription())
+				}
+			}
+		})
+	}
+}
+
+func TestParseLinesParseError(t *testing.T) {
+	lines := []string{
+		"#v1",
+		"CREATE TABLE foo (id INTEGER NOT NULL);",
+		"",
+		"  #va.b",
+		"DROP TABLE foo;",
+	}
+
+	e := &engine{}
+
+	_, err := e.ParseLines(lines)
+
+	var parseErr *ParseError
+
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("expected *ParseError; got error: %v\n", err)
+	}
+
+	if parseErr.Line != 4 || parseErr.Column != 3 {
+		t.Errorf("expected position: 4:3; got: %d:%d\n", parseErr.Line, parseErr.Column)
+	}
+
+	if !errors.Is(err, ErrBadVersioning) {
+		t.Errorf("expected error: %v; got error: %v\n", ErrBadVersioning, err)
+	}
+}
+
 func TestFilterCommands(t *testing.T) {
 	type testCase struct {
 		name     string
@@ -298,11 +582,11 @@ func TestFilterCommands(t *testing.T) {
 	}
 
 	var (
-		c1 Command = newCommand(nil, "", newSemver("1.1.1"))
-		c2 Command = newCommand(nil, "", newSemver("2.0.1"))
-		c3 Command = newCommand(nil, "", newSemver("3.4.1"))
-		c4 Command = newCommand(nil, "", newSemver("4.1.2"))
-		c5 Command = newCommand(nil, "", newSemver("4.1.2"), DirectionDown)
+		c1 Command = mustNewCommand(nil, "", newSemver("1.1.1"), DirectionUp)
+		c2 Command = mustNewCommand(nil, "", newSemver("2.0.1"), DirectionUp)
+		c3 Command = mustNewCommand(nil, "", newSemver("3.4.1"), DirectionUp)
+		c4 Command = mustNewCommand(nil, "", newSemver("4.1.2"), DirectionUp)
+		c5 Command = mustNewCommand(nil, "", newSemver("4.1.2"), DirectionDown)
 	)
 
 	tt := []testCase{
@@ -400,10 +684,10 @@ func TestGetPreviousSemver(t *testing.T) {
 			name: "expecting the right version",
 			cr:   newSemver("1.2.1"),
 			commands: []Command{
-				newCommand(nil, "", newSemver("1.2.1")),
-				newCommand(nil, "", newSemver("1.4.1")),
-				newCommand(nil, "", newSemver("1.3.1")),
-				newCommand(nil, "", newSemver("1.0.1")),
+				mustNewCommand(nil, "", newSemver("1.2.1"), DirectionUp),
+				mustNewCommand(nil, "", newSemver("1.4.1"), DirectionUp),
+				mustNewCommand(nil, "", newSemver("1.3.1"), DirectionUp),
+				mustNewCommand(nil, "", newSemver("1.0.1"), DirectionUp),
 			},
 			prev: newSemver("1.0.1"),
 		},
@@ -419,3 +703,1612 @@ func TestGetPreviousSemver(t *testing.T) {
 		})
 	}
 }
+
+func TestParseLinesWithVersionValidator(t *testing.T) {
+	type testCase struct {
+		name      string
+		lines     []string
+		validator func(Semver) error
+
+		expectedError error
+	}
+
+	var errNoPatch error = errors.New("missing patch version")
+
+	validator := func(sv Semver) error {
+		if sv.GetPatch() == 0 {
+			return errNoPatch
+		}
+
+		return nil
+	}
+
+	tt := []testCase{
+		{
+			name: "returns <nil> if every version is valid",
+			lines: []string{
+				"#v1.0.1",
+				"ALTER TABLE foo DROP COLUMN bar;",
+				"#v1.0.2",
+				"ALTER TABLE foo DROP COLUMN baz;",
+			},
+			validator:     validator,
+			expectedError: nil,
+		},
+		{
+			name: "returns the validator's error in case of invalid version",
+			lines: []string{
+				"#v1.0.1",
+				"ALTER TABLE foo DROP COLUMN bar;",
+				"#v1.1",
+				"ALTER TABLE foo DROP COLUMN baz;",
+			},
+			validator:     validator,
+			expectedError: errNoPatch,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			e := &engine{}
+
+			WithVersionValidator(tc.validator)(e)
+
+			_, err := e.ParseLines(tc.lines)
+
+			if !errors.Is(err, tc.expectedError) {
+				t.Errorf("expected error: %v; got error: %v\n", tc.expectedError, err)
+			}
+		})
+	}
+}
+
+type mockCommand struct {
+	errors []error
+	runs   int
+
+	Command
+}
+
+// Run returns the stored errors one after another,
+// then <nil> once they are exhausted.
+func (mc *mockCommand) Run() error {
+	mc.runs++
+
+	if len(mc.errors) == 0 {
+		return nil
+	}
+
+	err := mc.errors[0]
+	mc.errors = mc.errors[1:]
+
+	return err
+}
+
+func TestRunCommands(t *testing.T) {
+	type testCase struct {
+		name    string
+		errors  []error
+		onError ErrorHandlerFunc
+
+		expectedError       error
+		expectedFailingRuns int
+		expectedNextRuns    int
+	}
+
+	var (
+		runErr error = errors.New("mock-error")
+
+		stop = func(Command, error) ErrorAction { return ErrorActionStop }
+		cont = func(Command, error) ErrorAction { return ErrorActionContinue }
+	)
+
+	tt := []testCase{
+		{
+			name:                "returns <nil>, if every command runs successfully",
+			errors:              nil,
+			onError:             stop,
+			expectedError:       nil,
+			expectedFailingRuns: 1,
+			expectedNextRuns:    1,
+		},
+		{
+			name:                "returns the error in case of stop action",
+			errors:              []error{runErr},
+			onError:             stop,
+			expectedError:       runErr,
+			expectedFailingRuns: 1,
+			expectedNextRuns:    0,
+		},
+		{
+			name:                "returns <nil> in case of continue action",
+			errors:              []error{runErr},
+			onError:             cont,
+			expectedError:       nil,
+			expectedFailingRuns: 1,
+			expectedNextRuns:    1,
+		},
+		{
+			name:   "reruns the command in case of retry action",
+			errors: []error{runErr, runErr},
+			onError: func(Command, error) ErrorAction {
+				return ErrorActionRetry
+			},
+			expectedError:       nil,
+			expectedFailingRuns: 3,
+			expectedNextRuns:    1,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				failing = &mockCommand{errors: tc.errors}
+				next    = &mockCommand{}
+			)
+
+			err := runCommands([]Command{failing, next}, tc.onError)
+
+			if !errors.Is(err, tc.expectedError) {
+				t.Errorf("expected error: %v; got error: %v\n", tc.expectedError, err)
+			}
+
+			if failing.runs != tc.expectedFailingRuns {
+				t.Errorf("expected runs of failing command: %d; got: %d\n", tc.expectedFailingRuns, failing.runs)
+			}
+
+			if next.runs != tc.expectedNextRuns {
+				t.Errorf("expected runs of next command: %d; got: %d\n", tc.expectedNextRuns, next.runs)
+			}
+		})
+	}
+}
+
+func TestGetAppliedVersions(t *testing.T) {
+	type testCase struct {
+		name       string
+		doesExists bool
+		history    []*models.Migration
+
+		expectedVersions []Semver
+		expectedError    error
+	}
+
+	tt := []testCase{
+		{
+			name:             "returns empty slice without migrations table",
+			doesExists:       false,
+			history:          nil,
+			expectedVersions: []Semver{},
+			expectedError:    nil,
+		},
+		{
+			name:             "returns empty slice in case of no history",
+			doesExists:       true,
+			history:          nil,
+			expectedVersions: []Semver{},
+			expectedError:    nil,
+		},
+		{
+			name:       "returns error in case of invalid stored version",
+			doesExists: true,
+			history: []*models.Migration{
+				{Version: "1.0.0"},
+				{Version: "abc"},
+			},
+			expectedVersions: nil,
+			expectedError:    ErrInvalidLastVersion,
+		},
+		{
+			name:       "returns the unique versions in ascending order",
+			doesExists: true,
+			history: []*models.Migration{
+				{Version: "1.0.0"},
+				{Version: "1.2.0"},
+				{Version: "1.1.0"},
+				{Version: "1.2.0"},
+			},
+			expectedVersions: []Semver{
+				newSemver("1.0.0"),
+				newSemver("1.1.0"),
+				newSemver("1.2.0"),
+			},
+			expectedError: nil,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			e := &engine{
+				repositories: &repositories.Repositories{
+					Migrations: &mockMigrationsRepository{doesExists: tc.doesExists, all: tc.history},
+				},
+			}
+
+			versions, err := e.GetAppliedVersions()
+
+			if !errors.Is(err, tc.expectedError) {
+				t.Errorf("expected error: %v; got error: %v\n", tc.expectedError, err)
+			}
+
+			if !reflect.DeepEqual(versions, tc.expectedVersions) {
+				t.Errorf("expected versions: %v; got: %v\n", tc.expectedVersions, versions)
+			}
+		})
+	}
+}
+
+type mockLockerDatabase struct {
+	lockError error
+	unlocked  bool
+
+	database.Database
+}
+
+func (md *mockLockerDatabase) Lock(string, time.Duration) error { return md.lockError }
+
+func (md *mockLockerDatabase) Unlock(string) error {
+	md.unlocked = true
+
+	return nil
+}
+
+func (md *mockLockerDatabase) GetDatabaseName() string { return "foo" }
+
+func TestLock(t *testing.T) {
+	type testCase struct {
+		name                 string
+		db                   database.Database
+		postgresAdvisoryLock bool
+		expectedError        error
+	}
+
+	tt := []testCase{
+		{
+			name:          "returns error, if the database does not support locking",
+			db:            newMockDatabase(nil),
+			expectedError: ErrLockNotSupported,
+		},
+		{
+			name:          "returns error, if the lock could not be acquired",
+			db:            &mockLockerDatabase{lockError: ErrLockNotAcquired},
+			expectedError: ErrLockNotAcquired,
+		},
+		{
+			name:          "returns <nil>, if the lock is acquired",
+			db:            &mockLockerDatabase{},
+			expectedError: nil,
+		},
+		{
+			name:                 "returns error in case of advisory lock without postgres",
+			db:                   &mockLockerDatabase{},
+			postgresAdvisoryLock: true,
+			expectedError:        ErrLockNotSupported,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			e := &engine{
+				db:                   tc.db,
+				postgresAdvisoryLock: tc.postgresAdvisoryLock,
+				repositories:         repositories.New(tc.db, ""),
+			}
+
+			unlock, err := e.lock()
+
+			if !errors.Is(err, tc.expectedError) {
+				t.Errorf("expected error: %v; got error: %v\n", tc.expectedError, err)
+			}
+
+			if err != nil {
+				return
+			}
+
+			unlock()
+
+			if !tc.db.(*mockLockerDatabase).unlocked {
+				t.Error("expected the lock to be released")
+			}
+		})
+	}
+}
+
+// scriptedResult is the result of a query of the scripted driver.
+type scriptedResult struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+// scriptedDriver answers the queries by the results of the script,
+// whose name is the DSN. The queries are matched regardless of their
+// whitespaces, an unknown query is an error. Every exec succeeds.
+type scriptedDriver struct {
+	mu      sync.Mutex
+	scripts map[string]map[string]scriptedResult
+	execs   map[string][]string
+}
+
+type scriptedConn struct {
+	d    *scriptedDriver
+	name string
+}
+
+type scriptedRows struct {
+	scriptedResult
+	pos int
+}
+
+func (d *scriptedDriver) Open(name string) (driver.Conn, error) {
+	return &scriptedConn{d: d, name: name}, nil
+}
+
+func (c *scriptedConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+
+func (c *scriptedConn) Close() error { return nil }
+
+func (c *scriptedConn) Begin() (driver.Tx, error) { return nil, errors.New("not supported") }
+
+func (c *scriptedConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	c.d.mu.Lock()
+	defer c.d.mu.Unlock()
+
+	c.d.execs[c.name] = append(c.d.execs[c.name], normalizeQuery(query))
+
+	return driver.RowsAffected(0), nil
+}
+
+func (c *scriptedConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	c.d.mu.Lock()
+	defer c.d.mu.Unlock()
+
+	result, ok := c.d.scripts[c.name][normalizeQuery(query)]
+	if !ok {
+		return nil, fmt.Errorf("unexpected query: %s", normalizeQuery(query))
+	}
+
+	return &scriptedRows{scriptedResult: result}, nil
+}
+
+func (r *scriptedRows) Columns() []string { return r.columns }
+
+func (r *scriptedRows) Close() error { return nil }
+
+func (r *scriptedRows) Next(dest []driver.Value) error {
+	if r.pos == len(r.rows) {
+		return io.EOF
+	}
+
+	copy(dest, r.rows[r.pos])
+	r.pos++
+
+	return nil
+}
+
+func normalizeQuery(query string) string { return strings.Join(strings.Fields(query), " ") }
+
+var scripted = &scriptedDriver{
+	scripts: make(map[string]map[string]scriptedResult),
+	execs:   make(map[string][]string),
+}
+
+func init() {
+	sql.Register("dbmigrator-scripted", scripted)
+}
+
+// mockScriptedDatabase runs the queries with the scripted driver.
+type mockScriptedDatabase struct {
+	driver string
+	name   string
+	db     *sql.DB
+	schema []database.TableSchema
+
+	database.Database
+}
+
+// newMockScriptedDatabase returns a database answering the queries by
+// the given script, which is named after the test. The queries of the
+// script are given with single spaces between the words.
+func newMockScriptedDatabase(t *testing.T, driverName string, script map[string]scriptedResult) *mockScriptedDatabase {
+	t.Helper()
+
+	scripted.mu.Lock()
+	scripted.scripts[t.Name()] = script
+	scripted.execs[t.Name()] = nil
+	scripted.mu.Unlock()
+
+	db, err := sql.Open("dbmigrator-scripted", t.Name())
+	if err != nil {
+		t.Fatalf("expected error: <nil>; got error: %v\n", err)
+	}
+
+	t.Cleanup(func() { db.Close() })
+
+	return &mockScriptedDatabase{driver: driverName, name: "foo", db: db}
+}
+
+// execs returns the statements executed by the given database.
+func (md *mockScriptedDatabase) execs(t *testing.T) []string {
+	scripted.mu.Lock()
+	defer scripted.mu.Unlock()
+
+	return scripted.execs[t.Name()]
+}
+
+func (md *mockScriptedDatabase) GetDriverName() string { return md.driver }
+
+func (md *mockScriptedDatabase) GetDatabaseName() string { return md.name }
+
+func (md *mockScriptedDatabase) Schema() ([]database.TableSchema, error) { return md.schema, nil }
+
+func (md *mockScriptedDatabase) Exec(query string, args ...any) (sql.Result, error) {
+	return md.db.Exec(query, args...)
+}
+
+func (md *mockScriptedDatabase) Query(query string, args ...any) (*sql.Rows, error) {
+	return md.db.Query(query, args...)
+}
+
+func (md *mockScriptedDatabase) QueryRow(query string, args ...any) *sql.Row {
+	return md.db.QueryRow(query, args...)
+}
+
+func TestCheckReadOnly(t *testing.T) {
+	type testCase struct {
+		name          string
+		driver        string
+		script        map[string]scriptedResult
+		expectedError error
+	}
+
+	readOnly := func(query string, value driver.Value) map[string]scriptedResult {
+		return map[string]scriptedResult{
+			query: {columns: []string{"read_only"}, rows: [][]driver.Value{{value}}},
+		}
+	}
+
+	tt := []testCase{
+		{
+			name:          "returns error, if the mysql server is read-only",
+			driver:        "mysql",
+			script:        readOnly("SELECT @@read_only", int64(1)),
+			expectedError: ErrDatabaseReadOnly,
+		},
+		{
+			name:          "returns <nil>, if the mysql server is writable",
+			driver:        "mysql",
+			script:        readOnly("SELECT @@read_only", int64(0)),
+			expectedError: nil,
+		},
+		{
+			name:          "returns error, if the postgres server is in recovery",
+			driver:        "postgres",
+			script:        readOnly("SELECT pg_is_in_recovery()", true),
+			expectedError: ErrDatabaseReadOnly,
+		},
+		{
+			name:          "returns <nil>, if the postgres server is writable",
+			driver:        "pgx",
+			script:        readOnly("SELECT pg_is_in_recovery()", false),
+			expectedError: nil,
+		},
+		{
+			name:          "returns error in case of unsupported driver",
+			driver:        "sqlite3",
+			script:        nil,
+			expectedError: ErrUnsupportedDriver,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			e := &engine{db: newMockScriptedDatabase(t, tc.driver, tc.script)}
+
+			if err := e.checkReadOnly(); !errors.Is(err, tc.expectedError) {
+				t.Errorf("expected error: %v; got error: %v\n", tc.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestParseLinesWithEnvironment(t *testing.T) {
+	type testCase struct {
+		name        string
+		environment string
+
+		expectedCommands []Command
+	}
+
+	lines := []string{
+		"#v1",
+		"CREATE TABLE foo (id INTEGER NOT NULL);",
+		"#[ENV development, test]",
+		"INSERT INTO foo VALUES (1);",
+		"#[ENV production]",
+		"INSERT INTO foo VALUES (2);",
+		"#[ENV]",
+		"ALTER TABLE foo ADD COLUMN bar INT;",
+		"#[ENV production]",
+		"#v2",
+		"ALTER TABLE foo DROP COLUMN bar;",
+	}
+
+	tt := []testCase{
+		{
+			name:        "returns only the commands without environment, if it is not set",
+			environment: "",
+			expectedCommands: []Command{
+				mustNewCommand(nil, "CREATE TABLE foo (id INTEGER NOT NULL);", newSemver("1"), DirectionUp),
+				mustNewCommand(nil, "ALTER TABLE foo ADD COLUMN bar INT;", newSemver("1"), DirectionUp),
+				mustNewCommand(nil, "ALTER TABLE foo DROP COLUMN bar;", newSemver("2"), DirectionUp),
+			},
+		},
+		{
+			name:        "returns the commands of the matching environment",
+			environment: "test",
+			expectedCommands: []Command{
+				mustNewCommand(nil, "CREATE TABLE foo (id INTEGER NOT NULL);", newSemver("1"), DirectionUp),
+				mustNewCommand(nil, "INSERT INTO foo VALUES (1);", newSemver("1"), DirectionUp),
+				mustNewCommand(nil, "ALTER TABLE foo ADD COLUMN bar INT;", newSemver("1"), DirectionUp),
+				mustNewCommand(nil, "ALTER TABLE foo DROP COLUMN bar;", newSemver("2"), DirectionUp),
+			},
+		},
+		{
+			name:        "returns the commands of the other matching environment",
+			environment: "production",
+			expectedCommands: []Command{
+				mustNewCommand(nil, "CREATE TABLE foo (id INTEGER NOT NULL);", newSemver("1"), DirectionUp),
+				mustNewCommand(nil, "INSERT INTO foo VALUES (2);", newSemver("1"), DirectionUp),
+				mustNewCommand(nil, "ALTER TABLE foo ADD COLUMN bar INT;", newSemver("1"), DirectionUp),
+				mustNewCommand(nil, "ALTER TABLE foo DROP COLUMN bar;", newSemver("2"), DirectionUp),
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			e := &engine{}
+
+			WithEnvironment(tc.environment)(e)
+
+			commands, err := e.ParseLines(lines)
+			if err != nil {
+				t.Fatalf("expected error: <nil>; got error: %v\n", err)
+			}
+
+			if !reflect.DeepEqual(commands, tc.expectedCommands) {
+				t.Errorf("expected commands: %v; got: %v\n", tc.expectedCommands, commands)
+			}
+		})
+	}
+}
+
+func TestMigrateWithCancelledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	e := &engine{}
+
+	if err := e.Migrate(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected error: %v; got error: %v\n", context.Canceled, err)
+	}
+}
+
+func TestExecuteCommands(t *testing.T) {
+	e := &engine{conf: &Config{}}
+
+	if err := e.ExecuteCommands(nil); !errors.Is(err, ErrNothingToRun) {
+		t.Errorf("expected error: %v; got error: %v\n", ErrNothingToRun, err)
+	}
+
+	var (
+		failing = &mockCommand{
+			errors:  []error{errors.New("mock-error")},
+			Command: mustNewCommand(nil, "", newSemver("1.0.0"), DirectionUp),
+		}
+		next = &mockCommand{Command: mustNewCommand(nil, "", newSemver("1.1.0"), DirectionUp)}
+	)
+
+	WithOnError(func(Command, error) ErrorAction { return ErrorActionContinue })(e)
+
+	if err := e.ExecuteCommands([]Command{failing, next}); err != nil {
+		t.Fatalf("expected error: <nil>; got error: %v\n", err)
+	}
+
+	if failing.runs != 1 || next.runs != 1 {
+		t.Errorf("expected runs: 1, 1; got: %d, %d\n", failing.runs, next.runs)
+	}
+
+	stats := e.Stats()
+
+	if stats.CommandsRun != 2 {
+		t.Errorf("expected commands run: 2; got: %d\n", stats.CommandsRun)
+	}
+
+	if expected := []string{"1.0.0", "1.1.0"}; !reflect.DeepEqual(stats.VersionsApplied, expected) {
+		t.Errorf("expected versions applied: %v; got: %v\n", expected, stats.VersionsApplied)
+	}
+
+	if stats.TransactionUsed || stats.RolledBack {
+		t.Errorf("expected no transaction; got: %+v\n", stats)
+	}
+}
+
+func TestTransactionOptions(t *testing.T) {
+	type testCase struct {
+		name     string
+		conf     *Config
+		opt      EngineOptFunc
+		expected bool
+	}
+
+	tt := []testCase{
+		{
+			name:     "WithTransaction overrides the config",
+			conf:     &Config{WithTransaction: false},
+			opt:      WithTransaction(),
+			expected: true,
+		},
+		{
+			name:     "WithNoTransaction overrides the config",
+			conf:     &Config{WithTransaction: true},
+			opt:      WithNoTransaction(),
+			expected: false,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			e := &engine{conf: tc.conf}
+
+			tc.opt(e)
+
+			if e.conf.WithTransaction != tc.expected {
+				t.Errorf("expected WithTransaction: %t; got: %t\n", tc.expected, e.conf.WithTransaction)
+			}
+		})
+	}
+}
+
+func TestProcessHooks(t *testing.T) {
+	var (
+		hookErr = errors.New("mock-error")
+		calls   = make([]string, 0)
+		gotErr  error
+	)
+
+	e := &engine{}
+
+	WithBeforeProcess(func() error {
+		calls = append(calls, "before-1")
+
+		return hookErr
+	})(e)
+
+	WithBeforeProcess(func() error {
+		calls = append(calls, "before-2")
+
+		return nil
+	})(e)
+
+	WithAfterProcess(func(err error) {
+		calls = append(calls, "after")
+		gotErr = err
+	})(e)
+
+	if err := e.Process(); !errors.Is(err, hookErr) {
+		t.Errorf("expected error: %v; got error: %v\n", hookErr, err)
+	}
+
+	if expected := []string{"before-1", "after"}; !reflect.DeepEqual(calls, expected) {
+		t.Errorf("expected calls: %v; got: %v\n", expected, calls)
+	}
+
+	if !errors.Is(gotErr, hookErr) {
+		t.Errorf("expected error in after hook: %v; got error: %v\n", hookErr, gotErr)
+	}
+}
+
+func TestNewWithLazyConnect(t *testing.T) {
+	e, err := New(&Config{DriverName: "unregistered"}, WithLazyConnect())
+	if err != nil {
+		t.Fatalf("expected error: <nil>; got error: %v\n", err)
+	}
+
+	e.CloseDatabase()
+}
+
+func TestWithDatabaseName(t *testing.T) {
+	type testCase struct {
+		name     string
+		opts     []EngineOptFunc
+		expected string
+	}
+
+	tt := []testCase{
+		{
+			name:     "uses the database of the config without the option",
+			opts:     []EngineOptFunc{},
+			expected: "foo",
+		},
+		{
+			name:     "overrides the database of the config",
+			opts:     []EngineOptFunc{WithDatabaseName("bar")},
+			expected: "bar",
+		},
+		{
+			name:     "keeps the database of the config in case of empty name",
+			opts:     []EngineOptFunc{WithDatabaseName("")},
+			expected: "foo",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			conf := &Config{DriverName: "unregistered", Database: "foo"}
+
+			e, err := New(conf, append(tc.opts, WithLazyConnect())...)
+			if err != nil {
+				t.Fatalf("expected error: <nil>; got error: %v\n", err)
+			}
+			defer e.CloseDatabase()
+
+			if got := e.(*engine).db.GetDatabaseName(); got != tc.expected {
+				t.Errorf("expected database name: %s; got: %s\n", tc.expected, got)
+			}
+
+			// The option must not modify the given config.
+			if conf.Database != "foo" {
+				t.Errorf("expected config database: %s; got: %s\n", "foo", conf.Database)
+			}
+		})
+	}
+}
+
+func TestNewWithDatabase(t *testing.T) {
+	if _, err := NewWithDatabase(nil, &Config{}); !errors.Is(err, ErrDatabaseIsNil) {
+		t.Errorf("expected error: %v; got error: %v\n", ErrDatabaseIsNil, err)
+	}
+
+	db := &mockNamedDatabase{name: "foo"}
+
+	e, err := NewWithDatabase(db, &Config{DriverName: "unregistered"})
+	if err != nil {
+		t.Fatalf("expected error: <nil>; got error: %v\n", err)
+	}
+
+	if got := e.(*engine).db; got != db {
+		t.Errorf("expected the given database to be used; got: %v\n", got)
+	}
+}
+
+type mockClosingDatabase struct {
+	closed bool
+
+	database.Database
+}
+
+func (md *mockClosingDatabase) Close() { md.closed = true }
+
+func TestMigrateAndClose(t *testing.T) {
+	db := &mockClosingDatabase{}
+
+	e := &engine{
+		conf:         &Config{},
+		db:           db,
+		repositories: newMockRepo(true, nil),
+	}
+
+	if err := e.MigrateAndClose(context.Background()); !errors.Is(err, ErrNoFilePath) {
+		t.Errorf("expected error: %v; got error: %v\n", ErrNoFilePath, err)
+	}
+
+	if !db.closed {
+		t.Errorf("expected the database to be closed in case of error\n")
+	}
+}
+
+func TestGetMigrationTableName(t *testing.T) {
+	type testCase struct {
+		name     string
+		conf     *Config
+		opts     []EngineOptFunc
+		expected string
+	}
+
+	tt := []testCase{
+		{
+			name:     "returns the default name",
+			conf:     &Config{},
+			expected: "__migrations__",
+		},
+		{
+			name:     "returns the configured name qualified with the schema",
+			conf:     &Config{MigrationsTableName: "migrations", MigrationsTableSchema: "myapp"},
+			expected: "myapp.migrations",
+		},
+		{
+			name:     "returns the name given by option",
+			conf:     &Config{MigrationsTableName: "migrations"},
+			opts:     []EngineOptFunc{WithMigrationsTableName("other")},
+			expected: "other",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			e, err := NewWithDatabase(&mockNamedDatabase{}, tc.conf, tc.opts...)
+			if err != nil {
+				t.Fatalf("expected error: <nil>; got error: %v\n", err)
+			}
+
+			if got := e.GetMigrationTableName(); got != tc.expected {
+				t.Errorf("expected table name: %s; got: %s\n", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestGetCurrentVersion(t *testing.T) {
+	type testCase struct {
+		name        string
+		latest      *models.Migration
+		latestError error
+
+		expected      Semver
+		expectedError error
+	}
+
+	latestError := errors.New("mock-error")
+
+	tt := []testCase{
+		{
+			name:     "returns <nil> without stored migration",
+			expected: nil,
+		},
+		{
+			name:     "returns the stored version",
+			latest:   &models.Migration{Version: "1.2.0"},
+			expected: newSemver("1.2.0"),
+		},
+		{
+			name:          "returns the error of the repository",
+			latestError:   latestError,
+			expectedError: latestError,
+		},
+		{
+			name:          "returns error in case of invalid stored version",
+			latest:        &models.Migration{Version: "foo"},
+			expectedError: ErrInvalidLastVersion,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			e := &engine{
+				repositories: &repositories.Repositories{
+					Migrations: &mockMigrationsRepository{
+						doesExists:  true,
+						latest:      tc.latest,
+						latestError: tc.latestError,
+					},
+				},
+			}
+
+			got, err := e.GetCurrentVersion()
+			if !errors.Is(err, tc.expectedError) {
+				t.Errorf("expected error: %v; got error: %v\n", tc.expectedError, err)
+			}
+
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected version: %v; got: %v\n", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestGetPendingVersionCount(t *testing.T) {
+	type testCase struct {
+		name     string
+		latest   *models.Migration
+		expected int
+	}
+
+	content := `#v1.0.0
+CREATE TABLE foo (id INT);
+CREATE TABLE bar (id INT);
+#v1.1.0
+#[UP]
+ALTER TABLE foo ADD COLUMN bar INT;
+#[DOWN]
+ALTER TABLE foo DROP COLUMN bar;
+#v1.2.0
+ALTER TABLE bar ADD COLUMN baz INT;
+`
+
+	path := filepath.Join(t.TempDir(), "migrations.sql")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("could not write the migration file: %v\n", err)
+	}
+
+	tt := []testCase{
+		{
+			name:     "returns every version without history",
+			latest:   nil,
+			expected: 3,
+		},
+		{
+			name:     "returns the versions after the current one",
+			latest:   &models.Migration{Version: "1.0.0"},
+			expected: 2,
+		},
+		{
+			name:     "returns zero, if everything is applied",
+			latest:   &models.Migration{Version: "1.2.0"},
+			expected: 0,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			e := &engine{
+				conf: &Config{MigrationsFilePath: path},
+				repositories: &repositories.Repositories{
+					Migrations: &mockMigrationsRepository{doesExists: true, latest: tc.latest},
+				},
+			}
+
+			got, err := e.GetPendingVersionCount()
+			if err != nil {
+				t.Fatalf("expected error: <nil>; got error: %v\n", err)
+			}
+
+			if got != tc.expected {
+				t.Errorf("expected count: %d; got: %d\n", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestGetNextVersion(t *testing.T) {
+	type testCase struct {
+		name     string
+		latest   *models.Migration
+		dir      direction
+		expected Semver
+	}
+
+	content := `#v1.0.0
+CREATE TABLE foo (id INT);
+#v1.1.0
+#[UP]
+ALTER TABLE foo ADD COLUMN bar INT;
+#[DOWN]
+ALTER TABLE foo DROP COLUMN bar;
+#v1.2.0
+CREATE TABLE bar (id INT);
+`
+
+	path := filepath.Join(t.TempDir(), "migrations.sql")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("could not write the migration file: %v\n", err)
+	}
+
+	tt := []testCase{
+		{
+			name:     "returns the first version without history",
+			latest:   nil,
+			dir:      DirectionUp,
+			expected: newSemver("1.0.0"),
+		},
+		{
+			name:     "returns the lowest pending version",
+			latest:   &models.Migration{Version: "1.0.0"},
+			dir:      DirectionUp,
+			expected: newSemver("1.1.0"),
+		},
+		{
+			name:     "returns <nil>, if everything is applied",
+			latest:   &models.Migration{Version: "1.2.0"},
+			dir:      DirectionUp,
+			expected: nil,
+		},
+		{
+			name:     "returns the current version in down direction",
+			latest:   &models.Migration{Version: "1.1.0"},
+			dir:      DirectionDown,
+			expected: newSemver("1.1.0"),
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			e := &engine{
+				conf: &Config{MigrationsFilePath: path},
+				dir:  tc.dir,
+				repositories: &repositories.Repositories{
+					Migrations: &mockMigrationsRepository{doesExists: true, latest: tc.latest},
+				},
+			}
+
+			got, err := e.GetNextVersion()
+			if err != nil {
+				t.Fatalf("expected error: <nil>; got error: %v\n", err)
+			}
+
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected version: %v; got: %v\n", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestGetCommandsForVersion(t *testing.T) {
+	type testCase struct {
+		name          string
+		version       string
+		expected      []string
+		expectedError error
+	}
+
+	content := `#v1.0.0
+CREATE TABLE foo (id INT);
+#v1.1.0
+#[UP]
+ALTER TABLE foo ADD COLUMN bar INT;
+#[DOWN]
+ALTER TABLE foo DROP COLUMN bar;
+`
+
+	path := filepath.Join(t.TempDir(), "migrations.sql")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("could not write the migration file: %v\n", err)
+	}
+
+	tt := []testCase{
+		{
+			name:          "returns error in case of invalid version",
+			version:       "foo",
+			expectedError: ErrBadVersioning,
+		},
+		{
+			name:     "returns both directions of the version",
+			version:  "v1.1",
+			expected: []string{"up: ALTER TABLE foo ADD COLUMN bar INT;", "down: ALTER TABLE foo DROP COLUMN bar;"},
+		},
+		{
+			name:     "returns empty slice in case of unknown version",
+			version:  "2.0.0",
+			expected: []string{},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			e := &engine{conf: &Config{MigrationsFilePath: path}}
+
+			commands, err := e.GetCommandsForVersion(tc.version)
+			if !errors.Is(err, tc.expectedError) {
+				t.Fatalf("expected error: %v; got error: %v\n", tc.expectedError, err)
+			}
+
+			if err != nil {
+				return
+			}
+
+			got := make([]string, 0)
+			for _, c := range commands {
+				got = append(got, fmt.Sprintf("%s: %s", c.GetDirection(), c.GetQuery()))
+			}
+
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected commands: %v; got: %v\n", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestGetCommandCount(t *testing.T) {
+	content := `#v1.0.0
+CREATE TABLE foo (id INT);
+CREATE TABLE bar (id INT);
+#v1.1.0
+#[UP]
+ALTER TABLE foo ADD COLUMN bar INT;
+#[DOWN]
+ALTER TABLE foo DROP COLUMN bar;
+`
+
+	path := filepath.Join(t.TempDir(), "migrations.sql")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("could not write the migration file: %v\n", err)
+	}
+
+	e := &engine{conf: &Config{MigrationsFilePath: path}}
+
+	got, err := e.GetCommandCount()
+	if err != nil {
+		t.Fatalf("expected error: <nil>; got error: %v\n", err)
+	}
+
+	if got != 4 {
+		t.Errorf("expected count: %d; got: %d\n", 4, got)
+	}
+}
+
+type mockMultiDatabase struct {
+	// The queries fail once with the stored error.
+	failures map[string]error
+
+	calls    int
+	executed []string
+
+	database.Database
+}
+
+func (md *mockMultiDatabase) ExecMulti(queries []string) error {
+	md.calls++
+
+	for i, q := range queries {
+		if err, ok := md.failures[q]; ok {
+			delete(md.failures, q)
+
+			return &database.ExecMultiError{Index: i, Err: err}
+		}
+
+		md.executed = append(md.executed, q)
+	}
+
+	return nil
+}
+
+func TestRunCommandBatch(t *testing.T) {
+	type testCase struct {
+		name    string
+		onError ErrorHandlerFunc
+
+		expectedError    error
+		expectedExecuted []string
+		expectedCalls    int
+	}
+
+	var (
+		runErr error = errors.New("mock-error")
+
+		stop  = func(Command, error) ErrorAction { return ErrorActionStop }
+		cont  = func(Command, error) ErrorAction { return ErrorActionContinue }
+		retry = func(Command, error) ErrorAction { return ErrorActionRetry }
+	)
+
+	tt := []testCase{
+		{
+			name:             "stops at the failing command",
+			onError:          stop,
+			expectedError:    runErr,
+			expectedExecuted: []string{"a"},
+			expectedCalls:    1,
+		},
+		{
+			name:             "skips the failing command in case of continue",
+			onError:          cont,
+			expectedError:    nil,
+			expectedExecuted: []string{"a", "c", "d", "e"},
+			expectedCalls:    3,
+		},
+		{
+			name:             "retries the failing command",
+			onError:          retry,
+			expectedError:    nil,
+			expectedExecuted: []string{"a", "b", "c", "d", "e"},
+			expectedCalls:    3,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			db := &mockMultiDatabase{failures: map[string]error{"b": runErr}}
+
+			commands := []Command{
+				mustNewCommand(db, "a", newSemver("1.0.0"), DirectionUp),
+				mustNewCommand(db, "b", newSemver("1.0.0"), DirectionUp),
+				mustNewCommand(db, "c", newSemver("1.0.0"), DirectionUp),
+				mustNewCommand(db, "d", newSemver("1.1.0"), DirectionUp),
+				mustNewCommand(db, "e", newSemver("1.1.0"), DirectionUp),
+			}
+
+			if err := runCommands(commands, tc.onError); !errors.Is(err, tc.expectedError) {
+				t.Errorf("expected error: %v; got error: %v\n", tc.expectedError, err)
+			}
+
+			if !reflect.DeepEqual(db.executed, tc.expectedExecuted) {
+				t.Errorf("expected executed: %v; got: %v\n", tc.expectedExecuted, db.executed)
+			}
+
+			if db.calls != tc.expectedCalls {
+				t.Errorf("expected calls: %d; got: %d\n", tc.expectedCalls, db.calls)
+			}
+		})
+	}
+}
+
+func TestListAppliedVersions(t *testing.T) {
+	e := &engine{
+		repositories: &repositories.Repositories{
+			Migrations: &mockMigrationsRepository{
+				doesExists: true,
+				all: []*models.Migration{
+					{Version: "1.0.0"},
+					{Version: "v1.2"},
+					{Version: "1.0.0"},
+				},
+			},
+		},
+	}
+
+	got, err := e.ListAppliedVersions()
+	if err != nil {
+		t.Fatalf("expected error: <nil>; got error: %v\n", err)
+	}
+
+	if expected := []string{"1.0.0", "v1.2", "1.0.0"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected versions: %v; got: %v\n", expected, got)
+	}
+}
+
+func TestGetHistory(t *testing.T) {
+	type testCase struct {
+		name       string
+		doesExists bool
+		all        []*models.Migration
+		allError   error
+
+		expected      []*models.Migration
+		expectedError error
+	}
+
+	allError := errors.New("mock-error")
+
+	tt := []testCase{
+		{
+			name:          "returns empty slice without migrations table",
+			doesExists:    false,
+			allError:      allError,
+			expected:      []*models.Migration{},
+			expectedError: nil,
+		},
+		{
+			name:          "returns the stored migrations",
+			doesExists:    true,
+			all:           []*models.Migration{{Version: "1.0.0"}, {Version: "1.1.0"}},
+			expected:      []*models.Migration{{Version: "1.0.0"}, {Version: "1.1.0"}},
+			expectedError: nil,
+		},
+		{
+			name:          "returns the error of the repository",
+			doesExists:    true,
+			allError:      allError,
+			expected:      nil,
+			expectedError: allError,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			e := &engine{
+				repositories: &repositories.Repositories{
+					Migrations: &mockMigrationsRepository{
+						doesExists: tc.doesExists,
+						all:        tc.all,
+						allError:   tc.allError,
+					},
+				},
+			}
+
+			history, err := e.GetHistory()
+
+			if !errors.Is(err, tc.expectedError) {
+				t.Errorf("expected error: %v; got error: %v\n", tc.expectedError, err)
+			}
+
+			if !reflect.DeepEqual(history, tc.expected) {
+				t.Errorf("expected history: %v; got: %v\n", tc.expected, history)
+			}
+		})
+	}
+}
+
+func TestGetRecentHistory(t *testing.T) {
+	type testCase struct {
+		name             string
+		n                int
+		expectedVersions []string
+		expectedError    error
+	}
+
+	all := []*models.Migration{
+		{Version: "1.0.0"},
+		{Version: "1.1.0"},
+		{Version: "1.2.0"},
+	}
+
+	tt := []testCase{
+		{
+			name:             "returns error in case of invalid limit",
+			n:                0,
+			expectedVersions: []string{},
+			expectedError:    repositories.ErrInvalidLimit,
+		},
+		{
+			name:             "returns the latest migrations in descending order",
+			n:                2,
+			expectedVersions: []string{"1.2.0", "1.1.0"},
+			expectedError:    nil,
+		},
+		{
+			name:             "returns every migration, if the limit is greater",
+			n:                5,
+			expectedVersions: []string{"1.2.0", "1.1.0", "1.0.0"},
+			expectedError:    nil,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			e := &engine{
+				repositories: &repositories.Repositories{
+					Migrations: &mockMigrationsRepository{all: all},
+				},
+			}
+
+			history, err := e.GetRecentHistory(tc.n)
+
+			if !errors.Is(err, tc.expectedError) {
+				t.Errorf("expected error: %v; got error: %v\n", tc.expectedError, err)
+			}
+
+			versions := make([]string, 0, len(history))
+
+			for _, m := range history {
+				versions = append(versions, m.Version)
+			}
+
+			if !reflect.DeepEqual(versions, tc.expectedVersions) {
+				t.Errorf("expected versions: %v; got: %v\n", tc.expectedVersions, versions)
+			}
+		})
+	}
+}
+
+func TestClearMigrationHistory(t *testing.T) {
+	type testCase struct {
+		name          string
+		deleteError   error
+		expectedError error
+	}
+
+	var deleteError error = errors.New("mock-error")
+
+	tt := []testCase{
+		{
+			name:          "removes every stored migration",
+			deleteError:   nil,
+			expectedError: nil,
+		},
+		{
+			name:          "returns the error of the repository",
+			deleteError:   deleteError,
+			expectedError: deleteError,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := &mockMigrationsRepository{deleteError: tc.deleteError}
+
+			e := &engine{
+				repositories: &repositories.Repositories{Migrations: repo},
+			}
+
+			if err := e.ClearMigrationHistory(); !errors.Is(err, tc.expectedError) {
+				t.Errorf("expected error: %v; got error: %v\n", tc.expectedError, err)
+			}
+
+			if !repo.deletedAll {
+				t.Errorf("expected every migration to be deleted\n")
+			}
+		})
+	}
+}
+
+func TestGetAppliedAt(t *testing.T) {
+	type testCase struct {
+		name          string
+		version       string
+		doesExists    bool
+		expected      *time.Time
+		expectedError error
+	}
+
+	var (
+		appliedAt     = time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+		nextAppliedAt = time.Date(2024, 2, 3, 4, 5, 6, 0, time.UTC)
+	)
+
+	tt := []testCase{
+		{
+			name:          "returns error in case of invalid version",
+			version:       "foo",
+			doesExists:    true,
+			expectedError: ErrBadVersioning,
+		},
+		{
+			name:       "returns <nil> without migrations table",
+			version:    "1.0.0",
+			doesExists: false,
+			expected:   nil,
+		},
+		{
+			name:       "returns <nil> in case of not applied version",
+			version:    "2.0.0",
+			doesExists: true,
+			expected:   nil,
+		},
+		{
+			name:       "returns the time of the applied version",
+			version:    "v1",
+			doesExists: true,
+			expected:   &appliedAt,
+		},
+		{
+			name:       "returns the time of the run, which applied the intermediate version",
+			version:    "1.1.0",
+			doesExists: true,
+			expected:   &nextAppliedAt,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			e := &engine{
+				repositories: &repositories.Repositories{
+					Migrations: &mockMigrationsRepository{
+						doesExists: tc.doesExists,
+						all: []*models.Migration{
+							{Version: "1.0.0", CreatedAt: appliedAt},
+							{Version: "1.2.0", CreatedAt: nextAppliedAt},
+						},
+					},
+				},
+			}
+
+			got, err := e.GetAppliedAt(tc.version)
+			if !errors.Is(err, tc.expectedError) {
+				t.Errorf("expected error: %v; got error: %v\n", tc.expectedError, err)
+			}
+
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected time: %v; got: %v\n", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestForceMigrationsTableRecreate(t *testing.T) {
+	type testCase struct {
+		name          string
+		doesExists    bool
+		recreateError error
+
+		expectedError  error
+		expectedOutput string
+	}
+
+	recreateError := errors.New("mock-error")
+
+	tt := []testCase{
+		{
+			name:          "returns error without migrations table",
+			doesExists:    false,
+			expectedError: ErrMigrationsTableNotExists,
+		},
+		{
+			name:          "logs the steps and returns the error of the recreation",
+			doesExists:    true,
+			recreateError: recreateError,
+			expectedError: recreateError,
+			expectedOutput: "[INFO] -- recreating the migrations table --\n" +
+				"[INFO] -- backing up\n" +
+				"[ERROR] recreating the migrations table failed: mock-error\n",
+		},
+		{
+			name:       "logs every step",
+			doesExists: true,
+			expectedOutput: "[INFO] -- recreating the migrations table --\n" +
+				"[INFO] -- backing up\n" +
+				"[INFO] -- the migrations table is recreated --\n",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			e := &engine{
+				logger: NewWriterLogger(&buf),
+				repositories: &repositories.Repositories{
+					Migrations: &mockMigrationsRepository{
+						doesExists:    tc.doesExists,
+						recreateSteps: []string{"backing up"},
+						recreateError: tc.recreateError,
+					},
+				},
+			}
+
+			if err := e.ForceMigrationsTableRecreate(); !errors.Is(err, tc.expectedError) {
+				t.Errorf("expected error: %v; got error: %v\n", tc.expectedError, err)
+			}
+
+			if got := buf.String(); got != tc.expectedOutput {
+				t.Errorf("expected output: %q; got: %q\n", tc.expectedOutput, got)
+			}
+		})
+	}
+}
+
+type mockHealthDatabase struct {
+	status      database.HealthStatus
+	healthError error
+
+	database.Database
+}
+
+func (md *mockHealthDatabase) HealthCheck() (database.HealthStatus, error) {
+	return md.status, md.healthError
+}
+
+func TestDatabaseHealth(t *testing.T) {
+	type testCase struct {
+		name          string
+		db            *mockHealthDatabase
+		expected      database.HealthStatus
+		expectedError error
+	}
+
+	var healthError error = errors.New("mock-error")
+
+	tt := []testCase{
+		{
+			name: "returns the status of the database",
+			db: &mockHealthDatabase{
+				status: database.HealthStatus{Connected: true, LatencyMs: 3, ServerVersion: "8.0.36"},
+			},
+			expected:      database.HealthStatus{Connected: true, LatencyMs: 3, ServerVersion: "8.0.36"},
+			expectedError: nil,
+		},
+		{
+			name: "returns the partial status along with the error",
+			db: &mockHealthDatabase{
+				status:      database.HealthStatus{TransactionActive: true},
+				healthError: healthError,
+			},
+			expected:      database.HealthStatus{TransactionActive: true},
+			expectedError: healthError,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			e := &engine{db: tc.db}
+
+			status, err := e.DatabaseHealth()
+
+			if !errors.Is(err, tc.expectedError) {
+				t.Errorf("expected error: %v; got error: %v\n", tc.expectedError, err)
+			}
+
+			if status != tc.expected {
+				t.Errorf("expected status: %+v; got: %+v\n", tc.expected, status)
+			}
+		})
+	}
+}
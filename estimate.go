@@ -0,0 +1,107 @@
+package dbmigrator
+
+import (
+	"sort"
+	"time"
+)
+
+// EstimateMigrationTime estimates how long applying the pending versions
+// would take, based upon the durations stored in the migrations table.
+// Since every run is stored with the version it reached, the estimate of
+// a version is the average duration of the runs stored with it. For the
+// versions without such run, the median of every stored duration is used.
+// It returns zero without error, if there is no stored duration.
+func (e *engine) EstimateMigrationTime() (time.Duration, error) {
+	if !e.repositories.Migrations.DoesExists() {
+		return 0, nil
+	}
+
+	history, err := e.GetHistory()
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		all       = make([]int64, 0, len(history))
+		byVersion = make(map[string][]int64)
+	)
+
+	for _, m := range history {
+		// Zero means, that the duration was not stored, eg. by older releases.
+		if m.DurationMs <= 0 {
+			continue
+		}
+
+		sv := newSemver(m.Version)
+		if sv == nil {
+			continue
+		}
+
+		all = append(all, m.DurationMs)
+		byVersion[sv.ToString()] = append(byVersion[sv.ToString()], m.DurationMs)
+	}
+
+	if len(all) == 0 {
+		return 0, nil
+	}
+
+	currentVersion, err := e.GetCurrentVersion()
+	if err != nil {
+		return 0, err
+	}
+
+	if currentVersion == nil {
+		currentVersion = bottomVersion
+	}
+
+	commands, err := e.getCommands()
+	if err != nil {
+		return 0, err
+	}
+
+	var (
+		pending = getUniqueVersions(filterCommands(currentVersion, commands, DirectionUp, nil), nil)
+		median  = getMedian(all)
+		total   int64
+	)
+
+	for _, v := range pending {
+		durations, ok := byVersion[v.ToString()]
+		if !ok {
+			total += median
+
+			continue
+		}
+
+		total += getAverage(durations)
+	}
+
+	return time.Duration(total) * time.Millisecond, nil
+}
+
+// getAverage returns the average of the given non-empty values.
+func getAverage(values []int64) int64 {
+	var sum int64
+
+	for _, v := range values {
+		sum += v
+	}
+
+	return sum / int64(len(values))
+}
+
+// getMedian returns the median of the given non-empty values.
+func getMedian(values []int64) int64 {
+	sorted := make([]int64, len(values))
+	copy(sorted, values)
+
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	mid := len(sorted) / 2
+
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+
+	return sorted[mid]
+}
@@ -0,0 +1,75 @@
+package dbmigrator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/balazskvancz/dbmigrator/models"
+	"github.com/balazskvancz/dbmigrator/repositories"
+)
+
+func TestEstimateMigrationTime(t *testing.T) {
+	type testCase struct {
+		name     string
+		history  []*models.Migration
+		expected time.Duration
+	}
+
+	content := `#v1.0.0
+CREATE TABLE foo (id INT);
+#v1.1.0
+ALTER TABLE foo ADD COLUMN bar INT;
+#v1.2.0
+CREATE TABLE bar (id INT);
+`
+
+	path := filepath.Join(t.TempDir(), "migrations.sql")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("could not write the migration file: %v\n", err)
+	}
+
+	tt := []testCase{
+		{
+			name:     "returns zero without stored durations",
+			history:  []*models.Migration{{Version: "1.0.0"}},
+			expected: 0,
+		},
+		{
+			name: "sums the averages and falls back to the median",
+			history: []*models.Migration{
+				{Version: "1.0.0"},
+				{Version: "1.0.0", DurationMs: 100},
+				{Version: "1.1.0", DurationMs: 300},
+				{Version: "1.1.0", DurationMs: 500},
+			},
+			// 1.1.0: (300 + 500) / 2, 1.2.0: the median.
+			expected: 700 * time.Millisecond,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			e := &engine{
+				conf: &Config{MigrationsFilePath: path},
+				repositories: &repositories.Repositories{
+					Migrations: &mockMigrationsRepository{
+						doesExists: true,
+						all:        tc.history,
+						latest:     &models.Migration{Version: "1.0.0"},
+					},
+				},
+			}
+
+			got, err := e.EstimateMigrationTime()
+			if err != nil {
+				t.Fatalf("expected error: <nil>; got error: %v\n", err)
+			}
+
+			if got != tc.expected {
+				t.Errorf("expected estimate: %v; got: %v\n", tc.expected, got)
+			}
+		})
+	}
+}
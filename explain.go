@@ -0,0 +1,81 @@
+package dbmigrator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Explain returns a human-readable description of what the next Migrate
+// would do, without executing anything, eg. for deployment approval.
+// It contains the target database, the current and the new version, the
+// direction, the transaction mode and the number of commands to run.
+func (e *engine) Explain() string {
+	currentVersion, err := e.GetCurrentVersion()
+	if err != nil {
+		return fmt.Sprintf("Could not get the current version: %v.", err)
+	}
+
+	if currentVersion == nil {
+		currentVersion = bottomVersion
+	}
+
+	commands, err := e.getCommands()
+	if err != nil {
+		return fmt.Sprintf("Could not read the migration file: %v.", err)
+	}
+
+	var (
+		dir      = resolveDirection(currentVersion, e.dir, e.targetVersion)
+		selected = selectCommands(currentVersion, commands, dir, e.targetVersion)
+		target   = fmt.Sprintf("the database '%s' on host '%s:%d'", e.dbConf.Database, e.dbConf.Host, e.dbConf.Port)
+	)
+
+	newVersion := getNewLatestVersion(currentVersion, commands, dir, e.targetVersion)
+
+	if len(selected) == 0 || newVersion == nil {
+		return fmt.Sprintf("Nothing will run, %s is at version %s.", target, currentVersion.ToString())
+	}
+
+	versions := getUniqueVersions(selected, nil)
+
+	// Rolling back starts with the latest version.
+	if dir == DirectionDown {
+		for i, j := 0, len(versions)-1; i < j; i, j = i+1, j-1 {
+			versions[i], versions[j] = versions[j], versions[i]
+		}
+	}
+
+	names := make([]string, 0, len(versions))
+	for _, v := range versions {
+		names = append(names, "v"+v.ToString())
+	}
+
+	action := "apply %d UP %s (%s) to"
+	if dir == DirectionDown {
+		action = "roll back %d DOWN %s (%s) of"
+	}
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "This will "+action+" %s, ", len(versions), pluralize(len(versions), "migration"), strings.Join(names, ", "), target)
+
+	fmt.Fprintf(&b, "from version %s to version %s, running %d %s. ",
+		currentVersion.ToString(), newVersion.ToString(), len(selected), pluralize(len(selected), "command"))
+
+	if e.conf.WithTransaction {
+		b.WriteString("It will run in a transaction.")
+	} else {
+		b.WriteString("It will run without a transaction.")
+	}
+
+	return b.String()
+}
+
+// pluralize returns the plural form of the given word, if n is not one.
+func pluralize(n int, word string) string {
+	if n == 1 {
+		return word
+	}
+
+	return word + "s"
+}
@@ -0,0 +1,81 @@
+package dbmigrator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/balazskvancz/dbmigrator/database"
+	"github.com/balazskvancz/dbmigrator/models"
+	"github.com/balazskvancz/dbmigrator/repositories"
+)
+
+func TestExplain(t *testing.T) {
+	type testCase struct {
+		name            string
+		latest          *models.Migration
+		targetVersion   Semver
+		withTransaction bool
+
+		expected string
+	}
+
+	content := `#v1.0.0
+#[UP]
+CREATE TABLE foo (id INT);
+CREATE TABLE bar (id INT);
+#[DOWN]
+DROP TABLE bar;
+DROP TABLE foo;
+#v1.1.0
+#[UP]
+ALTER TABLE foo ADD COLUMN bar INT;
+#[DOWN]
+ALTER TABLE foo DROP COLUMN bar;
+`
+
+	path := filepath.Join(t.TempDir(), "migrations.sql")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("could not write the migration file: %v\n", err)
+	}
+
+	tt := []testCase{
+		{
+			name:            "explains the pending up migrations",
+			latest:          nil,
+			withTransaction: true,
+			expected: "This will apply 2 UP migrations (v1.0.0, v1.1.0) to the database 'myapp' on host 'db:3306', " +
+				"from version 0.0.0 to version 1.1.0, running 3 commands. It will run in a transaction.",
+		},
+		{
+			name:     "explains, that nothing will run",
+			latest:   &models.Migration{Version: "1.1.0"},
+			expected: "Nothing will run, the database 'myapp' on host 'db:3306' is at version 1.1.0.",
+		},
+		{
+			name:          "explains the rollback in case of lower target",
+			latest:        &models.Migration{Version: "1.1.0"},
+			targetVersion: bottomVersion,
+			expected: "This will roll back 2 DOWN migrations (v1.1.0, v1.0.0) of the database 'myapp' on host 'db:3306', " +
+				"from version 1.1.0 to version 0.0.0, running 3 commands. It will run without a transaction.",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			e := &engine{
+				conf:          &Config{MigrationsFilePath: path, WithTransaction: tc.withTransaction},
+				dbConf:        database.DatabaseConfig{Host: "db", Port: 3306, Database: "myapp"},
+				dir:           DirectionUp,
+				targetVersion: tc.targetVersion,
+				repositories: &repositories.Repositories{
+					Migrations: &mockMigrationsRepository{doesExists: true, latest: tc.latest},
+				},
+			}
+
+			if got := e.Explain(); got != tc.expected {
+				t.Errorf("expected explanation: %q; got: %q\n", tc.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,80 @@
+package dbmigrator
+
+import (
+	"encoding/json"
+
+	"github.com/balazskvancz/dbmigrator/models"
+)
+
+// PendingCommand is a command, which would run by the next Process.
+type PendingCommand struct {
+	Version   string    `json:"version"`
+	Direction direction `json:"direction"`
+	SQL       string    `json:"sql"`
+}
+
+// MigrationState is the machine-readable state of the migrations.
+type MigrationState struct {
+	CurrentVersion  string              `json:"currentVersion"`
+	FileVersion     string              `json:"fileVersion"`
+	UpToDate        bool                `json:"upToDate"`
+	PendingCommands []PendingCommand    `json:"pendingCommands"`
+	History         []*models.Migration `json:"history"`
+}
+
+// ExportToJSON returns the JSON encoded state of the migrations.
+func (e *engine) ExportToJSON() ([]byte, error) {
+	currentVersion, err := e.GetCurrentVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	commands, err := e.getCommands()
+	if err != nil {
+		return nil, err
+	}
+
+	history, err := e.GetHistory()
+	if err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(newMigrationState(currentVersion, commands, history))
+}
+
+// newMigrationState creates the state based upon the current
+// version, the commands of the file and the stored history.
+func newMigrationState(currentVersion Semver, commands []Command, history []*models.Migration) *MigrationState {
+	if currentVersion == nil {
+		currentVersion = bottomVersion
+	}
+
+	fileVersion := getLatestVersion(commands)
+	if fileVersion == nil {
+		fileVersion = bottomVersion
+	}
+
+	pending := filterCommands(currentVersion, commands, DirectionUp, nil)
+
+	state := &MigrationState{
+		CurrentVersion:  currentVersion.ToString(),
+		FileVersion:     fileVersion.ToString(),
+		UpToDate:        len(pending) == 0,
+		PendingCommands: make([]PendingCommand, 0, len(pending)),
+		History:         history,
+	}
+
+	if state.History == nil {
+		state.History = make([]*models.Migration, 0)
+	}
+
+	for _, c := range pending {
+		state.PendingCommands = append(state.PendingCommands, PendingCommand{
+			Version:   c.Semver().ToString(),
+			Direction: c.GetDirection(),
+			SQL:       c.GetQuery(),
+		})
+	}
+
+	return state
+}
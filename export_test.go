@@ -0,0 +1,96 @@
+package dbmigrator
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/balazskvancz/dbmigrator/models"
+	"github.com/balazskvancz/dbmigrator/repositories"
+)
+
+func TestNewMigrationState(t *testing.T) {
+	type testCase struct {
+		name     string
+		current  Semver
+		expected *MigrationState
+	}
+
+	commands := []Command{
+		mustNewCommand(nil, "CREATE TABLE foo (id INT);", newSemver("1.0.0"), DirectionUp),
+		mustNewCommand(nil, "DROP TABLE foo;", newSemver("1.0.0"), DirectionDown),
+		mustNewCommand(nil, "ALTER TABLE foo ADD COLUMN bar INT;", newSemver("1.1.0"), DirectionUp),
+	}
+
+	history := []*models.Migration{{Id: 1, Version: "1.0.0"}}
+
+	tt := []testCase{
+		{
+			name:    "returns the pending commands",
+			current: newSemver("1.0.0"),
+			expected: &MigrationState{
+				CurrentVersion: "1.0.0",
+				FileVersion:    "1.1.0",
+				UpToDate:       false,
+				PendingCommands: []PendingCommand{
+					{Version: "1.1.0", Direction: DirectionUp, SQL: "ALTER TABLE foo ADD COLUMN bar INT;"},
+				},
+				History: history,
+			},
+		},
+		{
+			name:    "returns up to date state",
+			current: newSemver("1.1.0"),
+			expected: &MigrationState{
+				CurrentVersion:  "1.1.0",
+				FileVersion:     "1.1.0",
+				UpToDate:        true,
+				PendingCommands: []PendingCommand{},
+				History:         history,
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			got := newMigrationState(tc.current, commands, history)
+
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected state: %+v; got: %+v\n", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestExportToJSONWithoutMigrationsTable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "migrations.sql")
+	if err := os.WriteFile(path, []byte("#v1.0.0\nCREATE TABLE foo (id INT);\n"), 0o644); err != nil {
+		t.Fatalf("could not write the migration file: %v\n", err)
+	}
+
+	e := &engine{
+		conf: &Config{MigrationsFilePath: path},
+		repositories: &repositories.Repositories{
+			// Querying the missing table would fail.
+			Migrations: &mockMigrationsRepository{doesExists: false, allError: errors.New("mock-error")},
+		},
+	}
+
+	data, err := e.ExportToJSON()
+	if err != nil {
+		t.Fatalf("expected error: <nil>; got error: %v\n", err)
+	}
+
+	var state MigrationState
+
+	if err := json.Unmarshal(data, &state); err != nil {
+		t.Fatalf("expected error: <nil>; got error: %v\n", err)
+	}
+
+	if state.CurrentVersion != "0.0.0" || len(state.History) != 0 || len(state.PendingCommands) != 1 {
+		t.Errorf("expected the state of a fresh database; got: %+v\n", state)
+	}
+}
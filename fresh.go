@@ -0,0 +1,63 @@
+package dbmigrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrFreshNotAllowed error = errors.New("fresh migration is only allowed with WithAllowFresh or on test and dev databases")
+)
+
+// The database names containing any of these are considered safe to wipe.
+var freshDatabaseMarkers = []string{"test", "dev"}
+
+// WithAllowFresh allows ProcessFresh regardless of the name of the database.
+func WithAllowFresh() EngineOptFunc {
+	return func(e *engine) {
+		e.allowFresh = true
+	}
+}
+
+// ProcessFresh migrates the database from scratch, eg. for integration
+// tests: it rolls back every applied version by their DOWN commands, clears
+// the migration history, then applies every UP command. Since it destroys
+// the data, it only runs with WithAllowFresh, or if the name of the
+// database contains "test" or "dev".
+func (e *engine) ProcessFresh() error {
+	databaseName := e.db.GetDatabaseName()
+
+	if !e.allowFresh && !isFreshAllowedDatabase(databaseName) {
+		return ErrFreshNotAllowed
+	}
+
+	e.Warn(fmt.Sprintf("-- wiping database %s: every version is rolled back and the history is cleared --", databaseName))
+
+	ctx := context.Background()
+
+	// There is nothing to roll back without history.
+	if err := e.process(ctx, e.getCommands, DirectionDown, bottomVersion, runHooks{}); err != nil && !errors.Is(err, ErrNothingToRun) {
+		return err
+	}
+
+	if err := e.ClearMigrationHistory(); err != nil {
+		return err
+	}
+
+	return e.process(ctx, e.getCommands, DirectionUp, nil, runHooks{})
+}
+
+// isFreshAllowedDatabase returns whether the given database can be wiped.
+func isFreshAllowedDatabase(name string) bool {
+	name = strings.ToLower(name)
+
+	for _, marker := range freshDatabaseMarkers {
+		if strings.Contains(name, marker) {
+			return true
+		}
+	}
+
+	return false
+}
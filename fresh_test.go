@@ -0,0 +1,58 @@
+package dbmigrator
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/balazskvancz/dbmigrator/database"
+)
+
+type mockNamedDatabase struct {
+	name string
+
+	database.Database
+}
+
+func (md *mockNamedDatabase) GetDatabaseName() string { return md.name }
+
+func TestIsFreshAllowedDatabase(t *testing.T) {
+	type testCase struct {
+		name     string
+		database string
+		expected bool
+	}
+
+	tt := []testCase{
+		{
+			name:     "allows test database",
+			database: "myapp_test",
+			expected: true,
+		},
+		{
+			name:     "allows dev database regardless of the case",
+			database: "DEV_myapp",
+			expected: true,
+		},
+		{
+			name:     "refuses any other database",
+			database: "myapp",
+			expected: false,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := isFreshAllowedDatabase(tc.database); got != tc.expected {
+				t.Errorf("expected: %t; got: %t\n", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestProcessFreshWithoutPermission(t *testing.T) {
+	e := &engine{db: &mockNamedDatabase{name: "myapp"}}
+
+	if err := e.ProcessFresh(); !errors.Is(err, ErrFreshNotAllowed) {
+		t.Errorf("expected error: %v; got error: %v\n", ErrFreshNotAllowed, err)
+	}
+}
@@ -0,0 +1,86 @@
+package dbmigrator
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ParseError describes a problem found while parsing the migration lines.
+// Both the line and the column numbers are 1-based.
+type ParseError struct {
+	Line    int
+	Column  int
+	Message string
+
+	// Err is the underlying error, eg. ErrBadVersioning.
+	Err error
+}
+
+func newParseError(line, column int, message string, err error) *ParseError {
+	return &ParseError{
+		Line:    line,
+		Column:  column,
+		Message: message,
+		Err:     err,
+	}
+}
+
+func (pe *ParseError) Error() string {
+	return fmt.Sprintf("line %d, column %d: %s", pe.Line, pe.Column, pe.Message)
+}
+
+// Unwrap returns the underlying error.
+func (pe *ParseError) Unwrap() error { return pe.Err }
+
+// MigrationLines represents the lines of a migration file.
+type MigrationLines []string
+
+// readLines reads every line of the given reader.
+func readLines(r io.Reader) (MigrationLines, error) {
+	return readLinesContext(context.Background(), r)
+}
+
+// readLinesContext reads every line of the given reader.
+// The context is checked before reading each line,
+// so a slow read can be cancelled between the lines.
+func readLinesContext(ctx context.Context, r io.Reader) (MigrationLines, error) {
+	var (
+		scanner = bufio.NewScanner(r)
+		lines   = make(MigrationLines, 0)
+	)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+
+		if !scanner.Scan() {
+			break
+		}
+
+		lines = append(lines, scanner.Text())
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return lines, nil
+}
+
+// LineCount returns the number of lines.
+func (ml MigrationLines) LineCount() int { return len(ml) }
+
+// Checksum returns the hex encoded SHA-256 checksum of the lines.
+func (ml MigrationLines) Checksum() string {
+	sum := sha256.Sum256([]byte(strings.Join(ml, "\n")))
+
+	return hex.EncodeToString(sum[:])
+}
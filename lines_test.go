@@ -0,0 +1,77 @@
+package dbmigrator
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReadLines(t *testing.T) {
+	type testCase struct {
+		name     string
+		input    string
+		expected MigrationLines
+	}
+
+	tt := []testCase{
+		{
+			name:     "returns empty slice in case of empty input",
+			input:    "",
+			expected: MigrationLines{},
+		},
+		{
+			name:  "returns every line, including the empty ones",
+			input: "#v1\n\nDROP TABLE foo;\n",
+			expected: MigrationLines{
+				"#v1",
+				"",
+				"DROP TABLE foo;",
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := readLines(strings.NewReader(tc.input))
+			if err != nil {
+				t.Fatalf("expected error: <nil>; got error: %v\n", err)
+			}
+
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected lines: %v; got: %v\n", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestReadLinesContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	lines, err := readLinesContext(ctx, strings.NewReader("#v1\nDROP TABLE foo;\n"))
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected error: %v; got error: %v\n", context.Canceled, err)
+	}
+
+	if lines != nil {
+		t.Errorf("expected lines: <nil>; got: %v\n", lines)
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	var (
+		lines   = MigrationLines{"#v1", "DROP TABLE foo;"}
+		same    = MigrationLines{"#v1", "DROP TABLE foo;"}
+		changed = MigrationLines{"#v1", "DROP TABLE bar;"}
+	)
+
+	if lines.Checksum() != same.Checksum() {
+		t.Error("expected equal checksums for equal lines")
+	}
+
+	if lines.Checksum() == changed.Checksum() {
+		t.Error("expected different checksums for different lines")
+	}
+}
@@ -0,0 +1,182 @@
+package dbmigrator
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sync"
+)
+
+const (
+	noColorEnvKey string = "NO_COLOR"
+
+	colorReset  string = "\x1b[0m"
+	colorRed    string = "\x1b[31m"
+	colorGreen  string = "\x1b[32m"
+	colorYellow string = "\x1b[33m"
+	colorBlue   string = "\x1b[34m"
+)
+
+// The accepted values of Config.LogLevel.
+const (
+	LogLevelDebug string = "debug"
+	LogLevelInfo  string = "info"
+	LogLevelWarn  string = "warn"
+	LogLevelError string = "error"
+)
+
+// CommandLogLevelNone disables the logging of the command execution.
+// The other accepted values of WithCommandLogLevel are LogLevelDebug and LogLevelInfo.
+const CommandLogLevelNone string = "none"
+
+var (
+	ErrInvalidLogLevel error = errors.New("invalid log level")
+)
+
+// The levels in ascending order of severity.
+var logLevels = []string{LogLevelDebug, LogLevelInfo, LogLevelWarn, LogLevelError}
+
+// LeveledLogger is a Logger, which also supports the debug and warn levels.
+// The engine logs the executed statements with debug level – if enabled by
+// WithCommandLogLevel –, and the skipped or repeated commands with warn
+// level, if the logger implements it.
+type LeveledLogger interface {
+	Logger
+	Debug(string)
+	Warn(string)
+}
+
+// WithCommandLogLevel controls the logging of the command execution,
+// independently of the level of the logger: LogLevelDebug logs every
+// statement before its execution, besides the version transitions,
+// LogLevelInfo logs only the version transitions, and CommandLogLevelNone
+// disables the logging of the commands. Without it, the statements are
+// logged only if Config.LogLevel is LogLevelDebug.
+func WithCommandLogLevel(level string) EngineOptFunc {
+	return func(e *engine) {
+		e.commandLogLevel = level
+	}
+}
+
+// validateCommandLogLevel returns ErrInvalidLogLevel,
+// if the given command log level is not accepted.
+func validateCommandLogLevel(level string) error {
+	switch level {
+	case "", LogLevelDebug, LogLevelInfo, CommandLogLevelNone:
+		return nil
+	}
+
+	return ErrInvalidLogLevel
+}
+
+// levelSetter is implemented by the built-in loggers, so the level
+// given by Config.LogLevel can be applied to them.
+type levelSetter interface {
+	SetLevel(string) error
+}
+
+// Matches the ANSI escape sequences, eg. colors.
+var ansiRegexp = regexp.MustCompile("\x1b\\[[0-9;]*[a-zA-Z]")
+
+// WriterLogger is a Logger, which writes every line to the underlying writer.
+// The output is colored only if the writer is a terminal and the NO_COLOR
+// environmental variable is not set, otherwise every ANSI escape sequence
+// is stripped from the lines.
+type WriterLogger struct {
+	mu        sync.Mutex
+	w         io.Writer
+	withColor bool
+	level     int
+}
+
+var (
+	_ LeveledLogger = (*WriterLogger)(nil)
+	_ levelSetter   = (*WriterLogger)(nil)
+)
+
+// NewWriterLogger creates a new logger writing to w with info level.
+func NewWriterLogger(w io.Writer) *WriterLogger {
+	return &WriterLogger{
+		w:         w,
+		withColor: os.Getenv(noColorEnvKey) == "" && isTerminal(w),
+		level:     getLogLevel(LogLevelInfo),
+	}
+}
+
+// SetLevel sets the minimum level of the written lines.
+func (l *WriterLogger) SetLevel(level string) error {
+	lvl := getLogLevel(level)
+	if lvl < 0 {
+		return ErrInvalidLogLevel
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.level = lvl
+
+	return nil
+}
+
+// Debug writes the given line with debug level.
+func (l *WriterLogger) Debug(line string) { l.write(LogLevelDebug, colorBlue, "DEBUG", line) }
+
+// Info writes the given line with info level.
+func (l *WriterLogger) Info(line string) { l.write(LogLevelInfo, colorGreen, "INFO", line) }
+
+// Warn writes the given line with warn level.
+func (l *WriterLogger) Warn(line string) { l.write(LogLevelWarn, colorYellow, "WARN", line) }
+
+// Error writes the given line with error level.
+func (l *WriterLogger) Error(line string) { l.write(LogLevelError, colorRed, "ERROR", line) }
+
+func (l *WriterLogger) write(logLevel, color, level, line string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if getLogLevel(logLevel) < l.level {
+		return
+	}
+
+	if !l.withColor {
+		fmt.Fprintf(l.w, "[%s] %s\n", level, stripANSI(line))
+
+		return
+	}
+
+	fmt.Fprintf(l.w, "%s[%s]%s %s\n", color, level, colorReset, line)
+}
+
+// getLogLevel returns the severity of the given level,
+// or -1 in case of unknown level.
+func getLogLevel(level string) int {
+	for i, l := range logLevels {
+		if l == level {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// stripANSI removes every ANSI escape sequence from the given string.
+func stripANSI(s string) string {
+	return ansiRegexp.ReplaceAllString(s, "")
+}
+
+// isTerminal returns whether the given writer is a terminal.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+
+	stat, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	return stat.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,209 @@
+package dbmigrator
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestWriterLogger(t *testing.T) {
+	type testCase struct {
+		name     string
+		level    string
+		write    func(*WriterLogger)
+		expected string
+	}
+
+	tt := []testCase{
+		{
+			name:     "writes info line",
+			write:    func(l *WriterLogger) { l.Info("foo") },
+			expected: "[INFO] foo\n",
+		},
+		{
+			name:     "writes error line",
+			write:    func(l *WriterLogger) { l.Error("bar") },
+			expected: "[ERROR] bar\n",
+		},
+		{
+			name:     "strips the ANSI codes in case of non-terminal writer",
+			write:    func(l *WriterLogger) { l.Info("\x1b[1;32mbaz\x1b[0m") },
+			expected: "[INFO] baz\n",
+		},
+		{
+			name:     "skips debug line with default level",
+			write:    func(l *WriterLogger) { l.Debug("foo") },
+			expected: "",
+		},
+		{
+			name:     "writes debug line with debug level",
+			level:    LogLevelDebug,
+			write:    func(l *WriterLogger) { l.Debug("foo") },
+			expected: "[DEBUG] foo\n",
+		},
+		{
+			name:  "writes only errors with error level",
+			level: LogLevelError,
+			write: func(l *WriterLogger) {
+				l.Info("foo")
+				l.Warn("bar")
+				l.Error("baz")
+			},
+			expected: "[ERROR] baz\n",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			l := NewWriterLogger(&buf)
+
+			if tc.level != "" {
+				if err := l.SetLevel(tc.level); err != nil {
+					t.Fatalf("expected error: <nil>; got error: %v\n", err)
+				}
+			}
+
+			tc.write(l)
+
+			if got := buf.String(); got != tc.expected {
+				t.Errorf("expected output: %q; got: %q\n", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestWriterLoggerSetLevel(t *testing.T) {
+	l := NewWriterLogger(&bytes.Buffer{})
+
+	if err := l.SetLevel("verbose"); !errors.Is(err, ErrInvalidLogLevel) {
+		t.Errorf("expected error: %v; got error: %v\n", ErrInvalidLogLevel, err)
+	}
+}
+
+func TestSetLogger(t *testing.T) {
+	var (
+		first  bytes.Buffer
+		second bytes.Buffer
+
+		e = &engine{}
+	)
+
+	// Logging without logger is a no-op.
+	e.Info("foo")
+
+	e.SetLogger(NewWriterLogger(&first))
+	e.Info("bar")
+
+	e.SetLogger(NewWriterLogger(&second))
+	e.Error("baz")
+
+	if got, expected := first.String(), "[INFO] bar\n"; got != expected {
+		t.Errorf("expected output: %q; got: %q\n", expected, got)
+	}
+
+	if got, expected := second.String(), "[ERROR] baz\n"; got != expected {
+		t.Errorf("expected output: %q; got: %q\n", expected, got)
+	}
+}
+
+func TestSetLoggerAppliesLogLevel(t *testing.T) {
+	var (
+		buf bytes.Buffer
+		e   = &engine{conf: &Config{LogLevel: LogLevelError}}
+	)
+
+	e.SetLogger(NewWriterLogger(&buf))
+
+	e.Info("foo")
+	e.Error("bar")
+
+	if got, expected := buf.String(), "[ERROR] bar\n"; got != expected {
+		t.Errorf("expected output: %q; got: %q\n", expected, got)
+	}
+}
+
+func TestCommandLogLevel(t *testing.T) {
+	type testCase struct {
+		name        string
+		level       string
+		configLevel string
+		expected    string
+	}
+
+	tt := []testCase{
+		{
+			name:  "logs only the version transitions by default",
+			level: "",
+			expected: "[INFO] -- running version 1.0.0 (up)\n" +
+				"[INFO] -- running version 1.1.0 (up)\n",
+		},
+		{
+			name:  "logs the statements with debug level",
+			level: LogLevelDebug,
+			expected: "[INFO] -- running version 1.0.0 (up)\n" +
+				"[DEBUG] -- executing version 1.0.0: foo;\n" +
+				"[DEBUG] -- executing version 1.0.0: bar;\n" +
+				"[INFO] -- running version 1.1.0 (up)\n" +
+				"[DEBUG] -- executing version 1.1.0: baz;\n",
+		},
+		{
+			name:     "logs nothing with none level",
+			level:    CommandLogLevelNone,
+			expected: "",
+		},
+		{
+			name:        "logs the statements with debug level of the config",
+			level:       "",
+			configLevel: LogLevelDebug,
+			expected: "[INFO] -- running version 1.0.0 (up)\n" +
+				"[DEBUG] -- executing version 1.0.0: foo;\n" +
+				"[DEBUG] -- executing version 1.0.0: bar;\n" +
+				"[INFO] -- running version 1.1.0 (up)\n" +
+				"[DEBUG] -- executing version 1.1.0: baz;\n",
+		},
+		{
+			name:        "overrides the debug level of the config",
+			level:       LogLevelInfo,
+			configLevel: LogLevelDebug,
+			expected: "[INFO] -- running version 1.0.0 (up)\n" +
+				"[INFO] -- running version 1.1.0 (up)\n",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			l := NewWriterLogger(&buf)
+			if err := l.SetLevel(LogLevelDebug); err != nil {
+				t.Fatalf("expected error: <nil>; got error: %v\n", err)
+			}
+
+			e := &engine{conf: &Config{LogLevel: tc.configLevel}, logger: l}
+
+			WithCommandLogLevel(tc.level)(e)
+
+			commands := []Command{
+				&mockCommand{Command: mustNewCommand(nil, "foo;", newSemver("1.0.0"), DirectionUp)},
+				&mockCommand{Command: mustNewCommand(nil, "bar;", newSemver("1.0.0"), DirectionUp)},
+				&mockCommand{Command: mustNewCommand(nil, "baz;", newSemver("1.1.0"), DirectionUp)},
+			}
+
+			if err := e.runCommands(commands, runHooks{}); err != nil {
+				t.Fatalf("expected error: <nil>; got error: %v\n", err)
+			}
+
+			if got := buf.String(); got != tc.expected {
+				t.Errorf("expected output: %q; got: %q\n", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestValidateCommandLogLevel(t *testing.T) {
+	if err := validateCommandLogLevel("verbose"); !errors.Is(err, ErrInvalidLogLevel) {
+		t.Errorf("expected error: %v; got error: %v\n", ErrInvalidLogLevel, err)
+	}
+}
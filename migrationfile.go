@@ -0,0 +1,119 @@
+package dbmigrator
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+)
+
+var (
+	ErrMigrationFileIsNil error = errors.New("given migration file is <nil>")
+)
+
+// MigrationFile is the structured representation of a migration file.
+type MigrationFile struct {
+	Path     string
+	Checksum string
+	Versions []VersionBlock
+}
+
+// VersionBlock holds the commands of a single version.
+type VersionBlock struct {
+	Semver       Semver
+	Description  string
+	UpCommands   []Command
+	DownCommands []Command
+}
+
+// GetMigrationFile reads and parses the migration file given by the config,
+// and returns its versions in ascending order. The checksum is calculated
+// the same way as MigrationLines.Checksum in case of sql files, and from
+// the raw content in case of YAML files.
+func (e *engine) GetMigrationFile() (*MigrationFile, error) {
+	f, err := e.openMigrationsFile(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	content, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		commands []Command
+		checksum string
+	)
+
+	if isYAMLFile(e.conf.MigrationsFilePath) {
+		sum := sha256.Sum256(content)
+		checksum = hex.EncodeToString(sum[:])
+
+		if commands, err = e.ParseYAMLLines(content); err != nil {
+			return nil, err
+		}
+	} else {
+		lines, err := readLines(bytes.NewReader(content))
+		if err != nil {
+			return nil, err
+		}
+
+		checksum = lines.Checksum()
+
+		if commands, err = e.ParseLines(lines); err != nil {
+			return nil, err
+		}
+	}
+
+	return &MigrationFile{
+		Path:     e.conf.MigrationsFilePath,
+		Checksum: checksum,
+		Versions: getVersionBlocks(commands),
+	}, nil
+}
+
+// ProcessWithMigrationFile works like Process, but runs the commands of the
+// given, already parsed migration file, skipping the reading and the parsing.
+func (e *engine) ProcessWithMigrationFile(mf *MigrationFile) error {
+	if mf == nil {
+		return ErrMigrationFileIsNil
+	}
+
+	return e.process(context.Background(), func() ([]Command, error) {
+		return mf.getCommands(), nil
+	}, e.dir, e.targetVersion, runHooks{})
+}
+
+// getCommands returns the commands of every version.
+func (mf *MigrationFile) getCommands() []Command {
+	commands := make([]Command, 0)
+
+	for _, v := range mf.Versions {
+		commands = append(commands, v.UpCommands...)
+		commands = append(commands, v.DownCommands...)
+	}
+
+	return commands
+}
+
+// getVersionBlocks groups the given commands by their
+// versions, and returns the blocks in ascending order.
+func getVersionBlocks(commands []Command) []VersionBlock {
+	versions := getUniqueVersions(commands, nil)
+	blocks := make([]VersionBlock, 0, len(versions))
+
+	for _, v := range versions {
+		blocks = append(blocks, VersionBlock{
+			Semver:       v,
+			Description:  getVersionDescription(v, commands),
+			UpCommands:   filterVersionCommands(commands, v, DirectionUp),
+			DownCommands: filterVersionCommands(commands, v, DirectionDown),
+		})
+	}
+
+	return blocks
+}
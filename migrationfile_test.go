@@ -0,0 +1,106 @@
+package dbmigrator
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/balazskvancz/dbmigrator/models"
+	"github.com/balazskvancz/dbmigrator/repositories"
+)
+
+func TestGetMigrationFile(t *testing.T) {
+	content := `#v1.1.0
+ALTER TABLE foo ADD COLUMN bar INT;
+#v1.0.0
+#[DESC] creation of foo
+#[UP]
+CREATE TABLE foo (id INT);
+#[DOWN]
+DROP TABLE foo;
+`
+
+	path := filepath.Join(t.TempDir(), "migrations.sql")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("could not write the migration file: %v\n", err)
+	}
+
+	e := &engine{conf: &Config{MigrationsFilePath: path}}
+
+	file, err := e.GetMigrationFile()
+	if err != nil {
+		t.Fatalf("expected error: <nil>; got error: %v\n", err)
+	}
+
+	lines, err := e.GetLines()
+	if err != nil {
+		t.Fatalf("expected error: <nil>; got error: %v\n", err)
+	}
+
+	if file.Path != path {
+		t.Errorf("expected path: %s; got: %s\n", path, file.Path)
+	}
+
+	if file.Checksum != lines.Checksum() {
+		t.Errorf("expected checksum: %s; got: %s\n", lines.Checksum(), file.Checksum)
+	}
+
+	if len(file.Versions) != 2 {
+		t.Fatalf("expected versions: 2; got: %d\n", len(file.Versions))
+	}
+
+	first, second := file.Versions[0], file.Versions[1]
+
+	if first.Semver.ToString() != "1.0.0" || second.Semver.ToString() != "1.1.0" {
+		t.Errorf("expected versions in ascending order; got: %s, %s\n", first.Semver.ToString(), second.Semver.ToString())
+	}
+
+	if first.Description != "creation of foo" {
+		t.Errorf("expected description: creation of foo; got: %s\n", first.Description)
+	}
+
+	if len(first.UpCommands) != 1 || len(first.DownCommands) != 1 {
+		t.Errorf("expected 1 up and 1 down command; got: %d, %d\n", len(first.UpCommands), len(first.DownCommands))
+	}
+
+	if len(second.UpCommands) != 1 || len(second.DownCommands) != 0 {
+		t.Errorf("expected 1 up and 0 down command; got: %d, %d\n", len(second.UpCommands), len(second.DownCommands))
+	}
+}
+
+func TestProcessWithMigrationFile(t *testing.T) {
+	e := &engine{}
+
+	if err := e.ProcessWithMigrationFile(nil); !errors.Is(err, ErrMigrationFileIsNil) {
+		t.Errorf("expected error: %v; got error: %v\n", ErrMigrationFileIsNil, err)
+	}
+
+	db := &mockDatabase{}
+
+	mf := &MigrationFile{
+		Versions: getVersionBlocks([]Command{
+			mustNewCommand(db, "CREATE TABLE foo (id INT);", newSemver("1.0.0"), DirectionUp),
+			mustNewCommand(db, "DROP TABLE foo;", newSemver("1.0.0"), DirectionDown),
+			mustNewCommand(db, "ALTER TABLE foo ADD COLUMN bar INT;", newSemver("1.1.0"), DirectionUp),
+		}),
+	}
+
+	repo := &mockMigrationsRepository{doesExists: true, latest: &models.Migration{Version: "1.0.0"}}
+
+	e = &engine{
+		conf:         &Config{},
+		db:           db,
+		dir:          DirectionUp,
+		repositories: &repositories.Repositories{Migrations: repo},
+	}
+
+	if err := e.ProcessWithMigrationFile(mf); err != nil {
+		t.Fatalf("expected error: <nil>; got error: %v\n", err)
+	}
+
+	if expected := []string{"1.1.0"}; !reflect.DeepEqual(repo.inserted, expected) {
+		t.Errorf("expected inserted versions: %v; got: %v\n", expected, repo.inserted)
+	}
+}
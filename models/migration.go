@@ -3,9 +3,12 @@ package models
 import "time"
 
 type Migration struct {
-	Id        int64
-	Version   string
-	CreatedAt time.Time
+	Id          int64     `json:"id"`
+	Version     string    `json:"version"`
+	Description string    `json:"description"`
+	BatchId     string    `json:"batchId"`
+	DurationMs  int64     `json:"durationMs"`
+	CreatedAt   time.Time `json:"createdAt"`
 }
 
 type Migrations []*Migration
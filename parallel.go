@@ -0,0 +1,121 @@
+package dbmigrator
+
+import (
+	"errors"
+	"sync"
+)
+
+const (
+	parallelCommand string = "#[PARALLEL]"
+)
+
+// WithParallelVersions allows running up to n versions concurrently.
+// Only the consecutive versions marked with `#[PARALLEL]` run in parallel,
+// every other version runs alone. Since a transaction is bound to a single
+// connection, the option has no effect in case of transaction.
+func WithParallelVersions(n int) EngineOptFunc {
+	return func(e *engine) {
+		e.parallelVersions = n
+	}
+}
+
+// versionGroup holds the commands of the same version.
+type versionGroup struct {
+	parallel bool
+	commands []Command
+}
+
+// groupConsecutiveVersions groups the consecutive commands
+// of the same version, preserving their order.
+func groupConsecutiveVersions(commands []Command) []*versionGroup {
+	groups := make([]*versionGroup, 0)
+
+	for _, c := range commands {
+		if len(groups) > 0 {
+			last := groups[len(groups)-1]
+
+			if last.commands[0].Semver().Equals(c.Semver()) {
+				last.commands = append(last.commands, c)
+
+				continue
+			}
+		}
+
+		groups = append(groups, &versionGroup{
+			parallel: c.IsParallel(),
+			commands: []Command{c},
+		})
+	}
+
+	return groups
+}
+
+// commandRunner runs the given commands with the given error handler.
+type commandRunner func([]Command, ErrorHandlerFunc) error
+
+// runCommandsParallel runs the given commands with at most n concurrent
+// versions, each of them by the given runner. The consecutive parallel
+// versions form a batch, and the next version starts only after the whole
+// batch finished. In case of error, the running batch is finished, but the
+// next versions do not start. The error handler may be called concurrently.
+func runCommandsParallel(commands []Command, onError ErrorHandlerFunc, n int, run commandRunner) error {
+	groups := groupConsecutiveVersions(commands)
+
+	for i := 0; i < len(groups); {
+		if !groups[i].parallel {
+			if err := run(groups[i].commands, onError); err != nil {
+				return err
+			}
+
+			i++
+
+			continue
+		}
+
+		j := i
+		for j < len(groups) && groups[j].parallel {
+			j++
+		}
+
+		if err := runBatch(groups[i:j], onError, n, run); err != nil {
+			return err
+		}
+
+		i = j
+	}
+
+	return nil
+}
+
+// runBatch runs the given version groups on at most n goroutines.
+func runBatch(groups []*versionGroup, onError ErrorHandlerFunc, n int, run commandRunner) error {
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs = make([]error, 0)
+		sem  = make(chan struct{}, n)
+	)
+
+	for _, g := range groups {
+		wg.Add(1)
+
+		sem <- struct{}{}
+
+		go func(g *versionGroup) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			if err := run(g.commands, onError); err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		}(g)
+	}
+
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
@@ -0,0 +1,106 @@
+package dbmigrator
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestGroupConsecutiveVersions(t *testing.T) {
+	commands := []Command{
+		&command{version: newSemver("1.0.0")},
+		&command{version: newSemver("1.0.0")},
+		&command{version: newSemver("1.1.0"), parallel: true},
+		&command{version: newSemver("1.2.0"), parallel: true},
+		&command{version: newSemver("1.2.0"), parallel: true},
+	}
+
+	groups := groupConsecutiveVersions(commands)
+
+	expected := []struct {
+		length   int
+		parallel bool
+	}{
+		{length: 2, parallel: false},
+		{length: 1, parallel: true},
+		{length: 2, parallel: true},
+	}
+
+	if len(groups) != len(expected) {
+		t.Fatalf("expected groups: %d; got: %d\n", len(expected), len(groups))
+	}
+
+	for i, g := range groups {
+		if len(g.commands) != expected[i].length || g.parallel != expected[i].parallel {
+			t.Errorf("expected group %d: %+v; got: %d, %t\n", i, expected[i], len(g.commands), g.parallel)
+		}
+	}
+}
+
+func TestRunCommandsParallel(t *testing.T) {
+	var (
+		runErr error = errors.New("mock-error")
+		stop         = func(Command, error) ErrorAction { return ErrorActionStop }
+	)
+
+	type testCase struct {
+		name             string
+		failing          bool
+		expected         error
+		expectedLastRuns int
+	}
+
+	tt := []testCase{
+		{
+			name:             "runs every command",
+			failing:          false,
+			expected:         nil,
+			expectedLastRuns: 1,
+		},
+		{
+			name:             "returns the error of a parallel version",
+			failing:          true,
+			expected:         runErr,
+			expectedLastRuns: 0,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				first  = &parallelMockCommand{version: newSemver("1.0.0")}
+				second = &parallelMockCommand{version: newSemver("1.1.0"), parallel: true}
+				third  = &parallelMockCommand{version: newSemver("1.2.0"), parallel: true}
+				last   = &parallelMockCommand{version: newSemver("1.3.0")}
+			)
+
+			if tc.failing {
+				second.errors = []error{runErr}
+			}
+
+			err := runCommandsParallel([]Command{first, second, third, last}, stop, 2, runCommands)
+
+			if !errors.Is(err, tc.expected) {
+				t.Errorf("expected error: %v; got error: %v\n", tc.expected, err)
+			}
+
+			if first.runs != 1 || second.runs != 1 || third.runs != 1 {
+				t.Errorf("expected every command of the batch to run once")
+			}
+
+			if last.runs != tc.expectedLastRuns {
+				t.Errorf("expected runs of last command: %d; got: %d\n", tc.expectedLastRuns, last.runs)
+			}
+		})
+	}
+}
+
+type parallelMockCommand struct {
+	version  Semver
+	parallel bool
+
+	mockCommand
+}
+
+func (pc *parallelMockCommand) Semver() Semver { return pc.version }
+
+func (pc *parallelMockCommand) IsParallel() bool { return pc.parallel }
@@ -0,0 +1,93 @@
+package dbmigrator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/balazskvancz/dbmigrator/database"
+)
+
+const (
+	defaultTestDatabasePrefix string = "dbmigrator_test_"
+)
+
+// WithTestDatabasePrefix sets the prefix of the temporary
+// database created by TestMigration, "dbmigrator_test_" by default.
+func WithTestDatabasePrefix(prefix string) EngineOptFunc {
+	return func(e *engine) {
+		e.testDatabasePrefix = prefix
+	}
+}
+
+// TestMigration runs every UP command of the migration file against a
+// temporary database on the same server, which is dropped at the end, so
+// the migrations can be validated before applying them to the real database.
+// The connection of the temporary database is derived from the config.
+// Only mysql and postgres are supported.
+func (e *engine) TestMigration(ctx context.Context) error {
+	id, err := newBatchId()
+	if err != nil {
+		return err
+	}
+
+	prefix := e.testDatabasePrefix
+	if prefix == "" {
+		prefix = defaultTestDatabasePrefix
+	}
+
+	name := prefix + strings.ReplaceAll(id, "-", "_")
+
+	var create, drop string
+
+	switch e.db.GetDriverName() {
+	case "mysql":
+		create = fmt.Sprintf("CREATE DATABASE IF NOT EXISTS `%s`", name)
+		drop = fmt.Sprintf("DROP DATABASE IF EXISTS `%s`", name)
+	case "postgres", "pgx":
+		create = fmt.Sprintf(`CREATE DATABASE "%s"`, name)
+		drop = fmt.Sprintf(`DROP DATABASE IF EXISTS "%s"`, name)
+	default:
+		return ErrUnsupportedDriver
+	}
+
+	commands, err := e.getCommandsContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	e.Info(fmt.Sprintf("-- creating test database %s --", name))
+
+	if _, err := e.db.Exec(create); err != nil {
+		return err
+	}
+
+	defer func() {
+		if _, err := e.db.Exec(drop); err != nil {
+			e.Error(fmt.Sprintf("could not drop the test database: %v", err))
+		}
+	}()
+
+	testConf := e.dbConf
+	testConf.Database = name
+
+	testDB, err := database.New(ctx, testConf)
+	if err != nil {
+		return err
+	}
+	defer testDB.Close()
+
+	for _, c := range filterCommands(bottomVersion, commands, DirectionUp, nil) {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if err := c.Clone(testDB).Run(); err != nil {
+			return fmt.Errorf("test migration of version %s failed: %w", c.Semver().ToString(), err)
+		}
+	}
+
+	e.Info(fmt.Sprintf("-- test migration succeeded on %s --", name))
+
+	return nil
+}
@@ -0,0 +1,25 @@
+package dbmigrator
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/balazskvancz/dbmigrator/database"
+)
+
+type mockDriverDatabase struct {
+	driver string
+
+	database.Database
+}
+
+func (md *mockDriverDatabase) GetDriverName() string { return md.driver }
+
+func TestTestMigrationUnsupportedDriver(t *testing.T) {
+	e := &engine{db: &mockDriverDatabase{driver: "sqlite3"}}
+
+	if err := e.TestMigration(context.Background()); !errors.Is(err, ErrUnsupportedDriver) {
+		t.Errorf("expected error: %v; got error: %v\n", ErrUnsupportedDriver, err)
+	}
+}
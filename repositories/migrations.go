@@ -1,54 +1,103 @@
 package repositories
 
 import (
+	"database/sql"
+	"errors"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/balazskvancz/dbmigrator/database"
 	"github.com/balazskvancz/dbmigrator/models"
 )
 
+const (
+	createdAtLayout string = "2006-01-02 15:04:05"
+
+	backupTableSuffix string = "_backup"
+)
+
+// The columns of the migrations table.
+var migrationColumns = []string{"id", "version", "description", "batchId", "durationMs", "createdAt"}
+
+var (
+	ErrInvalidLimit error = errors.New("limit must be greater than zero")
+)
+
 type MigrationsRepository interface {
-	Insert(string) error
-	GetLatest() *models.Migration
+	Insert(string, string, string, int64) error
+	GetLatest() (*models.Migration, error)
+	GetLatestN(int) ([]*models.Migration, error)
+	GetAll() ([]*models.Migration, error)
+	GetByVersion(string) (*models.Migration, error)
 	DoesExists() bool
 	CreateTable() error
+	GetTableName() string
+	DeleteAll() error
+	DeleteBatch(string) error
+	Recreate(func(string)) error
 }
 
 type migrationsRepository struct {
+	// The name used in the queries, qualified with the schema, if there is one.
 	tableName string
-	db        database.Database
+
+	name   string
+	schema string
+	db     database.Database
 }
 
-func newMigrationsRepository(tableName string, db database.Database) MigrationsRepository {
+func newMigrationsRepository(name string, schema string, db database.Database) MigrationsRepository {
+	tableName := name
+	if schema != "" {
+		tableName = fmt.Sprintf("%s.%s", schema, name)
+	}
+
 	return &migrationsRepository{
 		tableName: tableName,
+		name:      name,
+		schema:    schema,
 		db:        db,
 	}
 }
 
-// Insert saves the version of the latest migration defined in the input.
-func (mr *migrationsRepository) Insert(version string) error {
-	_, err := mr.db.Exec(fmt.Sprintf(`
-		INSERT INTO %s SET
-			version 	= ?,
-			createdAt = NOW()
-	`, mr.tableName), version)
+// GetTableName returns the name of the migrations table,
+// qualified with the schema, if there is one.
+func (mr *migrationsRepository) GetTableName() string { return mr.tableName }
+
+// Insert saves the version of the latest migration defined in the input,
+// along with its description, the id of the batch, ie. the run, which
+// applied it and the duration of the run in milliseconds. Empty
+// description and batch id are stored as NULL.
+func (mr *migrationsRepository) Insert(version string, description string, batchId string, durationMs int64) error {
+	_, err := mr.db.Exec(mr.bind(fmt.Sprintf(`
+		INSERT INTO %s
+			(version, description, batchId, durationMs, createdAt)
+		VALUES
+			(?, ?, ?, ?, NOW())
+	`, mr.tableName)),
+		version,
+		sql.NullString{String: description, Valid: description != ""},
+		sql.NullString{String: batchId, Valid: batchId != ""},
+		durationMs,
+	)
 
 	return err
 }
 
-// GetLatest returns the lates migration entity stored in the database.
-func (mr *migrationsRepository) GetLatest() *models.Migration {
+// GetLatest returns the latest migration entity stored in the database.
+// If there is no stored migration, it returns <nil> without error.
+func (mr *migrationsRepository) GetLatest() (*models.Migration, error) {
 	row := mr.db.QueryRow(fmt.Sprintf(`
 		SELECT
 		 	id,
 			version
 		FROM %s
-		ORDER BY createdAt DESC	
+		ORDER BY createdAt DESC, id DESC
 		LIMIT 1
 	`, mr.tableName))
 	if row == nil {
-		return nil
+		return nil, nil
 	}
 
 	var (
@@ -57,24 +106,137 @@ func (mr *migrationsRepository) GetLatest() *models.Migration {
 	)
 
 	if err := row.Scan(&id, &version); err != nil {
-		return nil
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+
+		return nil, err
 	}
 
 	return &models.Migration{
 		Id:      id,
 		Version: version,
+	}, nil
+}
+
+// GetLatestN returns the latest n migration entities stored
+// in the database in descending order.
+func (mr *migrationsRepository) GetLatestN(n int) ([]*models.Migration, error) {
+	if n <= 0 {
+		return nil, ErrInvalidLimit
 	}
+
+	rows, err := mr.db.Query(mr.bind(fmt.Sprintf(`
+		SELECT
+			id,
+			version,
+			description,
+			batchId,
+			durationMs,
+			createdAt
+		FROM %s
+		ORDER BY createdAt DESC, id DESC
+		LIMIT ?
+	`, mr.tableName)), n)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanMigrations(rows)
+}
+
+// GetAll returns every stored migration entity in ascending order.
+func (mr *migrationsRepository) GetAll() ([]*models.Migration, error) {
+	rows, err := mr.db.Query(fmt.Sprintf(`
+		SELECT
+			id,
+			version,
+			description,
+			batchId,
+			durationMs,
+			createdAt
+		FROM %s
+		ORDER BY createdAt ASC, id ASC
+	`, mr.tableName))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return scanMigrations(rows)
 }
 
-// DoesExists returns if the migrations table exists in the current database.
+// GetByVersion returns the latest stored migration entity of the given
+// version. If the version was never stored, it returns <nil> without error.
+func (mr *migrationsRepository) GetByVersion(version string) (*models.Migration, error) {
+	rows, err := mr.db.Query(mr.bind(fmt.Sprintf(`
+		SELECT
+			id,
+			version,
+			description,
+			batchId,
+			durationMs,
+			createdAt
+		FROM %s
+		WHERE version = ?
+		ORDER BY createdAt DESC, id DESC
+		LIMIT 1
+	`, mr.tableName)), version)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	migrations, err := scanMigrations(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(migrations) == 0 {
+		return nil, nil
+	}
+
+	return migrations[0], nil
+}
+
+// DeleteAll removes every stored migration entity. It uses DELETE instead
+// of TRUNCATE, since the latter is DDL, which cannot be rolled back everywhere.
+func (mr *migrationsRepository) DeleteAll() error {
+	_, err := mr.db.Exec(fmt.Sprintf("DELETE FROM %s", mr.tableName))
+
+	return err
+}
+
+// DeleteBatch removes every stored migration entity of the given batch.
+func (mr *migrationsRepository) DeleteBatch(batchId string) error {
+	_, err := mr.db.Exec(mr.bind(fmt.Sprintf("DELETE FROM %s WHERE batchId = ?", mr.tableName)), batchId)
+
+	return err
+}
+
+// DoesExists returns if the migrations table exists in its schema, which
+// is the current database (the current schema in case of postgres),
+// if no schema was given.
 func (mr *migrationsRepository) DoesExists() bool {
-	row := mr.db.QueryRow(`
+	var row *sql.Row
+
+	query := `
 		SELECT
 			TABLE_SCHEMA
 		FROM INFORMATION_SCHEMA.TABLES
-		WHERE TABLE_SCHEMA = ?
+		WHERE TABLE_SCHEMA = %s
 		AND TABLE_NAME = ?
-	`, mr.db.GetDatabaseName(), mr.tableName)
+	`
+
+	switch {
+	case mr.schema != "":
+		row = mr.db.QueryRow(mr.bind(fmt.Sprintf(query, "?")), mr.schema, mr.name)
+	case mr.isPostgres():
+		row = mr.db.QueryRow(mr.bind(fmt.Sprintf(query, "current_schema()")), mr.name)
+	default:
+		row = mr.db.QueryRow(fmt.Sprintf(query, "?"), mr.db.GetDatabaseName(), mr.name)
+	}
 
 	var name string
 
@@ -83,15 +245,203 @@ func (mr *migrationsRepository) DoesExists() bool {
 
 // CreateTable creates the migrations table.
 func (mr *migrationsRepository) CreateTable() error {
+	idType, createdAtType := "INTEGER AUTO_INCREMENT", "DATETIME"
+	if mr.isPostgres() {
+		idType, createdAtType = "SERIAL", "TIMESTAMP"
+	}
+
 	_, err := mr.db.Exec(fmt.Sprintf(`
 		CREATE TABLE %s (
-			id 				INTEGER 			AUTO_INCREMENT,
-			version 	VARCHAR (10)	NOT NULL,
-			createdAt	DATETIME			NOT NULL,
+			id 					%s,
+			version 		VARCHAR (64)	NOT NULL,
+			description	TEXT					DEFAULT NULL,
+			batchId			VARCHAR (36)	DEFAULT NULL,
+			durationMs	BIGINT				DEFAULT NULL,
+			createdAt		%s			NOT NULL,
 
 			PRIMARY KEY (id)
 		)
-	`, mr.tableName))
+	`, mr.tableName, idType, createdAtType))
+
+	return err
+}
+
+// Recreate recreates the migrations table with the current schema, eg. after
+// new columns were added. The rows are copied into a backup table, then the
+// table is dropped and created again, and the rows are copied back, with
+// the defaults of the new columns. In case of postgres, the id sequence is
+// advanced past the restored ids. The backup table is dropped at the end,
+// but it is kept in case of failure. Every step is reported to onStep.
+func (mr *migrationsRepository) Recreate(onStep func(string)) error {
+	backupName := mr.tableName + backupTableSuffix
+
+	onStep(fmt.Sprintf("backing up %s into %s", mr.tableName, backupName))
+
+	if _, err := mr.db.Exec(fmt.Sprintf("CREATE TABLE %s AS SELECT * FROM %s", backupName, mr.tableName)); err != nil {
+		return err
+	}
+
+	columns, err := mr.getCommonColumns(backupName)
+	if err != nil {
+		return err
+	}
+
+	onStep(fmt.Sprintf("dropping %s", mr.tableName))
+
+	if _, err := mr.db.Exec(fmt.Sprintf("DROP TABLE %s", mr.tableName)); err != nil {
+		return err
+	}
+
+	onStep(fmt.Sprintf("creating %s", mr.tableName))
+
+	if err := mr.CreateTable(); err != nil {
+		return fmt.Errorf("could not create the table, the rows are kept in %s: %w", backupName, err)
+	}
+
+	onStep(fmt.Sprintf("restoring the columns %s from %s", strings.Join(columns, ", "), backupName))
+
+	list := strings.Join(columns, ", ")
+
+	if _, err := mr.db.Exec(fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s", mr.tableName, list, list, backupName)); err != nil {
+		return fmt.Errorf("could not restore the rows, they are kept in %s: %w", backupName, err)
+	}
+
+	// The ids are copied explicitly, so the sequence of the new
+	// SERIAL column must be advanced past them, otherwise the
+	// next insert would collide with a restored row.
+	if mr.isPostgres() {
+		if _, err := mr.db.Exec(fmt.Sprintf(
+			"SELECT setval(pg_get_serial_sequence('%s', 'id'), COALESCE(MAX(id), 0) + 1, false) FROM %s",
+			mr.tableName, mr.tableName,
+		)); err != nil {
+			return fmt.Errorf("could not reset the id sequence: %w", err)
+		}
+	}
+
+	onStep(fmt.Sprintf("dropping %s", backupName))
+
+	_, err = mr.db.Exec(fmt.Sprintf("DROP TABLE %s", backupName))
 
 	return err
 }
+
+// isPostgres returns whether the database is accessed by a postgres driver.
+func (mr *migrationsRepository) isPostgres() bool {
+	driver := mr.db.GetDriverName()
+
+	return driver == "postgres" || driver == "pgx"
+}
+
+// bind rewrites the ? placeholders of the query to $1, $2, ...
+// in case of postgres, which does not support the former.
+func (mr *migrationsRepository) bind(query string) string {
+	if !mr.isPostgres() {
+		return query
+	}
+
+	var (
+		b strings.Builder
+		n int
+	)
+
+	for _, r := range query {
+		if r != '?' {
+			b.WriteRune(r)
+
+			continue
+		}
+
+		n++
+		fmt.Fprintf(&b, "$%d", n)
+	}
+
+	return b.String()
+}
+
+// getCommonColumns returns the columns of the migrations
+// table, which are present in the given table as well.
+func (mr *migrationsRepository) getCommonColumns(table string) ([]string, error) {
+	rows, err := mr.db.Query(fmt.Sprintf("SELECT * FROM %s LIMIT 0", table))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	existing, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make([]string, 0, len(migrationColumns))
+
+	for _, column := range migrationColumns {
+		for _, e := range existing {
+			// Eg. postgres folds the unquoted names to lower case.
+			if strings.EqualFold(column, e) {
+				columns = append(columns, column)
+
+				break
+			}
+		}
+	}
+
+	return columns, nil
+}
+
+// scanMigrations reads every migration entity from the given rows.
+func scanMigrations(rows *sql.Rows) ([]*models.Migration, error) {
+	migrations := make([]*models.Migration, 0)
+
+	for rows.Next() {
+		var (
+			id          int64
+			version     string
+			description sql.NullString
+			batchId     sql.NullString
+			durationMs  sql.NullInt64
+			createdAt   any
+		)
+
+		if err := rows.Scan(&id, &version, &description, &batchId, &durationMs, &createdAt); err != nil {
+			return nil, err
+		}
+
+		parsedCreatedAt, err := parseCreatedAt(createdAt)
+		if err != nil {
+			return nil, err
+		}
+
+		migrations = append(migrations, &models.Migration{
+			Id:          id,
+			Version:     version,
+			Description: description.String,
+			BatchId:     batchId.String,
+			DurationMs:  durationMs.Int64,
+			CreatedAt:   parsedCreatedAt,
+		})
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return migrations, nil
+}
+
+// parseCreatedAt converts the scanned createdAt value to time.Time.
+// Depending on the driver config (eg. parseTime for mysql), the
+// value is either already a time.Time or its raw textual form.
+func parseCreatedAt(v any) (time.Time, error) {
+	switch t := v.(type) {
+	case time.Time:
+		return t, nil
+	case []byte:
+		return time.Parse(createdAtLayout, string(t))
+	case string:
+		return time.Parse(createdAtLayout, t)
+	case nil:
+		return time.Time{}, nil
+	}
+
+	return time.Time{}, fmt.Errorf("unsupported createdAt type: %T", v)
+}
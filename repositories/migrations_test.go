@@ -0,0 +1,404 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/balazskvancz/dbmigrator/database"
+	"github.com/balazskvancz/dbmigrator/models"
+)
+
+type mockDatabase struct {
+	driver    string
+	execError error
+
+	// The rows returned by every query.
+	rows [][]driver.Value
+
+	query string
+	args  []any
+
+	// Every executed statement in order.
+	executed []string
+
+	database.Database
+}
+
+func (md *mockDatabase) Exec(query string, args ...any) (sql.Result, error) {
+	md.query = query
+	md.args = args
+	md.executed = append(md.executed, query)
+
+	return nil, md.execError
+}
+
+func (md *mockDatabase) GetDriverName() string { return md.driver }
+
+func (md *mockDatabase) Query(query string, args ...any) (*sql.Rows, error) {
+	md.query = query
+	md.args = args
+
+	return sql.OpenDB(rowsConnector{rows: md.rows}).Query(query)
+}
+
+// rowsConnector returns connections, which answer every
+// query with the given rows of the migrations table.
+type rowsConnector struct {
+	rows [][]driver.Value
+}
+
+type rowsConn struct {
+	rows [][]driver.Value
+}
+
+type mockRows struct {
+	rows [][]driver.Value
+}
+
+func (c rowsConnector) Connect(context.Context) (driver.Conn, error) { return rowsConn(c), nil }
+
+func (c rowsConnector) Driver() driver.Driver { return nil }
+
+func (c rowsConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+
+func (c rowsConn) Close() error { return nil }
+
+func (c rowsConn) Begin() (driver.Tx, error) { return nil, errors.New("not supported") }
+
+func (c rowsConn) QueryContext(context.Context, string, []driver.NamedValue) (driver.Rows, error) {
+	return &mockRows{rows: c.rows}, nil
+}
+
+func (r *mockRows) Columns() []string { return migrationColumns }
+
+func (r *mockRows) Close() error { return nil }
+
+func (r *mockRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+
+	return nil
+}
+
+func TestInsert(t *testing.T) {
+	type testCase struct {
+		name        string
+		driver      string
+		version     string
+		description string
+		batchId     string
+		durationMs  int64
+		execError   error
+
+		expectedQuery *regexp.Regexp
+		expectedArgs  []any
+		expectedError error
+	}
+
+	var (
+		// The columns are listed explicitly, so the same query works with every driver.
+		mysqlInsert    = regexp.MustCompile(`\(version, description, batchId, durationMs, createdAt\)\s+VALUES\s+\(\?, \?, \?, \?, NOW\(\)\)`)
+		postgresInsert = regexp.MustCompile(`\(version, description, batchId, durationMs, createdAt\)\s+VALUES\s+\(\$1, \$2, \$3, \$4, NOW\(\)\)`)
+	)
+
+	var execError error = errors.New("mock-error")
+
+	tt := []testCase{
+		{
+			name:          "inserts the version with empty description",
+			version:       "1.0.0",
+			description:   "",
+			expectedQuery: mysqlInsert,
+			expectedArgs: []any{
+				"1.0.0",
+				sql.NullString{},
+				sql.NullString{},
+				int64(0),
+			},
+			expectedError: nil,
+		},
+		{
+			name:          "inserts the version with description and batch id",
+			version:       "1.2.0",
+			description:   "foo",
+			batchId:       "bar",
+			durationMs:    42,
+			expectedQuery: mysqlInsert,
+			expectedArgs: []any{
+				"1.2.0",
+				sql.NullString{String: "foo", Valid: true},
+				sql.NullString{String: "bar", Valid: true},
+				int64(42),
+			},
+			expectedError: nil,
+		},
+		{
+			name:          "returns the error of the database",
+			version:       "1.0.0",
+			execError:     execError,
+			expectedQuery: mysqlInsert,
+			expectedArgs:  []any{"1.0.0", sql.NullString{}, sql.NullString{}, int64(0)},
+			expectedError: execError,
+		},
+		{
+			name:          "inserts with numbered placeholders in case of postgres",
+			driver:        "postgres",
+			version:       "1.0.0",
+			expectedQuery: postgresInsert,
+			expectedArgs:  []any{"1.0.0", sql.NullString{}, sql.NullString{}, int64(0)},
+			expectedError: nil,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			db := &mockDatabase{driver: tc.driver, execError: tc.execError}
+
+			err := newMigrationsRepository(defaultMigrationsTableName, "", db).Insert(tc.version, tc.description, tc.batchId, tc.durationMs)
+
+			if !errors.Is(err, tc.expectedError) {
+				t.Errorf("expected error: %v; got error: %v\n", tc.expectedError, err)
+			}
+
+			if !tc.expectedQuery.MatchString(db.query) {
+				t.Errorf("malformed insert query: %s\n", db.query)
+			}
+
+			if !reflect.DeepEqual(db.args, tc.expectedArgs) {
+				t.Errorf("expected args: %v; got: %v\n", tc.expectedArgs, db.args)
+			}
+		})
+	}
+}
+
+func TestGetTableName(t *testing.T) {
+	type testCase struct {
+		name     string
+		table    string
+		schema   string
+		expected string
+	}
+
+	tt := []testCase{
+		{
+			name:     "returns the plain name without schema",
+			table:    "__migrations__",
+			schema:   "",
+			expected: "__migrations__",
+		},
+		{
+			name:     "returns the qualified name with schema",
+			table:    "__migrations__",
+			schema:   "myapp",
+			expected: "myapp.__migrations__",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := newMigrationsRepository(tc.table, tc.schema, nil).GetTableName(); got != tc.expected {
+				t.Errorf("expected table name: %s; got: %s\n", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestGetLatestN(t *testing.T) {
+	type testCase struct {
+		name          string
+		driver        string
+		n             int
+		rows          [][]driver.Value
+		expectedQuery *regexp.Regexp
+		expected      []*models.Migration
+		expectedError error
+	}
+
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tt := []testCase{
+		{
+			name:          "returns error in case of zero limit",
+			n:             0,
+			expected:      nil,
+			expectedError: ErrInvalidLimit,
+		},
+		{
+			name:          "returns error in case of negative limit",
+			n:             -1,
+			expected:      nil,
+			expectedError: ErrInvalidLimit,
+		},
+		{
+			name:   "returns the latest migrations in descending order",
+			n:      2,
+			driver: "mysql",
+			rows: [][]driver.Value{
+				{int64(3), "1.2.0", "bar", nil, int64(12), []byte("2024-01-02 03:04:05")},
+				{int64(2), "1.1.0", nil, "batch", nil, createdAt},
+			},
+			expectedQuery: regexp.MustCompile(`ORDER BY createdAt DESC, id DESC\s+LIMIT \?`),
+			expected: []*models.Migration{
+				{Id: 3, Version: "1.2.0", Description: "bar", DurationMs: 12, CreatedAt: createdAt},
+				{Id: 2, Version: "1.1.0", BatchId: "batch", CreatedAt: createdAt},
+			},
+			expectedError: nil,
+		},
+		{
+			name:          "limits with numbered placeholder in case of postgres",
+			n:             1,
+			driver:        "postgres",
+			rows:          [][]driver.Value{},
+			expectedQuery: regexp.MustCompile(`ORDER BY createdAt DESC, id DESC\s+LIMIT \$1`),
+			expected:      []*models.Migration{},
+			expectedError: nil,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			db := &mockDatabase{driver: tc.driver, rows: tc.rows}
+
+			got, err := newMigrationsRepository(defaultMigrationsTableName, "", db).GetLatestN(tc.n)
+
+			if !errors.Is(err, tc.expectedError) {
+				t.Errorf("expected error: %v; got error: %v\n", tc.expectedError, err)
+			}
+
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected migrations: %v; got: %v\n", tc.expected, got)
+			}
+
+			if tc.expectedQuery == nil {
+				return
+			}
+
+			if !tc.expectedQuery.MatchString(db.query) {
+				t.Errorf("malformed query: %s\n", db.query)
+			}
+
+			if !reflect.DeepEqual(db.args, []any{tc.n}) {
+				t.Errorf("expected args: %v; got: %v\n", []any{tc.n}, db.args)
+			}
+		})
+	}
+}
+
+func TestDeleteAll(t *testing.T) {
+	type testCase struct {
+		name          string
+		schema        string
+		execError     error
+		expectedQuery string
+		expectedError error
+	}
+
+	var execError error = errors.New("mock-error")
+
+	tt := []testCase{
+		{
+			name:          "deletes every row of the table",
+			schema:        "",
+			expectedQuery: "DELETE FROM __migrations__",
+			expectedError: nil,
+		},
+		{
+			name:          "deletes every row of the table in the schema",
+			schema:        "myapp",
+			expectedQuery: "DELETE FROM myapp.__migrations__",
+			expectedError: nil,
+		},
+		{
+			name:          "returns the error of the database",
+			schema:        "",
+			execError:     execError,
+			expectedQuery: "DELETE FROM __migrations__",
+			expectedError: execError,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			db := &mockDatabase{execError: tc.execError}
+
+			err := newMigrationsRepository(defaultMigrationsTableName, tc.schema, db).DeleteAll()
+
+			if !errors.Is(err, tc.expectedError) {
+				t.Errorf("expected error: %v; got error: %v\n", tc.expectedError, err)
+			}
+
+			// DELETE is used instead of TRUNCATE, so it can be rolled back.
+			if db.query != tc.expectedQuery {
+				t.Errorf("expected query: %s; got: %s\n", tc.expectedQuery, db.query)
+			}
+		})
+	}
+}
+
+func TestRecreate(t *testing.T) {
+	type testCase struct {
+		name     string
+		driver   string
+		expected []string
+	}
+
+	const columns string = "id, version, description, batchId, durationMs, createdAt"
+
+	tt := []testCase{
+		{
+			name:   "copies the rows back into the new table",
+			driver: "mysql",
+			expected: []string{
+				"CREATE TABLE __migrations___backup AS SELECT * FROM __migrations__",
+				"DROP TABLE __migrations__",
+				"CREATE TABLE",
+				"INSERT INTO __migrations__ (" + columns + ") SELECT " + columns + " FROM __migrations___backup",
+				"DROP TABLE __migrations___backup",
+			},
+		},
+		{
+			name:   "advances the id sequence in case of postgres",
+			driver: "postgres",
+			expected: []string{
+				"CREATE TABLE __migrations___backup AS SELECT * FROM __migrations__",
+				"DROP TABLE __migrations__",
+				"CREATE TABLE",
+				"INSERT INTO __migrations__ (" + columns + ") SELECT " + columns + " FROM __migrations___backup",
+				"SELECT setval(pg_get_serial_sequence('__migrations__', 'id'), COALESCE(MAX(id), 0) + 1, false) FROM __migrations__",
+				"DROP TABLE __migrations___backup",
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			db := &mockDatabase{driver: tc.driver}
+
+			if err := newMigrationsRepository(defaultMigrationsTableName, "", db).Recreate(func(string) {}); err != nil {
+				t.Fatalf("expected error: <nil>; got error: %v\n", err)
+			}
+
+			if len(db.executed) != len(tc.expected) {
+				t.Fatalf("expected statements: %v; got: %v\n", tc.expected, db.executed)
+			}
+
+			for i, stmt := range db.executed {
+				if !strings.HasPrefix(strings.TrimSpace(stmt), tc.expected[i]) {
+					t.Errorf("expected statement: %s; got: %s\n", tc.expected[i], stmt)
+				}
+			}
+		})
+	}
+}
@@ -12,12 +12,18 @@ type Repositories struct {
 
 // New creates an instace of the common repository holder.
 func New(db database.Database, migrationsTableName string) *Repositories {
+	return NewWithSchema(db, migrationsTableName, "")
+}
+
+// NewWithSchema works like New, but the migrations table is
+// qualified with the given schema, if it is not empty.
+func NewWithSchema(db database.Database, migrationsTableName string, migrationsTableSchema string) *Repositories {
 	finalTableName := defaultMigrationsTableName
 	if migrationsTableName != "" {
 		finalTableName = migrationsTableName
 	}
 
 	return &Repositories{
-		Migrations: newMigrationsRepository(finalTableName, db),
+		Migrations: newMigrationsRepository(finalTableName, migrationsTableSchema, db),
 	}
 }
@@ -0,0 +1,164 @@
+package dbmigrator
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// MigrationResult is the outcome of a single run of a command.
+type MigrationResult struct {
+	Version   string
+	Direction direction
+	SQL       string
+
+	// Err is the error of the run, <nil> in case of success.
+	Err error
+}
+
+// resultCollector records the outcome of every run of the commands.
+type resultCollector struct {
+	mu      sync.Mutex
+	results []MigrationResult
+
+	// The index of the latest run of each command.
+	latest map[Command]int
+}
+
+func newResultCollector() *resultCollector {
+	return &resultCollector{
+		results: make([]MigrationResult, 0),
+		latest:  make(map[Command]int),
+	}
+}
+
+// onRun records the run of the given command as successful.
+func (rc *resultCollector) onRun(c Command) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	rc.latest[c] = len(rc.results)
+	rc.results = append(rc.results, MigrationResult{
+		Version:   c.Semver().ToString(),
+		Direction: c.GetDirection(),
+		SQL:       c.GetQuery(),
+	})
+}
+
+// onError records the failure of the latest run of the given command.
+func (rc *resultCollector) onError(c Command, err error) {
+	if t, ok := c.(*trackedCommand); ok {
+		c = t.Command
+	}
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	if i, ok := rc.latest[c]; ok {
+		rc.results[i].Err = err
+	}
+}
+
+// getErrors returns the errors of the failed runs.
+func (rc *resultCollector) getErrors() []error {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	errs := make([]error, 0)
+
+	for _, r := range rc.results {
+		if r.Err != nil {
+			errs = append(errs, r.Err)
+		}
+	}
+
+	return errs
+}
+
+// ProcessUntilError works like Process, but the error handling is given at
+// call time instead of the registered error handler: with stopOnFirst the
+// execution stops at the first failed command, otherwise every command runs
+// and the errors are accumulated. The results report the outcome of every
+// executed command in both modes. Whether a transaction is used, is still
+// decided by the config.
+func (e *engine) ProcessUntilError(stopOnFirst bool) ([]MigrationResult, error) {
+	action := ErrorActionContinue
+	if stopOnFirst {
+		action = ErrorActionStop
+	}
+
+	collector, err := e.processCollectingResults(func(Command, error) ErrorAction {
+		return action
+	})
+
+	if stopOnFirst || err != nil {
+		return collector.results, err
+	}
+
+	return collector.results, errors.Join(collector.getErrors()...)
+}
+
+// ProcessWithCallback works like Process, but the given function is called
+// inline, as soon as the commands of a version finished – successfully or
+// not –, with the first error of them, so eg. the progress can be reported.
+// The versions, which did not start, are not reported. With parallel
+// versions, the function may be called concurrently. The failures are
+// handled by the registered error handler, as usual. Keep in mind, that
+// in case of transaction, the reported versions are still rolled back,
+// if a later one fails.
+func (e *engine) ProcessWithCallback(fn func(version string, dir direction, err error)) error {
+	return e.process(context.Background(), e.getCommands, e.dir, e.targetVersion, runHooks{onVersion: fn})
+}
+
+// withVersionReports wraps the given runner, so it runs the commands
+// version by version, and reports each of them by the given function
+// right after its last command finished.
+func withVersionReports(run commandRunner, onVersion func(string, direction, error)) commandRunner {
+	return func(commands []Command, onError ErrorHandlerFunc) error {
+		for _, g := range groupConsecutiveVersions(commands) {
+			// The first failure of the version, which was not retried.
+			var versionErr error
+
+			err := run(g.commands, func(c Command, err error) ErrorAction {
+				action := onError(c, err)
+
+				if action != ErrorActionRetry && versionErr == nil {
+					versionErr = err
+				}
+
+				return action
+			})
+
+			// Eg. the timeout aborts the run without the error handler.
+			if versionErr == nil {
+				versionErr = err
+			}
+
+			first := g.commands[0]
+			onVersion(first.Semver().ToString(), first.GetDirection(), versionErr)
+
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
+
+// processCollectingResults runs Process with the given error
+// handler and records the outcome of every executed command.
+func (e *engine) processCollectingResults(onError ErrorHandlerFunc) (*resultCollector, error) {
+	collector := newResultCollector()
+
+	err := e.process(context.Background(), e.getCommands, e.dir, e.targetVersion, runHooks{
+		onError: func(c Command, err error) ErrorAction {
+			collector.onError(c, err)
+
+			return onError(c, err)
+		},
+		onRun: collector.onRun,
+	})
+
+	return collector, err
+}
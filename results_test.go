@@ -0,0 +1,220 @@
+package dbmigrator
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/balazskvancz/dbmigrator/repositories"
+)
+
+func TestResultCollector(t *testing.T) {
+	type testCase struct {
+		name     string
+		action   ErrorAction
+		expected []MigrationResult
+	}
+
+	mockErr := errors.New("mock-error")
+
+	tt := []testCase{
+		{
+			name:   "stops at the first failed command",
+			action: ErrorActionStop,
+			expected: []MigrationResult{
+				{Version: "1.0.0", Direction: DirectionUp, SQL: "foo;"},
+				{Version: "1.0.0", Direction: DirectionUp, SQL: "bar;", Err: mockErr},
+			},
+		},
+		{
+			name:   "runs every command and records the failure",
+			action: ErrorActionContinue,
+			expected: []MigrationResult{
+				{Version: "1.0.0", Direction: DirectionUp, SQL: "foo;"},
+				{Version: "1.0.0", Direction: DirectionUp, SQL: "bar;", Err: mockErr},
+				{Version: "1.1.0", Direction: DirectionUp, SQL: "baz;"},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			var (
+				collector = newResultCollector()
+				commands  = []Command{
+					&mockCommand{Command: mustNewCommand(nil, "foo;", newSemver("1.0.0"), DirectionUp)},
+					&mockCommand{errors: []error{mockErr}, Command: mustNewCommand(nil, "bar;", newSemver("1.0.0"), DirectionUp)},
+					&mockCommand{Command: mustNewCommand(nil, "baz;", newSemver("1.1.0"), DirectionUp)},
+				}
+			)
+
+			e := &engine{conf: &Config{}}
+
+			hooks := runHooks{
+				onRun: collector.onRun,
+				onError: func(c Command, err error) ErrorAction {
+					collector.onError(c, err)
+
+					return tc.action
+				},
+			}
+
+			if err := e.runCommands(commands, hooks); err != nil && tc.action != ErrorActionStop {
+				t.Fatalf("expected error: <nil>; got error: %v\n", err)
+			}
+
+			if !reflect.DeepEqual(collector.results, tc.expected) {
+				t.Errorf("expected results: %v; got: %v\n", tc.expected, collector.results)
+			}
+		})
+	}
+}
+
+// newResultsEngine returns an engine with a migration file of two
+// versions, whose second command fails with the given error.
+func newResultsEngine(t *testing.T, execErr error) *engine {
+	t.Helper()
+
+	content := `#v1.0.0
+CREATE TABLE foo (id INT);
+CREATE TABLE bar (id INT);
+#v1.1.0
+CREATE TABLE baz (id INT);
+`
+
+	path := filepath.Join(t.TempDir(), "migrations.sql")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("could not write the migration file: %v\n", err)
+	}
+
+	return &engine{
+		conf: &Config{MigrationsFilePath: path},
+		db: &mockRecordingDatabase{
+			failures: map[string]error{"CREATE TABLE bar (id INT);": execErr},
+		},
+		dir:          DirectionUp,
+		repositories: &repositories.Repositories{Migrations: &mockMigrationsRepository{doesExists: true}},
+	}
+}
+
+func TestProcessUntilError(t *testing.T) {
+	type testCase struct {
+		name        string
+		stopOnFirst bool
+		expected    []MigrationResult
+	}
+
+	mockErr := errors.New("mock-error")
+
+	tt := []testCase{
+		{
+			name:        "stops at the first failed command",
+			stopOnFirst: true,
+			expected: []MigrationResult{
+				{Version: "1.0.0", Direction: DirectionUp, SQL: "CREATE TABLE foo (id INT);"},
+				{Version: "1.0.0", Direction: DirectionUp, SQL: "CREATE TABLE bar (id INT);", Err: mockErr},
+			},
+		},
+		{
+			name:        "runs every command and accumulates the errors",
+			stopOnFirst: false,
+			expected: []MigrationResult{
+				{Version: "1.0.0", Direction: DirectionUp, SQL: "CREATE TABLE foo (id INT);"},
+				{Version: "1.0.0", Direction: DirectionUp, SQL: "CREATE TABLE bar (id INT);", Err: mockErr},
+				{Version: "1.1.0", Direction: DirectionUp, SQL: "CREATE TABLE baz (id INT);"},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			registeredCalls := 0
+
+			e := newResultsEngine(t, mockErr)
+			e.onError = func(Command, error) ErrorAction {
+				registeredCalls++
+
+				return ErrorActionStop
+			}
+
+			results, err := e.ProcessUntilError(tc.stopOnFirst)
+
+			if !errors.Is(err, mockErr) {
+				t.Errorf("expected error: %v; got error: %v\n", mockErr, err)
+			}
+
+			if !reflect.DeepEqual(results, tc.expected) {
+				t.Errorf("expected results: %v; got: %v\n", tc.expected, results)
+			}
+
+			// The handler given at call time overrides the registered one
+			// only for the run, the engine itself is left untouched.
+			if registeredCalls != 0 {
+				t.Errorf("expected calls of the registered handler: 0; got: %d\n", registeredCalls)
+			}
+
+			e.getErrorHandler()(nil, mockErr)
+
+			if registeredCalls != 1 {
+				t.Errorf("expected the registered handler to be kept\n")
+			}
+		})
+	}
+}
+
+func TestProcessWithCallback(t *testing.T) {
+	type report struct {
+		version  string
+		dir      direction
+		err      error
+		executed int
+	}
+
+	type testCase struct {
+		name     string
+		onError  ErrorHandlerFunc
+		expected []report
+	}
+
+	mockErr := errors.New("mock-error")
+
+	tt := []testCase{
+		{
+			name:    "reports every version right after its commands",
+			onError: func(Command, error) ErrorAction { return ErrorActionContinue },
+			expected: []report{
+				{version: "1.0.0", dir: DirectionUp, err: mockErr, executed: 2},
+				{version: "1.1.0", dir: DirectionUp, err: nil, executed: 3},
+			},
+		},
+		{
+			name:    "reports only the failed version, if the run stops",
+			onError: func(Command, error) ErrorAction { return ErrorActionStop },
+			expected: []report{
+				{version: "1.0.0", dir: DirectionUp, err: mockErr, executed: 2},
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			e := newResultsEngine(t, mockErr)
+			e.onError = tc.onError
+
+			db := e.db.(*mockRecordingDatabase)
+			reports := make([]report, 0)
+
+			e.ProcessWithCallback(func(version string, dir direction, err error) {
+				// The number of the executed statements shows,
+				// that the version is reported during the run.
+				reports = append(reports, report{version: version, dir: dir, err: err, executed: len(db.executed)})
+			})
+
+			if !reflect.DeepEqual(reports, tc.expected) {
+				t.Errorf("expected reports: %v; got: %v\n", tc.expected, reports)
+			}
+		})
+	}
+}
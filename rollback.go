@@ -0,0 +1,176 @@
+package dbmigrator
+
+import (
+	"context"
+	"errors"
+
+	"github.com/balazskvancz/dbmigrator/models"
+)
+
+var (
+	ErrInvalidRollbackSteps  error = errors.New("rollback steps must be greater than zero")
+	ErrInvalidRollbackTarget error = errors.New("rollback target is greater than the current version")
+	ErrNotEnoughHistory      error = errors.New("less versions are applied than the steps to roll back")
+)
+
+// Rollback rolls back the given number of versions based upon the migration
+// history, the same way as ProcessDowngrade, but if less versions are applied
+// than the given steps, all of them are rolled back. Keep in mind, that the
+// history only stores the last version of each run, so the versions applied
+// by the same run are rolled back together.
+func (e *engine) Rollback(steps int) error {
+	if steps <= 0 {
+		return ErrInvalidRollbackSteps
+	}
+
+	if err := e.SetupDatabase(); err != nil {
+		return err
+	}
+
+	return e.process(context.Background(), e.getCommands, DirectionDown, nil, runHooks{
+		resolveTarget: func(current Semver) (Semver, error) {
+			if current == nil {
+				return nil, ErrNothingToRun
+			}
+
+			target, _, err := e.getDowngradeTarget(steps)
+
+			return target, err
+		},
+	})
+}
+
+// RollbackTo rolls back the database to the given version, by running
+// the DOWN commands of every applied version greater than it. It fails
+// with ErrInvalidRollbackTarget, if the target is greater than the
+// current version, since that would be an upgrade.
+func (e *engine) RollbackTo(version string) error {
+	target := newSemver(version)
+	if target == nil {
+		return ErrBadVersioning
+	}
+
+	if err := e.SetupDatabase(); err != nil {
+		return err
+	}
+
+	return e.process(context.Background(), e.getCommands, DirectionDown, target, runHooks{
+		resolveTarget: func(current Semver) (Semver, error) {
+			if current == nil {
+				return nil, ErrNothingToRun
+			}
+
+			if target.GreaterThan(current) {
+				return nil, ErrInvalidRollbackTarget
+			}
+
+			return target, nil
+		},
+	})
+}
+
+// ProcessDowngrade rolls back the given number of versions based upon the
+// migration history: the DOWN commands of the most recently applied versions
+// run in reverse chronological order, then the version applied before them
+// is stored as the latest. It fails with ErrNotEnoughHistory, if less
+// versions are applied than the given steps.
+func (e *engine) ProcessDowngrade(steps int) error {
+	if steps <= 0 {
+		return ErrInvalidRollbackSteps
+	}
+
+	if err := e.SetupDatabase(); err != nil {
+		return err
+	}
+
+	return e.process(context.Background(), e.getCommands, DirectionDown, nil, runHooks{
+		resolveTarget: func(Semver) (Semver, error) {
+			target, enough, err := e.getDowngradeTarget(steps)
+			if err != nil {
+				return nil, err
+			}
+
+			if !enough {
+				return nil, ErrNotEnoughHistory
+			}
+
+			return target, nil
+		},
+	})
+}
+
+// getDowngradeTarget returns the version, which becomes the current one after
+// rolling back the given number of the most recently applied versions of the
+// history, and whether there are enough applied versions. If there are not,
+// the target is bottomVersion.
+func (e *engine) getDowngradeTarget(steps int) (Semver, bool, error) {
+	history, err := e.GetHistory()
+	if err != nil {
+		return nil, false, err
+	}
+
+	applied, err := getRollbackableVersions(history)
+	if err != nil {
+		return nil, false, err
+	}
+
+	if len(applied) <= steps {
+		return bottomVersion, len(applied) == steps, nil
+	}
+
+	return applied[steps], true, nil
+}
+
+// getRollbackableVersions returns the currently applied versions of the given
+// history – which is in chronological order – in reverse chronological order.
+// The records of the rolled back versions are skipped, since every version
+// must be lower than the one applied after it. The records before rolling
+// back everything are skipped as well.
+func getRollbackableVersions(history []*models.Migration) ([]Semver, error) {
+	applied := make([]Semver, 0)
+
+	for i := len(history) - 1; i >= 0; i-- {
+		sv := newSemver(history[i].Version)
+		if sv == nil {
+			return nil, ErrInvalidLastVersion
+		}
+
+		if sv.Equals(bottomVersion) {
+			break
+		}
+
+		if len(applied) == 0 || applied[len(applied)-1].GreaterThan(sv) {
+			applied = append(applied, sv)
+		}
+	}
+
+	return applied, nil
+}
+
+// getUniqueVersions returns the unique versions of the given
+// commands, which match the filter, in ascending order.
+func getUniqueVersions(commands []Command, filter func(Semver) bool) []Semver {
+	var (
+		versions = make([]Semver, 0)
+		seen     = make(map[string]struct{})
+	)
+
+	for _, c := range commands {
+		sv := c.Semver()
+
+		if filter != nil && !filter(sv) {
+			continue
+		}
+
+		if _, ok := seen[sv.ToString()]; ok {
+			continue
+		}
+
+		seen[sv.ToString()] = struct{}{}
+		versions = append(versions, sv)
+	}
+
+	sortSemvers(versions)
+
+	return versions
+}
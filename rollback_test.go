@@ -0,0 +1,294 @@
+package dbmigrator
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/balazskvancz/dbmigrator/models"
+	"github.com/balazskvancz/dbmigrator/repositories"
+)
+
+// newRollbackEngine returns an engine with a migration file of three
+// versions and the given history, whose last record is the current version.
+func newRollbackEngine(t *testing.T, history []*models.Migration) (*engine, *mockRecordingDatabase, *mockMigrationsRepository) {
+	t.Helper()
+
+	content := `#v1.0.0
+#[UP]
+CREATE TABLE foo (id INT);
+#[DOWN]
+DROP TABLE foo;
+#v1.1.0
+#[UP]
+CREATE TABLE bar (id INT);
+#[DOWN]
+DROP TABLE bar;
+#v1.2.0
+#[UP]
+CREATE TABLE baz (id INT);
+#[DOWN]
+DROP TABLE baz;
+`
+
+	path := filepath.Join(t.TempDir(), "migrations.sql")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("could not write the migration file: %v\n", err)
+	}
+
+	var (
+		db   = &mockRecordingDatabase{}
+		repo = &mockMigrationsRepository{doesExists: true, all: history}
+	)
+
+	if len(history) > 0 {
+		repo.latest = history[len(history)-1]
+	}
+
+	e := &engine{
+		conf:         &Config{MigrationsFilePath: path},
+		db:           db,
+		repositories: &repositories.Repositories{Migrations: repo},
+	}
+
+	return e, db, repo
+}
+
+func TestRollback(t *testing.T) {
+	type testCase struct {
+		name    string
+		steps   int
+		history []*models.Migration
+
+		expectedError    error
+		expectedExecuted []string
+		expectedInserted []string
+	}
+
+	// The second run applied both 1.1.0 and 1.2.0.
+	history := []*models.Migration{{Version: "1.0.0"}, {Version: "1.2.0"}}
+
+	tt := []testCase{
+		{
+			name:          "returns error in case of invalid steps",
+			steps:         0,
+			history:       history,
+			expectedError: ErrInvalidRollbackSteps,
+		},
+		{
+			name:          "returns error without history",
+			steps:         1,
+			history:       nil,
+			expectedError: ErrNothingToRun,
+		},
+		{
+			name:             "rolls back the versions of the latest run",
+			steps:            1,
+			history:          history,
+			expectedExecuted: []string{"DROP TABLE baz;", "DROP TABLE bar;"},
+			expectedInserted: []string{"1.0.0"},
+		},
+		{
+			name:             "rolls back everything, if less versions are applied than the steps",
+			steps:            5,
+			history:          history,
+			expectedExecuted: []string{"DROP TABLE baz;", "DROP TABLE bar;", "DROP TABLE foo;"},
+			expectedInserted: []string{"0.0.0"},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			e, db, repo := newRollbackEngine(t, tc.history)
+
+			if err := e.Rollback(tc.steps); !errors.Is(err, tc.expectedError) {
+				t.Fatalf("expected error: %v; got error: %v\n", tc.expectedError, err)
+			}
+
+			if !reflect.DeepEqual(db.executed, tc.expectedExecuted) {
+				t.Errorf("expected executed: %v; got: %v\n", tc.expectedExecuted, db.executed)
+			}
+
+			if !reflect.DeepEqual(repo.inserted, tc.expectedInserted) {
+				t.Errorf("expected inserted versions: %v; got: %v\n", tc.expectedInserted, repo.inserted)
+			}
+		})
+	}
+}
+
+func TestSortCommandsDescending(t *testing.T) {
+	var (
+		c1 = mustNewCommand(nil, "a", newSemver("1.0.0"), DirectionDown)
+		c2 = mustNewCommand(nil, "b", newSemver("1.1.0"), DirectionDown)
+		c3 = mustNewCommand(nil, "c", newSemver("1.1.0"), DirectionDown)
+		c4 = mustNewCommand(nil, "d", newSemver("1.2.0"), DirectionDown)
+	)
+
+	commands := []Command{c1, c2, c3, c4}
+
+	sortCommandsDescending(commands)
+
+	if expected := []Command{c4, c2, c3, c1}; !reflect.DeepEqual(commands, expected) {
+		t.Errorf("expected commands: %v; got: %v\n", expected, commands)
+	}
+}
+
+func TestGetUniqueVersions(t *testing.T) {
+	commands := []Command{
+		mustNewCommand(nil, "", newSemver("1.2.0"), DirectionUp),
+		mustNewCommand(nil, "", newSemver("1.0.0"), DirectionUp),
+		mustNewCommand(nil, "", newSemver("1.0.0"), DirectionDown),
+		mustNewCommand(nil, "", newSemver("1.1.0"), DirectionUp),
+	}
+
+	expected := []Semver{
+		newSemver("1.0.0"),
+		newSemver("1.1.0"),
+		newSemver("1.2.0"),
+	}
+
+	if got := getUniqueVersions(commands, nil); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected versions: %v; got: %v\n", expected, got)
+	}
+}
+
+func TestRollbackTo(t *testing.T) {
+	type testCase struct {
+		name    string
+		version string
+		history []*models.Migration
+
+		expectedError    error
+		expectedExecuted []string
+	}
+
+	history := []*models.Migration{{Version: "1.2.0"}}
+
+	tt := []testCase{
+		{
+			name:          "returns error in case of malformed version",
+			version:       "foo",
+			history:       history,
+			expectedError: ErrBadVersioning,
+		},
+		{
+			name:          "returns error, if there is no applied version",
+			version:       "1.0.0",
+			history:       nil,
+			expectedError: ErrNothingToRun,
+		},
+		{
+			name:          "returns error, if the target is greater than the current version",
+			version:       "1.3.0",
+			history:       history,
+			expectedError: ErrInvalidRollbackTarget,
+		},
+		{
+			name:             "rolls back the versions greater than the target",
+			version:          "1.0.0",
+			history:          history,
+			expectedExecuted: []string{"DROP TABLE baz;", "DROP TABLE bar;"},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			e, db, _ := newRollbackEngine(t, tc.history)
+
+			if err := e.RollbackTo(tc.version); !errors.Is(err, tc.expectedError) {
+				t.Errorf("expected error: %v; got error: %v\n", tc.expectedError, err)
+			}
+
+			if !reflect.DeepEqual(db.executed, tc.expectedExecuted) {
+				t.Errorf("expected executed: %v; got: %v\n", tc.expectedExecuted, db.executed)
+			}
+		})
+	}
+}
+
+func TestProcessDowngrade(t *testing.T) {
+	type testCase struct {
+		name    string
+		steps   int
+		history []*models.Migration
+
+		expectedError    error
+		expectedExecuted []string
+		expectedInserted []string
+	}
+
+	tt := []testCase{
+		{
+			name:          "returns error in case of invalid steps",
+			steps:         0,
+			expectedError: ErrInvalidRollbackSteps,
+		},
+		{
+			name:          "returns error without history",
+			steps:         1,
+			expectedError: ErrNotEnoughHistory,
+		},
+		{
+			name:          "returns error, if less versions are applied than the steps",
+			steps:         3,
+			history:       []*models.Migration{{Version: "1.0.0"}, {Version: "1.1.0"}},
+			expectedError: ErrNotEnoughHistory,
+		},
+		{
+			name:             "rolls back the most recently applied versions",
+			steps:            2,
+			history:          []*models.Migration{{Version: "1.0.0"}, {Version: "1.1.0"}, {Version: "1.2.0"}},
+			expectedExecuted: []string{"DROP TABLE baz;", "DROP TABLE bar;"},
+			expectedInserted: []string{"1.0.0"},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			e, db, repo := newRollbackEngine(t, tc.history)
+
+			if err := e.ProcessDowngrade(tc.steps); !errors.Is(err, tc.expectedError) {
+				t.Fatalf("expected error: %v; got error: %v\n", tc.expectedError, err)
+			}
+
+			if !reflect.DeepEqual(db.executed, tc.expectedExecuted) {
+				t.Errorf("expected executed: %v; got: %v\n", tc.expectedExecuted, db.executed)
+			}
+
+			if !reflect.DeepEqual(repo.inserted, tc.expectedInserted) {
+				t.Errorf("expected inserted versions: %v; got: %v\n", tc.expectedInserted, repo.inserted)
+			}
+		})
+	}
+}
+
+func TestGetRollbackableVersions(t *testing.T) {
+	history := []*models.Migration{
+		{Version: "1.0.0"},
+		{Version: "1.1.0"},
+		{Version: "1.2.0"},
+		// The record of rolling back 1.2.0.
+		{Version: "1.1.0"},
+		{Version: "1.3.0"},
+	}
+
+	// The records before rolling back everything are not applied anymore.
+	history = append([]*models.Migration{{Version: "2.0.0"}, {Version: "0.0.0"}}, history...)
+
+	applied, err := getRollbackableVersions(history)
+	if err != nil {
+		t.Fatalf("expected error: <nil>; got error: %v\n", err)
+	}
+
+	got := make([]string, 0)
+
+	for _, sv := range applied {
+		got = append(got, sv.ToString())
+	}
+
+	if expected := []string{"1.3.0", "1.1.0", "1.0.0"}; !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected applied versions: %v; got: %v\n", expected, got)
+	}
+}
@@ -0,0 +1,73 @@
+package dbmigrator
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strings"
+)
+
+const (
+	s3Scheme string = "s3://"
+)
+
+var (
+	ErrNoS3Client    error = errors.New("missing s3 client for s3:// migrations file path")
+	ErrInvalidS3Path error = errors.New("s3 path must follow `s3://bucket/key` format")
+)
+
+// S3GetterAPI is the minimal interface of an S3-compatible object store
+// needed to read the migration file. The AWS SDK v2 s3.Client has a
+// different GetObject signature, so it needs a small adapter.
+type S3GetterAPI interface {
+	GetObject(ctx context.Context, bucket, key string) (io.ReadCloser, error)
+}
+
+// WithS3Client sets the client used to read the migration file,
+// if its path is given as `s3://bucket/path/migration.sql`.
+func WithS3Client(client S3GetterAPI) EngineOptFunc {
+	return func(e *engine) {
+		e.s3Client = client
+	}
+}
+
+// isS3Path returns whether the given path points to an object store.
+func isS3Path(path string) bool {
+	return strings.HasPrefix(path, s3Scheme)
+}
+
+// parseS3Path returns the bucket and the key of the given `s3://bucket/key` path.
+func parseS3Path(path string) (string, string, error) {
+	bucket, key, ok := strings.Cut(strings.TrimPrefix(path, s3Scheme), "/")
+	if !ok || bucket == "" || key == "" {
+		return "", "", ErrInvalidS3Path
+	}
+
+	return bucket, key, nil
+}
+
+// openMigrationsFile opens the migration file, which is
+// either a local file or an object of the object store.
+func (e *engine) openMigrationsFile(ctx context.Context) (io.ReadCloser, error) {
+	path := e.conf.MigrationsFilePath
+
+	if path == "" {
+		return nil, ErrNoFilePath
+	}
+
+	if !isS3Path(path) {
+		return os.Open(path)
+	}
+
+	if e.s3Client == nil {
+		return nil, ErrNoS3Client
+	}
+
+	bucket, key, err := parseS3Path(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.s3Client.GetObject(ctx, bucket, key)
+}
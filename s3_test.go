@@ -0,0 +1,106 @@
+package dbmigrator
+
+import (
+	"context"
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type mockS3Client struct {
+	objects map[string]string
+}
+
+func (mc *mockS3Client) GetObject(_ context.Context, bucket, key string) (io.ReadCloser, error) {
+	content, ok := mc.objects[bucket+"/"+key]
+	if !ok {
+		return nil, errors.New("no such key")
+	}
+
+	return io.NopCloser(strings.NewReader(content)), nil
+}
+
+func TestParseS3Path(t *testing.T) {
+	type testCase struct {
+		name           string
+		path           string
+		expectedBucket string
+		expectedKey    string
+		expectedError  error
+	}
+
+	tt := []testCase{
+		{
+			name:           "returns the bucket and the key",
+			path:           "s3://bucket/path/migration.sql",
+			expectedBucket: "bucket",
+			expectedKey:    "path/migration.sql",
+		},
+		{
+			name:          "returns error in case of missing key",
+			path:          "s3://bucket",
+			expectedError: ErrInvalidS3Path,
+		},
+		{
+			name:          "returns error in case of missing bucket",
+			path:          "s3:///migration.sql",
+			expectedError: ErrInvalidS3Path,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			bucket, key, err := parseS3Path(tc.path)
+			if !errors.Is(err, tc.expectedError) {
+				t.Errorf("expected error: %v; got error: %v\n", tc.expectedError, err)
+			}
+
+			if bucket != tc.expectedBucket || key != tc.expectedKey {
+				t.Errorf("expected: %s, %s; got: %s, %s\n", tc.expectedBucket, tc.expectedKey, bucket, key)
+			}
+		})
+	}
+}
+
+func TestGetLinesFromS3(t *testing.T) {
+	type testCase struct {
+		name          string
+		client        S3GetterAPI
+		expected      MigrationLines
+		expectedError error
+	}
+
+	tt := []testCase{
+		{
+			name:          "returns error without client",
+			client:        nil,
+			expectedError: ErrNoS3Client,
+		},
+		{
+			name: "returns the lines of the object",
+			client: &mockS3Client{objects: map[string]string{
+				"bucket/migration.sql": "#v1\nDROP TABLE foo;\n",
+			}},
+			expected: MigrationLines{"#v1", "DROP TABLE foo;"},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			e := &engine{conf: &Config{MigrationsFilePath: "s3://bucket/migration.sql"}}
+
+			WithS3Client(tc.client)(e)
+
+			got, err := e.GetLines()
+			if !errors.Is(err, tc.expectedError) {
+				t.Errorf("expected error: %v; got error: %v\n", tc.expectedError, err)
+			}
+
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected lines: %v; got: %v\n", tc.expected, got)
+			}
+		})
+	}
+}
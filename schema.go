@@ -0,0 +1,223 @@
+package dbmigrator
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/balazskvancz/dbmigrator/database"
+)
+
+var (
+	ErrSchemaDumpDisabled error = errors.New("schema dump is not enabled")
+)
+
+// WithSchemaDump enables dumping the schema via DumpSchema.
+func WithSchemaDump() EngineOptFunc {
+	return func(e *engine) {
+		e.withSchemaDump = true
+	}
+}
+
+// DumpSchema writes the schema of the target database to w. In case of
+// mysql, the output is the `CREATE` statements of every table and view,
+// in case of postgres it is a human-readable list of the tables and
+// views of the current schema with their columns and constraints.
+func (e *engine) DumpSchema(w io.Writer) error {
+	if !e.withSchemaDump {
+		return ErrSchemaDumpDisabled
+	}
+
+	switch e.db.GetDriverName() {
+	case "mysql":
+		statements, err := showCreateTables(e.db)
+		if err != nil {
+			return err
+		}
+
+		for _, stmt := range statements {
+			if _, err := fmt.Fprintf(w, "%s;\n\n", stmt); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	case "postgres", "pgx":
+		return dumpPostgresSchema(e.db, w)
+	}
+
+	return ErrUnsupportedDriver
+}
+
+// showCreateTables returns the `CREATE` statements
+// of every table and view of the given mysql database.
+func showCreateTables(db database.Database) ([]string, error) {
+	tables, err := queryStrings(db, "SHOW TABLES")
+	if err != nil {
+		return nil, err
+	}
+
+	statements := make([]string, 0, len(tables))
+
+	for _, table := range tables {
+		stmt, err := showCreateTable(db, table)
+		if err != nil {
+			return nil, err
+		}
+
+		statements = append(statements, stmt)
+	}
+
+	return statements, nil
+}
+
+// showCreateTable returns the `CREATE` statement of the given mysql table
+// or view. The statement is always the second column, but in case of views
+// the character set and the collation are returned as well.
+func showCreateTable(db database.Database, table string) (string, error) {
+	rows, err := db.Query(fmt.Sprintf("SHOW CREATE TABLE `%s`", table))
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+
+	if !rows.Next() || len(columns) < 2 {
+		if err := rows.Err(); err != nil {
+			return "", err
+		}
+
+		return "", fmt.Errorf("no create statement of %s", table)
+	}
+
+	var (
+		values = make([]string, len(columns))
+		dest   = make([]any, len(columns))
+	)
+
+	for i := range values {
+		dest[i] = &values[i]
+	}
+
+	if err := rows.Scan(dest...); err != nil {
+		return "", err
+	}
+
+	return values[1], nil
+}
+
+// dumpPostgresSchema writes the tables of the given postgres
+// database with their columns and constraints to w.
+func dumpPostgresSchema(db database.Database, w io.Writer) error {
+	tables, err := db.Schema()
+	if err != nil {
+		return err
+	}
+
+	for _, table := range tables {
+		if _, err := fmt.Fprintf(w, "TABLE %s\n", table.Name); err != nil {
+			return err
+		}
+
+		for _, column := range table.Columns {
+			nullable := "NOT NULL"
+			if column.Nullable == "YES" {
+				nullable = "NULL"
+			}
+
+			if _, err := fmt.Fprintf(w, "\t%s %s %s\n", column.Name, column.Type, nullable); err != nil {
+				return err
+			}
+		}
+
+		rows, err := db.Query(`
+			SELECT
+				constraint_name,
+				constraint_type
+			FROM information_schema.table_constraints
+			WHERE table_catalog = $1
+			AND table_schema = current_schema()
+			AND table_name = $2
+			ORDER BY constraint_name
+		`, db.GetDatabaseName(), table.Name)
+		if err != nil {
+			return err
+		}
+
+		if err := writeRows(rows, w, func(values []string) string {
+			return fmt.Sprintf("\tCONSTRAINT %s %s\n", values[0], values[1])
+		}); err != nil {
+			return err
+		}
+
+		if _, err := fmt.Fprintln(w); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// queryStrings returns the first column of every row of the given query.
+func queryStrings(db database.Database, query string, args ...any) ([]string, error) {
+	rows, err := db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	values := make([]string, 0)
+
+	for rows.Next() {
+		var value string
+
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+
+		values = append(values, value)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+// writeRows writes every row formatted by the given function to w.
+// Every column of the rows must be scannable into a string.
+func writeRows(rows *sql.Rows, w io.Writer, format func([]string) string) error {
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	for rows.Next() {
+		var (
+			values = make([]string, len(columns))
+			dest   = make([]any, len(columns))
+		)
+
+		for i := range values {
+			dest[i] = &values[i]
+		}
+
+		if err := rows.Scan(dest...); err != nil {
+			return err
+		}
+
+		if _, err := io.WriteString(w, format(values)); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,108 @@
+package dbmigrator
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/balazskvancz/dbmigrator/database"
+)
+
+func TestDumpSchema(t *testing.T) {
+	type testCase struct {
+		name           string
+		withSchemaDump bool
+		driver         string
+		script         map[string]scriptedResult
+		schema         []database.TableSchema
+		expected       string
+		expectedError  error
+	}
+
+	constraintsQuery := "SELECT constraint_name, constraint_type FROM information_schema.table_constraints " +
+		"WHERE table_catalog = $1 AND table_schema = current_schema() AND table_name = $2 ORDER BY constraint_name"
+
+	tt := []testCase{
+		{
+			name:           "returns error, if the schema dump is not enabled",
+			withSchemaDump: false,
+			driver:         "mysql",
+			expected:       "",
+			expectedError:  ErrSchemaDumpDisabled,
+		},
+		{
+			name:           "returns error in case of unsupported driver",
+			withSchemaDump: true,
+			driver:         "sqlite3",
+			expected:       "",
+			expectedError:  ErrUnsupportedDriver,
+		},
+		{
+			name:           "writes the create statements of the mysql tables and views",
+			withSchemaDump: true,
+			driver:         "mysql",
+			script: map[string]scriptedResult{
+				"SHOW TABLES": {
+					columns: []string{"Tables_in_foo"},
+					rows:    [][]driver.Value{{"bar"}, {"baz"}},
+				},
+				"SHOW CREATE TABLE `bar`": {
+					columns: []string{"Table", "Create Table"},
+					rows:    [][]driver.Value{{"bar", "CREATE TABLE `bar` (`id` int)"}},
+				},
+				"SHOW CREATE TABLE `baz`": {
+					columns: []string{"View", "Create View", "character_set_client", "collation_connection"},
+					rows:    [][]driver.Value{{"baz", "CREATE VIEW `baz` AS SELECT 1", "utf8mb4", "utf8mb4_0900_ai_ci"}},
+				},
+			},
+			expected:      "CREATE TABLE `bar` (`id` int);\n\nCREATE VIEW `baz` AS SELECT 1;\n\n",
+			expectedError: nil,
+		},
+		{
+			name:           "writes the postgres tables with their columns and constraints",
+			withSchemaDump: true,
+			driver:         "postgres",
+			script: map[string]scriptedResult{
+				constraintsQuery: {
+					columns: []string{"constraint_name", "constraint_type"},
+					rows:    [][]driver.Value{{"bar_pkey", "PRIMARY KEY"}},
+				},
+			},
+			schema: []database.TableSchema{
+				{
+					Name: "bar",
+					Columns: []database.ColumnSchema{
+						{Name: "id", Type: "integer", Nullable: "NO"},
+						{Name: "name", Type: "text", Nullable: "YES"},
+					},
+				},
+			},
+			expected:      "TABLE bar\n\tid integer NOT NULL\n\tname text NULL\n\tCONSTRAINT bar_pkey PRIMARY KEY\n\n",
+			expectedError: nil,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			db := newMockScriptedDatabase(t, tc.driver, tc.script)
+			db.schema = tc.schema
+
+			e := &engine{db: db}
+
+			if tc.withSchemaDump {
+				WithSchemaDump()(e)
+			}
+
+			var buf bytes.Buffer
+
+			if err := e.DumpSchema(&buf); !errors.Is(err, tc.expectedError) {
+				t.Fatalf("expected error: %v; got error: %v\n", tc.expectedError, err)
+			}
+
+			if got := buf.String(); got != tc.expected {
+				t.Errorf("expected schema: %q; got: %q\n", tc.expected, got)
+			}
+		})
+	}
+}
@@ -1,19 +1,39 @@
 package dbmigrator
 
 import (
+	"encoding"
+	"encoding/binary"
+	"errors"
 	"fmt"
+	"sort"
 	"strconv"
 	"strings"
 )
 
 const (
-	versionSeparator string = "."
+	versionSeparator    string = "."
+	preReleaseSeparator string = "-"
+
+	// The weights of the parts used by Distance.
+	distanceMajorWeight int = 1000000
+	distanceMinorWeight int = 1000
+
+	// The binary form is the three fields as little-endian
+	// uint32s, followed by the pre-release label, if any.
+	semverBinaryLength int = 12
+)
+
+var (
+	ErrInvalidSemverEncoding error = errors.New("invalid binary encoding of semver")
 )
 
 type semver struct {
 	major int
 	minor int
 	patch int
+
+	// The label after the dash, eg. "alpha.1" of 1.0.0-alpha.1.
+	preRelease string
 }
 
 type Semver interface {
@@ -21,10 +41,36 @@ type Semver interface {
 	ToString() string
 	Equals(Semver) bool
 	WouldRollback(Semver) bool
+	Distance(Semver) int
+	IsPreRelease() bool
 
 	GetMajor() int
 	GetMinor() int
 	GetPatch() int
+	GetPreRelease() string
+
+	encoding.BinaryMarshaler
+	encoding.TextMarshaler
+}
+
+var (
+	_ encoding.BinaryUnmarshaler = (*semver)(nil)
+	_ encoding.TextUnmarshaler   = (*semver)(nil)
+)
+
+// ParseSemver parses the given string as a semver.
+// It returns <nil> in case of invalid input.
+func ParseSemver(str string) Semver { return newSemver(str) }
+
+// MustParseSemver is like ParseSemver, but panics
+// if the given string is not a valid semver.
+func MustParseSemver(str string) Semver {
+	sv := newSemver(str)
+	if sv == nil {
+		panic(fmt.Sprintf("dbmigrator: invalid semver: %q", str))
+	}
+
+	return sv
 }
 
 func newSemver(str string) Semver {
@@ -43,8 +89,30 @@ func newSemver(str string) Semver {
 		str = str[1:]
 	}
 
+	// The pre-release label, eg. `1.0.0-alpha.1`, is separated by the first dash.
+	parts := strings.SplitN(str, preReleaseSeparator, 2)
+
+	str = parts[0]
+
+	var preRelease string
+
+	if len(parts) == 2 {
+		preRelease = parts[1]
+
+		if !isValidPreRelease(preRelease) {
+			return nil
+		}
+	}
+
+	// Empty parts, eg. `1.0.0.` or `1..0` are malformed.
+	if strings.HasPrefix(str, versionSeparator) ||
+		strings.HasSuffix(str, versionSeparator) ||
+		strings.Contains(str, versionSeparator+versionSeparator) {
+		return nil
+	}
+
 	var (
-		sv  = &semver{}
+		sv  = &semver{preRelease: preRelease}
 		spl = strings.Split(str, versionSeparator)
 	)
 
@@ -77,8 +145,29 @@ func newSemver(str string) Semver {
 	return sv
 }
 
+// isValidPreRelease returns whether the given pre-release label consists
+// of non-empty, dot separated identifiers of alphanumerics and hyphens.
+func isValidPreRelease(str string) bool {
+	for _, ident := range strings.Split(str, versionSeparator) {
+		if ident == "" {
+			return false
+		}
+
+		for _, r := range ident {
+			isAlnum := (r >= '0' && r <= '9') || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+
+			if !isAlnum && r != '-' {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
 // GreaterThan compares two semvers and returns if the pointer
-// receiver semver is greater than the compared to one.
+// receiver semver is greater than the compared to one. In case of
+// equal versions, a pre-release is less than the release.
 func (sv *semver) GreaterThan(cmp Semver) bool {
 	if sv.major > cmp.GetMajor() {
 		return true
@@ -92,10 +181,78 @@ func (sv *semver) GreaterThan(cmp Semver) bool {
 		return true
 	}
 
+	if sv.major == cmp.GetMajor() && sv.minor == cmp.GetMinor() && sv.patch == cmp.GetPatch() {
+		return comparePreReleases(sv.preRelease, cmp.GetPreRelease()) > 0
+	}
+
 	return false
 }
 
+// comparePreReleases compares two pre-release labels by the precedence of the
+// semver spec, and returns 1, if a is greater, -1, if b is greater, otherwise 0.
+// The empty label means release, which is greater than every pre-release.
+func comparePreReleases(a, b string) int {
+	if a == b {
+		return 0
+	}
+
+	if a == "" {
+		return 1
+	}
+
+	if b == "" {
+		return -1
+	}
+
+	var (
+		aIdents = strings.Split(a, versionSeparator)
+		bIdents = strings.Split(b, versionSeparator)
+	)
+
+	for i := 0; i < len(aIdents) && i < len(bIdents); i++ {
+		aNum, aErr := strconv.Atoi(aIdents[i])
+		bNum, bErr := strconv.Atoi(bIdents[i])
+
+		switch {
+		// Numeric identifiers are compared numerically.
+		case aErr == nil && bErr == nil:
+			if aNum != bNum {
+				return compareInts(aNum, bNum)
+			}
+		// Numeric identifiers have lower precedence than alphanumeric ones.
+		case aErr == nil:
+			return -1
+		case bErr == nil:
+			return 1
+		default:
+			if c := strings.Compare(aIdents[i], bIdents[i]); c != 0 {
+				return c
+			}
+		}
+	}
+
+	// The larger set of identifiers has higher precedence.
+	return compareInts(len(aIdents), len(bIdents))
+}
+
+// compareInts returns 1, if a is greater, -1, if b is greater, otherwise 0.
+func compareInts(a, b int) int {
+	if a > b {
+		return 1
+	}
+
+	if a < b {
+		return -1
+	}
+
+	return 0
+}
+
 func (sv *semver) ToString() string {
+	if sv.preRelease != "" {
+		return fmt.Sprintf("%d.%d.%d-%s", sv.major, sv.minor, sv.patch, sv.preRelease)
+	}
+
 	return fmt.Sprintf("%d.%d.%d", sv.major, sv.minor, sv.patch)
 }
 
@@ -108,11 +265,18 @@ func (sv *semver) GetMinor() int { return sv.minor }
 // GetPatch return the patch version of the semver.
 func (sv *semver) GetPatch() int { return sv.patch }
 
+// GetPreRelease returns the pre-release label of the semver, if any.
+func (sv *semver) GetPreRelease() string { return sv.preRelease }
+
+// IsPreRelease returns whether the semver has a pre-release label.
+func (sv *semver) IsPreRelease() bool { return sv.preRelease != "" }
+
 // Equals compares two Semver and returns whether two semvers are equal or not.
 func (sv *semver) Equals(cmp Semver) bool {
 	return (sv.major == cmp.GetMajor() &&
 		sv.minor == cmp.GetMinor() &&
-		sv.patch == cmp.GetPatch())
+		sv.patch == cmp.GetPatch() &&
+		sv.preRelease == cmp.GetPreRelease())
 }
 
 // WouldRollback compares two Sember and returns whether the compared
@@ -130,6 +294,85 @@ func (sv *semver) WouldRollback(cmp Semver) bool {
 		return true
 	}
 
+	if sv.major == cmp.GetMajor() && sv.minor == cmp.GetMinor() && sv.patch == cmp.GetPatch() {
+		return comparePreReleases(sv.preRelease, cmp.GetPreRelease()) < 0
+	}
+
 	return false
 
 }
+
+// Distance returns an approximation of how far the two semvers are from
+// each other: the absolute difference of major*1000000 + minor*1000 + patch
+// of both. It does not know about the versions of the migration file, so
+// eg. 1.0.0 and 2.0.0 are 1000000 apart; to get the number of pending
+// versions, use Engine.GetPendingVersionCount instead.
+func (sv *semver) Distance(cmp Semver) int {
+	diff := getSemverWeight(sv) - getSemverWeight(cmp)
+	if diff < 0 {
+		return -diff
+	}
+
+	return diff
+}
+
+// getSemverWeight returns the single number representation of the semver.
+func getSemverWeight(sv Semver) int {
+	return sv.GetMajor()*distanceMajorWeight + sv.GetMinor()*distanceMinorWeight + sv.GetPatch()
+}
+
+// MarshalBinary encodes the semver into 12 bytes, the major, minor
+// and patch versions as little-endian uint32s, followed by the
+// bytes of the pre-release label, if there is one.
+func (sv *semver) MarshalBinary() ([]byte, error) {
+	b := make([]byte, semverBinaryLength, semverBinaryLength+len(sv.preRelease))
+
+	binary.LittleEndian.PutUint32(b[0:4], uint32(sv.major))
+	binary.LittleEndian.PutUint32(b[4:8], uint32(sv.minor))
+	binary.LittleEndian.PutUint32(b[8:12], uint32(sv.patch))
+
+	return append(b, sv.preRelease...), nil
+}
+
+// UnmarshalBinary decodes the form created by MarshalBinary.
+func (sv *semver) UnmarshalBinary(b []byte) error {
+	if len(b) < semverBinaryLength {
+		return ErrInvalidSemverEncoding
+	}
+
+	preRelease := string(b[semverBinaryLength:])
+	if preRelease != "" && !isValidPreRelease(preRelease) {
+		return ErrInvalidSemverEncoding
+	}
+
+	sv.major = int(binary.LittleEndian.Uint32(b[0:4]))
+	sv.minor = int(binary.LittleEndian.Uint32(b[4:8]))
+	sv.patch = int(binary.LittleEndian.Uint32(b[8:12]))
+	sv.preRelease = preRelease
+
+	return nil
+}
+
+// MarshalText encodes the semver in the "X.Y.Z" or "X.Y.Z-label" form.
+func (sv *semver) MarshalText() ([]byte, error) {
+	return []byte(sv.ToString()), nil
+}
+
+// UnmarshalText decodes the "X.Y.Z" or "X.Y.Z-label" form of semver.
+func (sv *semver) UnmarshalText(b []byte) error {
+	parsed, ok := newSemver(string(b)).(*semver)
+	if !ok || parsed == nil {
+		return ErrBadVersioning
+	}
+
+	*sv = *parsed
+
+	return nil
+}
+
+// sortSemvers sorts the given semvers in ascending order.
+func sortSemvers(svs []Semver) {
+	sort.Slice(svs, func(i, j int) bool {
+		return svs[j].GreaterThan(svs[i])
+	})
+}
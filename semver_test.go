@@ -1,6 +1,7 @@
 package dbmigrator
 
 import (
+	"errors"
 	"reflect"
 	"testing"
 )
@@ -43,6 +44,36 @@ func TestNewSemver(t *testing.T) {
 			input:    "1.fo.3",
 			expected: &semver{major: 1, minor: 0, patch: 3},
 		},
+		{
+			name:     "returns <nil> in case of trailing separator",
+			input:    "1.0.0.",
+			expected: nil,
+		},
+		{
+			name:     "returns <nil> in case of leading separator",
+			input:    "v.1.0",
+			expected: nil,
+		},
+		{
+			name:     "returns <nil> in case of empty part",
+			input:    "1..0",
+			expected: nil,
+		},
+		{
+			name:     "returns semver ptr with pre-release",
+			input:    "v1.0.0-alpha.1",
+			expected: &semver{major: 1, preRelease: "alpha.1"},
+		},
+		{
+			name:     "returns <nil> in case of empty pre-release",
+			input:    "1.0.0-",
+			expected: nil,
+		},
+		{
+			name:     "returns <nil> in case of invalid pre-release",
+			input:    "1.0.0-alpha..1",
+			expected: nil,
+		},
 	}
 
 	for _, tc := range tt {
@@ -127,6 +158,42 @@ func TestGreaterThan(t *testing.T) {
 			sw2:       &semver{major: 3, minor: 1, patch: 1},
 			isGreater: false,
 		},
+		{
+			name:      "release is greater than its pre-release",
+			sw1:       &semver{major: 1},
+			sw2:       &semver{major: 1, preRelease: "rc.1"},
+			isGreater: true,
+		},
+		{
+			name:      "pre-release is not greater than its release",
+			sw1:       &semver{major: 1, preRelease: "rc.1"},
+			sw2:       &semver{major: 1},
+			isGreater: false,
+		},
+		{
+			name:      "pre-release is greater than the previous release",
+			sw1:       &semver{major: 1, minor: 1, preRelease: "alpha"},
+			sw2:       &semver{major: 1},
+			isGreater: true,
+		},
+		{
+			name:      "greater by numeric pre-release identifier",
+			sw1:       &semver{major: 1, preRelease: "alpha.10"},
+			sw2:       &semver{major: 1, preRelease: "alpha.2"},
+			isGreater: true,
+		},
+		{
+			name:      "alphanumeric identifier is greater than numeric",
+			sw1:       &semver{major: 1, preRelease: "alpha.beta"},
+			sw2:       &semver{major: 1, preRelease: "alpha.1"},
+			isGreater: true,
+		},
+		{
+			name:      "larger set of identifiers is greater",
+			sw1:       &semver{major: 1, preRelease: "alpha.1"},
+			sw2:       &semver{major: 1, preRelease: "alpha"},
+			isGreater: true,
+		},
 	}
 
 	for _, tc := range tt {
@@ -139,3 +206,166 @@ func TestGreaterThan(t *testing.T) {
 		})
 	}
 }
+
+func TestMustParseSemver(t *testing.T) {
+	type testCase struct {
+		name        string
+		input       string
+		shouldPanic bool
+	}
+
+	tt := []testCase{
+		{
+			name:        "returns the semver in case of valid input",
+			input:       "1.2.3",
+			shouldPanic: false,
+		},
+		{
+			name:        "panics in case of invalid input",
+			input:       "abc",
+			shouldPanic: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			defer func() {
+				if r := recover(); (r != nil) != tc.shouldPanic {
+					t.Errorf("expected panic: %t; got: %v\n", tc.shouldPanic, r)
+				}
+			}()
+
+			MustParseSemver(tc.input)
+		})
+	}
+}
+
+func TestSemverBinaryEncoding(t *testing.T) {
+	sv := newSemver("1.2.3")
+
+	b, err := sv.MarshalBinary()
+	if err != nil {
+		t.Fatalf("expected error: <nil>; got error: %v\n", err)
+	}
+
+	if expected := []byte{1, 0, 0, 0, 2, 0, 0, 0, 3, 0, 0, 0}; !reflect.DeepEqual(b, expected) {
+		t.Errorf("expected encoding: %v; got: %v\n", expected, b)
+	}
+
+	decoded := &semver{}
+	if err := decoded.UnmarshalBinary(b); err != nil {
+		t.Fatalf("expected error: <nil>; got error: %v\n", err)
+	}
+
+	if !decoded.Equals(sv) {
+		t.Errorf("expected semver: %s; got: %s\n", sv.ToString(), decoded.ToString())
+	}
+
+	if err := decoded.UnmarshalBinary(b[:4]); !errors.Is(err, ErrInvalidSemverEncoding) {
+		t.Errorf("expected error: %v; got error: %v\n", ErrInvalidSemverEncoding, err)
+	}
+
+	pre := newSemver("1.2.3-rc.1")
+
+	b, err = pre.MarshalBinary()
+	if err != nil {
+		t.Fatalf("expected error: <nil>; got error: %v\n", err)
+	}
+
+	if err := decoded.UnmarshalBinary(b); err != nil {
+		t.Fatalf("expected error: <nil>; got error: %v\n", err)
+	}
+
+	if !decoded.Equals(pre) {
+		t.Errorf("expected semver: %s; got: %s\n", pre.ToString(), decoded.ToString())
+	}
+}
+
+func TestIsPreRelease(t *testing.T) {
+	if newSemver("1.0.0").IsPreRelease() {
+		t.Errorf("expected release not to be pre-release\n")
+	}
+
+	if !newSemver("1.0.0-alpha.1").IsPreRelease() {
+		t.Errorf("expected pre-release to be pre-release\n")
+	}
+}
+
+func TestSemverTextEncoding(t *testing.T) {
+	type testCase struct {
+		name          string
+		input         string
+		expected      string
+		expectedError error
+	}
+
+	tt := []testCase{
+		{
+			name:     "decodes valid semver",
+			input:    "v1.2.3",
+			expected: "1.2.3",
+		},
+		{
+			name:          "returns error in case of invalid semver",
+			input:         "foo",
+			expectedError: ErrBadVersioning,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			sv := &semver{}
+
+			err := sv.UnmarshalText([]byte(tc.input))
+			if !errors.Is(err, tc.expectedError) {
+				t.Errorf("expected error: %v; got error: %v\n", tc.expectedError, err)
+			}
+
+			if err != nil {
+				return
+			}
+
+			if got, _ := sv.MarshalText(); string(got) != tc.expected {
+				t.Errorf("expected text: %s; got: %s\n", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestDistance(t *testing.T) {
+	type testCase struct {
+		name     string
+		sv1      Semver
+		sv2      Semver
+		expected int
+	}
+
+	tt := []testCase{
+		{
+			name:     "returns zero in case of equal versions",
+			sv1:      newSemver("1.2.3"),
+			sv2:      newSemver("1.2.3"),
+			expected: 0,
+		},
+		{
+			name:     "returns the difference of the patch versions",
+			sv1:      newSemver("1.2.3"),
+			sv2:      newSemver("1.2.1"),
+			expected: 2,
+		},
+		{
+			name:     "returns the weighted difference regardless of the order",
+			sv1:      newSemver("1.0.0"),
+			sv2:      newSemver("2.1.0"),
+			expected: 1001000,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.sv1.Distance(tc.sv2); got != tc.expected {
+				t.Errorf("expected distance: %d; got: %d\n", tc.expected, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package dbmigrator
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/balazskvancz/dbmigrator/models"
+)
+
+var (
+	ErrSnapshotsNotAllowed error = errors.New("snapshots are only allowed with WithAllowSnapshots")
+)
+
+// WithAllowSnapshots allows Snapshot and RestoreSnapshot,
+// which are meant for local development, not for production.
+func WithAllowSnapshots() EngineOptFunc {
+	return func(e *engine) {
+		e.allowSnapshots = true
+	}
+}
+
+// migrationsSnapshot is the JSON form of the migrations table.
+type migrationsSnapshot struct {
+	CurrentVersion string              `json:"currentVersion"`
+	Migrations     []*models.Migration `json:"migrations"`
+}
+
+// Snapshot returns the JSON encoded current version and stored
+// migrations, which can be restored later by RestoreSnapshot.
+func (e *engine) Snapshot() ([]byte, error) {
+	if !e.allowSnapshots {
+		return nil, ErrSnapshotsNotAllowed
+	}
+
+	currentVersion, err := e.GetCurrentVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	if currentVersion == nil {
+		currentVersion = bottomVersion
+	}
+
+	history := make([]*models.Migration, 0)
+
+	// Without the migrations table, the snapshot is empty.
+	if e.repositories.Migrations.DoesExists() {
+		if history, err = e.GetHistory(); err != nil {
+			return nil, err
+		}
+	}
+
+	if history == nil {
+		history = make([]*models.Migration, 0)
+	}
+
+	return json.Marshal(&migrationsSnapshot{
+		CurrentVersion: currentVersion.ToString(),
+		Migrations:     history,
+	})
+}
+
+// RestoreSnapshot replaces the stored migrations with the ones of the given
+// snapshot created by Snapshot. The records are inserted in their original
+// order inside a transaction, but their creation time is the time of the restore.
+func (e *engine) RestoreSnapshot(data []byte) error {
+	if !e.allowSnapshots {
+		return ErrSnapshotsNotAllowed
+	}
+
+	snapshot := &migrationsSnapshot{}
+	if err := json.Unmarshal(data, snapshot); err != nil {
+		return err
+	}
+
+	for _, m := range snapshot.Migrations {
+		if m == nil || newSemver(m.Version) == nil {
+			return ErrBadVersioning
+		}
+	}
+
+	if err := e.SetupDatabase(); err != nil {
+		return err
+	}
+
+	if err := e.db.StartTransaction(); err != nil {
+		return err
+	}
+
+	if err := e.restoreMigrations(snapshot.Migrations); err != nil {
+		if rollbackErr := e.db.Rollback(); rollbackErr != nil {
+			return rollbackErr
+		}
+
+		return err
+	}
+
+	return e.db.Commit()
+}
+
+// restoreMigrations replaces the stored migrations with the given ones.
+func (e *engine) restoreMigrations(migrations []*models.Migration) error {
+	if err := e.repositories.Migrations.DeleteAll(); err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if err := e.repositories.Migrations.Insert(m.Version, m.Description, m.BatchId, m.DurationMs); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
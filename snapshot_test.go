@@ -0,0 +1,100 @@
+package dbmigrator
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/balazskvancz/dbmigrator/database"
+	"github.com/balazskvancz/dbmigrator/models"
+	"github.com/balazskvancz/dbmigrator/repositories"
+)
+
+type mockTxDatabase struct {
+	committed  bool
+	rolledBack bool
+
+	database.Database
+}
+
+func (md *mockTxDatabase) StartTransaction() error { return nil }
+
+func (md *mockTxDatabase) Commit() error {
+	md.committed = true
+
+	return nil
+}
+
+func (md *mockTxDatabase) Rollback() error {
+	md.rolledBack = true
+
+	return nil
+}
+
+func TestSnapshotWithoutPermission(t *testing.T) {
+	e := &engine{}
+
+	if _, err := e.Snapshot(); !errors.Is(err, ErrSnapshotsNotAllowed) {
+		t.Errorf("expected error: %v; got error: %v\n", ErrSnapshotsNotAllowed, err)
+	}
+
+	if err := e.RestoreSnapshot(nil); !errors.Is(err, ErrSnapshotsNotAllowed) {
+		t.Errorf("expected error: %v; got error: %v\n", ErrSnapshotsNotAllowed, err)
+	}
+}
+
+func TestSnapshotAndRestore(t *testing.T) {
+	history := []*models.Migration{
+		{Id: 1, Version: "1.0.0", BatchId: "a"},
+		{Id: 2, Version: "1.1.0", BatchId: "b"},
+	}
+
+	source := &engine{
+		allowSnapshots: true,
+		repositories: &repositories.Repositories{
+			Migrations: &mockMigrationsRepository{doesExists: true, all: history, latest: history[1]},
+		},
+	}
+
+	data, err := source.Snapshot()
+	if err != nil {
+		t.Fatalf("expected error: <nil>; got error: %v\n", err)
+	}
+
+	snapshot := &migrationsSnapshot{}
+	if err := json.Unmarshal(data, snapshot); err != nil {
+		t.Fatalf("expected error: <nil>; got error: %v\n", err)
+	}
+
+	if snapshot.CurrentVersion != "1.1.0" {
+		t.Errorf("expected current version: 1.1.0; got: %s\n", snapshot.CurrentVersion)
+	}
+
+	var (
+		db   = &mockTxDatabase{}
+		repo = &mockMigrationsRepository{doesExists: true}
+	)
+
+	target := &engine{
+		allowSnapshots: true,
+		db:             db,
+		repositories:   &repositories.Repositories{Migrations: repo},
+	}
+
+	if err := target.RestoreSnapshot(data); err != nil {
+		t.Fatalf("expected error: <nil>; got error: %v\n", err)
+	}
+
+	if !repo.deletedAll || !db.committed {
+		t.Errorf("expected the table to be cleared and the transaction to be committed\n")
+	}
+
+	if expected := []string{"1.0.0", "1.1.0"}; !reflect.DeepEqual(repo.inserted, expected) {
+		t.Errorf("expected inserted versions: %v; got: %v\n", expected, repo.inserted)
+	}
+
+	if err := target.RestoreSnapshot([]byte(`{"migrations":[{"version":"foo"}]}`)); !errors.Is(err, ErrBadVersioning) {
+		t.Errorf("expected error: %v; got error: %v\n", ErrBadVersioning, err)
+	}
+}
@@ -0,0 +1,252 @@
+package dbmigrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/balazskvancz/dbmigrator/database"
+)
+
+const (
+	squashDatabasePrefix string = "dbmigrator_squash_"
+)
+
+var (
+	ErrInvalidSquashRange error = errors.New("squash range must be in ascending order")
+	ErrSquashAltersTable  error = errors.New("squashed versions alter a table created before them")
+)
+
+// schemaObject is a table or view of a mysql database.
+type schemaObject struct {
+	name   string
+	isView bool
+	stmt   string
+}
+
+// SquashVersions consolidates the versions after `from` up to and including
+// `to` into a single `to` version, and writes it to outputFile. The commands
+// are executed against a scratch database on the same server: first the UP
+// commands until `from`, then the squashed ones. The difference of the two
+// schemas, dumped via `SHOW CREATE TABLE`, gives the UP section – creating
+// the new tables and views, dropping the removed ones – and the DOWN section,
+// which reverts them. Since altering a table, which existed before, cannot be
+// expressed by its `CREATE` statement, it fails with ErrSquashAltersTable.
+// The original file remains untouched.
+//
+// Only mysql is supported, other drivers fail with ErrUnsupportedDriver.
+func (e *engine) SquashVersions(from, to, outputFile string) error {
+	fromVersion, toVersion := newSemver(from), newSemver(to)
+	if fromVersion == nil || toVersion == nil {
+		return ErrBadVersioning
+	}
+
+	if fromVersion.GreaterThan(toVersion) {
+		return ErrInvalidSquashRange
+	}
+
+	if e.db.GetDriverName() != "mysql" {
+		return ErrUnsupportedDriver
+	}
+
+	commands, err := e.getCommands()
+	if err != nil {
+		return err
+	}
+
+	baseCommands := filterCommands(bottomVersion, commands, DirectionUp, fromVersion)
+
+	squashedCommands := filterCommands(fromVersion, commands, DirectionUp, toVersion)
+	if len(squashedCommands) == 0 {
+		return ErrNothingToRun
+	}
+
+	before, after, err := e.buildSquashedSchemas(baseCommands, squashedCommands)
+	if err != nil {
+		return err
+	}
+
+	up, down, err := diffSchemas(before, after)
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "%s%s\n", e.getVersionPrefix(), toVersion.ToString())
+	fmt.Fprintf(&b, "%s squashed versions after %s until %s\n", descCommand, fromVersion.ToString(), toVersion.ToString())
+	fmt.Fprintf(&b, "%s\n", e.getUpMarker())
+
+	for _, stmt := range up {
+		fmt.Fprintf(&b, "%s;\n\n", stmt)
+	}
+
+	fmt.Fprintf(&b, "%s\n", e.getDownMarker())
+
+	for _, stmt := range down {
+		fmt.Fprintf(&b, "%s;\n\n", stmt)
+	}
+
+	return os.WriteFile(outputFile, []byte(b.String()), 0o644)
+}
+
+// buildSquashedSchemas runs the base commands against a scratch database,
+// then the squashed ones, and returns the schema before and after the latter.
+func (e *engine) buildSquashedSchemas(baseCommands, squashedCommands []Command) ([]schemaObject, []schemaObject, error) {
+	scratchName := fmt.Sprintf("%s%d", squashDatabasePrefix, time.Now().UnixNano())
+
+	if _, err := e.db.Exec(fmt.Sprintf("CREATE DATABASE `%s`", scratchName)); err != nil {
+		return nil, nil, err
+	}
+
+	defer func() {
+		if _, err := e.db.Exec(fmt.Sprintf("DROP DATABASE `%s`", scratchName)); err != nil {
+			e.Error(fmt.Sprintf("could not drop the scratch database: %v", err))
+		}
+	}()
+
+	scratchConf := e.dbConf
+	scratchConf.Database = scratchName
+
+	scratch, err := database.New(context.Background(), scratchConf)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer scratch.Close()
+
+	if err := execSquashCommands(scratch, baseCommands); err != nil {
+		return nil, nil, err
+	}
+
+	before, err := getSchemaObjects(scratch)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := execSquashCommands(scratch, squashedCommands); err != nil {
+		return nil, nil, err
+	}
+
+	after, err := getSchemaObjects(scratch)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return before, after, nil
+}
+
+// execSquashCommands executes the given commands one after another.
+func execSquashCommands(db database.Database, commands []Command) error {
+	for _, c := range commands {
+		if _, err := db.Exec(c.GetQuery()); err != nil {
+			return fmt.Errorf("squash of version %s failed: %w", c.Semver().ToString(), err)
+		}
+	}
+
+	return nil
+}
+
+// getSchemaObjects returns the tables and views of the given mysql
+// database with their `CREATE` statements, the tables first.
+func getSchemaObjects(db database.Database) ([]schemaObject, error) {
+	rows, err := db.Query("SHOW FULL TABLES")
+	if err != nil {
+		return nil, err
+	}
+
+	var tables, views []schemaObject
+
+	if err := writeRows(rows, io.Discard, func(values []string) string {
+		obj := schemaObject{name: values[0], isView: values[1] == "VIEW"}
+
+		if obj.isView {
+			views = append(views, obj)
+		} else {
+			tables = append(tables, obj)
+		}
+
+		return ""
+	}); err != nil {
+		return nil, err
+	}
+
+	// Views depend on the tables, so they must be created last.
+	objects := append(tables, views...)
+
+	for i := range objects {
+		stmt, err := showCreateTable(db, objects[i].name)
+		if err != nil {
+			return nil, err
+		}
+
+		objects[i].stmt = stmt
+	}
+
+	return objects, nil
+}
+
+// diffSchemas returns the statements turning the schema before into the one
+// after, and the ones reverting it. The created objects are dropped in
+// reverse order by the revert. It fails with ErrSquashAltersTable,
+// if the statement of an object, which exists in both, differs.
+func diffSchemas(before, after []schemaObject) ([]string, []string, error) {
+	existing := make(map[string]schemaObject, len(before))
+
+	for _, obj := range before {
+		existing[obj.name] = obj
+	}
+
+	var (
+		up      = make([]string, 0)
+		down    = make([]string, 0)
+		created = make([]schemaObject, 0)
+		kept    = make(map[string]bool, len(after))
+	)
+
+	for _, obj := range after {
+		prev, ok := existing[obj.name]
+		if !ok {
+			up = append(up, obj.stmt)
+			created = append(created, obj)
+
+			continue
+		}
+
+		if prev.stmt != obj.stmt {
+			return nil, nil, fmt.Errorf("%w: %s", ErrSquashAltersTable, obj.name)
+		}
+
+		kept[obj.name] = true
+	}
+
+	for i := len(created) - 1; i >= 0; i-- {
+		down = append(down, getDropStatement(created[i]))
+	}
+
+	for i := len(before) - 1; i >= 0; i-- {
+		if !kept[before[i].name] {
+			up = append(up, getDropStatement(before[i]))
+		}
+	}
+
+	for _, obj := range before {
+		if !kept[obj.name] {
+			down = append(down, obj.stmt)
+		}
+	}
+
+	return up, down, nil
+}
+
+// getDropStatement returns the statement dropping the given object.
+func getDropStatement(obj schemaObject) string {
+	if obj.isView {
+		return fmt.Sprintf("DROP VIEW `%s`", obj.name)
+	}
+
+	return fmt.Sprintf("DROP TABLE `%s`", obj.name)
+}
@@ -0,0 +1,221 @@
+package dbmigrator
+
+import (
+	"database/sql/driver"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSquashVersions(t *testing.T) {
+	type testCase struct {
+		name          string
+		driver        string
+		from          string
+		to            string
+		expectedExecs []string
+		expectedError error
+	}
+
+	content := `#v1.0.0
+#[UP]
+CREATE TABLE foo (id INT);
+#[DOWN]
+DROP TABLE foo;
+#v1.1.0
+#[UP]
+CREATE TABLE bar (id INT);
+#[DOWN]
+DROP TABLE bar;
+`
+
+	path := filepath.Join(t.TempDir(), "migrations.sql")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("could not write the migration file: %v\n", err)
+	}
+
+	tt := []testCase{
+		{
+			name:          "returns error in case of invalid version",
+			driver:        "mysql",
+			from:          "foo",
+			to:            "1.1.0",
+			expectedError: ErrBadVersioning,
+		},
+		{
+			name:          "returns error in case of descending range",
+			driver:        "mysql",
+			from:          "1.1.0",
+			to:            "1.0.0",
+			expectedError: ErrInvalidSquashRange,
+		},
+		{
+			name:          "returns error in case of unsupported driver",
+			driver:        "postgres",
+			from:          "0.0.0",
+			to:            "1.1.0",
+			expectedError: ErrUnsupportedDriver,
+		},
+		{
+			name:          "returns error, if there is no version after from",
+			driver:        "mysql",
+			from:          "1.1.0",
+			to:            "1.1.0",
+			expectedError: ErrNothingToRun,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			e := &engine{
+				conf: &Config{MigrationsFilePath: path},
+				db:   newMockScriptedDatabase(t, tc.driver, nil),
+			}
+
+			output := filepath.Join(t.TempDir(), "squashed.sql")
+
+			if err := e.SquashVersions(tc.from, tc.to, output); !errors.Is(err, tc.expectedError) {
+				t.Errorf("expected error: %v; got error: %v\n", tc.expectedError, err)
+			}
+
+			if _, err := os.Stat(output); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("expected no output file; got error: %v\n", err)
+			}
+		})
+	}
+}
+
+func TestSquashVersionsDropsScratchDatabase(t *testing.T) {
+	content := "#v1.0.0\n#[UP]\nCREATE TABLE foo (id INT);\n"
+
+	path := filepath.Join(t.TempDir(), "migrations.sql")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("could not write the migration file: %v\n", err)
+	}
+
+	db := newMockScriptedDatabase(t, "mysql", nil)
+
+	// The scratch database can not be connected, since
+	// no mysql driver is registered in the tests.
+	e := &engine{
+		conf: &Config{MigrationsFilePath: path},
+		db:   db,
+	}
+
+	if err := e.SquashVersions("0.0.0", "1.0.0", filepath.Join(t.TempDir(), "squashed.sql")); err == nil {
+		t.Fatalf("expected connection error; got <nil>\n")
+	}
+
+	execs := db.execs(t)
+
+	if len(execs) != 2 || !strings.HasPrefix(execs[0], "CREATE DATABASE `"+squashDatabasePrefix) || !strings.HasPrefix(execs[1], "DROP DATABASE `"+squashDatabasePrefix) {
+		t.Errorf("expected the scratch database to be created and dropped; got: %v\n", execs)
+	}
+}
+
+func TestGetSchemaObjects(t *testing.T) {
+	db := newMockScriptedDatabase(t, "mysql", map[string]scriptedResult{
+		"SHOW FULL TABLES": {
+			columns: []string{"Tables_in_foo", "Table_type"},
+			rows:    [][]driver.Value{{"bar_view", "VIEW"}, {"foo", "BASE TABLE"}},
+		},
+		"SHOW CREATE TABLE `foo`": {
+			columns: []string{"Table", "Create Table"},
+			rows:    [][]driver.Value{{"foo", "CREATE TABLE `foo` (`id` int)"}},
+		},
+		"SHOW CREATE TABLE `bar_view`": {
+			columns: []string{"View", "Create View", "character_set_client", "collation_connection"},
+			rows:    [][]driver.Value{{"bar_view", "CREATE VIEW `bar_view` AS SELECT 1", "utf8mb4", "utf8mb4_0900_ai_ci"}},
+		},
+	})
+
+	objects, err := getSchemaObjects(db)
+	if err != nil {
+		t.Fatalf("expected error: <nil>; got error: %v\n", err)
+	}
+
+	expected := []schemaObject{
+		{name: "foo", isView: false, stmt: "CREATE TABLE `foo` (`id` int)"},
+		{name: "bar_view", isView: true, stmt: "CREATE VIEW `bar_view` AS SELECT 1"},
+	}
+
+	if !reflect.DeepEqual(objects, expected) {
+		t.Errorf("expected objects: %v; got: %v\n", expected, objects)
+	}
+}
+
+func TestDiffSchemas(t *testing.T) {
+	type testCase struct {
+		name          string
+		before        []schemaObject
+		after         []schemaObject
+		expectedUp    []string
+		expectedDown  []string
+		expectedError error
+	}
+
+	var (
+		foo      = schemaObject{name: "foo", stmt: "CREATE TABLE `foo` (`id` int)"}
+		bar      = schemaObject{name: "bar", stmt: "CREATE TABLE `bar` (`id` int)"}
+		baz      = schemaObject{name: "baz", isView: true, stmt: "CREATE VIEW `baz` AS SELECT 1"}
+		alterFoo = schemaObject{name: "foo", stmt: "CREATE TABLE `foo` (`id` int, `bar` int)"}
+	)
+
+	tt := []testCase{
+		{
+			name:          "returns no statements, if nothing changed",
+			before:        []schemaObject{foo},
+			after:         []schemaObject{foo},
+			expectedUp:    []string{},
+			expectedDown:  []string{},
+			expectedError: nil,
+		},
+		{
+			name:          "creates the new objects and drops them in reverse order",
+			before:        []schemaObject{foo},
+			after:         []schemaObject{foo, bar, baz},
+			expectedUp:    []string{bar.stmt, baz.stmt},
+			expectedDown:  []string{"DROP VIEW `baz`", "DROP TABLE `bar`"},
+			expectedError: nil,
+		},
+		{
+			name:          "drops the removed objects and recreates them",
+			before:        []schemaObject{foo, bar},
+			after:         []schemaObject{foo},
+			expectedUp:    []string{"DROP TABLE `bar`"},
+			expectedDown:  []string{bar.stmt},
+			expectedError: nil,
+		},
+		{
+			name:          "returns error, if an existing table is altered",
+			before:        []schemaObject{foo},
+			after:         []schemaObject{alterFoo},
+			expectedError: ErrSquashAltersTable,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			up, down, err := diffSchemas(tc.before, tc.after)
+
+			if !errors.Is(err, tc.expectedError) {
+				t.Fatalf("expected error: %v; got error: %v\n", tc.expectedError, err)
+			}
+
+			if err != nil {
+				return
+			}
+
+			if !reflect.DeepEqual(up, tc.expectedUp) {
+				t.Errorf("expected up: %v; got: %v\n", tc.expectedUp, up)
+			}
+
+			if !reflect.DeepEqual(down, tc.expectedDown) {
+				t.Errorf("expected down: %v; got: %v\n", tc.expectedDown, down)
+			}
+		})
+	}
+}
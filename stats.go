@@ -0,0 +1,134 @@
+package dbmigrator
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// MigrationFileStats summarizes the content of the migration file.
+type MigrationFileStats struct {
+	TotalVersions    int
+	TotalCommands    int
+	UpCommandCount   int
+	DownCommandCount int
+	EarliestVersion  Semver
+	LatestVersion    Semver
+}
+
+// newMigrationFileStats creates the stats based upon the parsed commands.
+func newMigrationFileStats(commands []Command) MigrationFileStats {
+	var (
+		stats    = MigrationFileStats{TotalCommands: len(commands)}
+		versions = make(map[string]struct{})
+	)
+
+	for _, c := range commands {
+		if c.GetDirection() == DirectionDown {
+			stats.DownCommandCount++
+		} else {
+			stats.UpCommandCount++
+		}
+
+		sv := c.Semver()
+
+		versions[sv.ToString()] = struct{}{}
+
+		if stats.EarliestVersion == nil || stats.EarliestVersion.GreaterThan(sv) {
+			stats.EarliestVersion = sv
+		}
+
+		if stats.LatestVersion == nil || sv.GreaterThan(stats.LatestVersion) {
+			stats.LatestVersion = sv
+		}
+	}
+
+	stats.TotalVersions = len(versions)
+
+	return stats
+}
+
+// String returns the human-readable form of the stats.
+func (s MigrationFileStats) String() string {
+	versionString := func(sv Semver) string {
+		if sv == nil {
+			return "-"
+		}
+
+		return sv.ToString()
+	}
+
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "versions:         %d\n", s.TotalVersions)
+	fmt.Fprintf(&b, "commands:         %d\n", s.TotalCommands)
+	fmt.Fprintf(&b, "up commands:      %d\n", s.UpCommandCount)
+	fmt.Fprintf(&b, "down commands:    %d\n", s.DownCommandCount)
+	fmt.Fprintf(&b, "earliest version: %s\n", versionString(s.EarliestVersion))
+	fmt.Fprintf(&b, "latest version:   %s\n", versionString(s.LatestVersion))
+
+	return b.String()
+}
+
+// EngineStats summarizes the last run of the engine.
+type EngineStats struct {
+	VersionsApplied []string
+	CommandsRun     int
+	TotalDuration   time.Duration
+	TransactionUsed bool
+	RolledBack      bool
+}
+
+// Stats returns the stats of the last run. It is reset
+// at the start of every run, and requires no database queries.
+func (e *engine) Stats() EngineStats {
+	e.statsMu.Lock()
+	defer e.statsMu.Unlock()
+
+	stats := e.stats
+	stats.VersionsApplied = append([]string(nil), e.stats.VersionsApplied...)
+
+	return stats
+}
+
+// startStats resets the stats, and returns the function,
+// which records the duration of the run.
+func (e *engine) startStats() func() {
+	start := time.Now()
+
+	e.recordStats(func(s *EngineStats) { *s = EngineStats{} })
+
+	return func() {
+		e.recordStats(func(s *EngineStats) { s.TotalDuration = time.Since(start) })
+	}
+}
+
+// recordStats modifies the stats with the given function.
+func (e *engine) recordStats(fn func(*EngineStats)) {
+	e.statsMu.Lock()
+	defer e.statsMu.Unlock()
+
+	fn(&e.stats)
+}
+
+// getExecutedVersions returns the unique versions
+// of the given commands in execution order.
+func getExecutedVersions(commands []Command) []string {
+	var (
+		versions = make([]string, 0)
+		seen     = make(map[string]struct{})
+	)
+
+	for _, c := range commands {
+		v := c.Semver().ToString()
+
+		if _, ok := seen[v]; ok {
+			continue
+		}
+
+		seen[v] = struct{}{}
+		versions = append(versions, v)
+	}
+
+	return versions
+}
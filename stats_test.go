@@ -0,0 +1,50 @@
+package dbmigrator
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewMigrationFileStats(t *testing.T) {
+	type testCase struct {
+		name     string
+		commands []Command
+		expected MigrationFileStats
+	}
+
+	tt := []testCase{
+		{
+			name:     "returns empty stats in case of empty slice",
+			commands: []Command{},
+			expected: MigrationFileStats{},
+		},
+		{
+			name: "returns the right stats",
+			commands: []Command{
+				mustNewCommand(nil, "", newSemver("1.1.0"), DirectionUp),
+				mustNewCommand(nil, "", newSemver("1.1.0"), DirectionDown),
+				mustNewCommand(nil, "", newSemver("2.0.1"), DirectionUp),
+				mustNewCommand(nil, "", newSemver("1.0.0"), DirectionUp),
+				mustNewCommand(nil, "", newSemver("1.0.0"), DirectionUp),
+			},
+			expected: MigrationFileStats{
+				TotalVersions:    3,
+				TotalCommands:    5,
+				UpCommandCount:   4,
+				DownCommandCount: 1,
+				EarliestVersion:  newSemver("1.0.0"),
+				LatestVersion:    newSemver("2.0.1"),
+			},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			got := newMigrationFileStats(tc.commands)
+
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected stats: %v; got: %v\n", tc.expected, got)
+			}
+		})
+	}
+}
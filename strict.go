@@ -0,0 +1,52 @@
+package dbmigrator
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrMissingDownMigration error = errors.New("missing down migration")
+)
+
+// MissingDownMigrationError is returned in strict mode, if a version
+// has UP commands, but no DOWN commands. It wraps ErrMissingDownMigration.
+type MissingDownMigrationError struct {
+	Version Semver
+}
+
+func (e *MissingDownMigrationError) Error() string {
+	return fmt.Sprintf("%v: version %s", ErrMissingDownMigration, e.Version.ToString())
+}
+
+func (e *MissingDownMigrationError) Unwrap() error { return ErrMissingDownMigration }
+
+// WithStrictMode makes the parsing fail, if a version has UP commands, but
+// no DOWN commands, so every schema change must be reversible.
+func WithStrictMode() EngineOptFunc {
+	return func(e *engine) {
+		e.strictMode = true
+	}
+}
+
+// checkDownMigrations returns *MissingDownMigrationError for the
+// lowest version, which has UP commands, but no DOWN commands.
+func checkDownMigrations(commands []Command) error {
+	hasDown := make(map[string]bool)
+
+	for _, c := range commands {
+		if c.GetDirection() == DirectionDown {
+			hasDown[c.Semver().ToString()] = true
+		}
+	}
+
+	versions := getUniqueVersions(commands, func(sv Semver) bool {
+		return !hasDown[sv.ToString()]
+	})
+
+	if len(versions) > 0 {
+		return &MissingDownMigrationError{Version: versions[0]}
+	}
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package dbmigrator
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseLinesWithStrictMode(t *testing.T) {
+	type testCase struct {
+		name            string
+		lines           MigrationLines
+		expectedVersion string
+	}
+
+	tt := []testCase{
+		{
+			name: "returns no error, if every version has down commands",
+			lines: MigrationLines{
+				"#v1",
+				"#[UP]",
+				"CREATE TABLE foo (id INT);",
+				"#[DOWN]",
+				"DROP TABLE foo;",
+			},
+			expectedVersion: "",
+		},
+		{
+			name: "returns the version without down commands",
+			lines: MigrationLines{
+				"#v1",
+				"#[UP]",
+				"CREATE TABLE foo (id INT);",
+				"#[DOWN]",
+				"DROP TABLE foo;",
+				"#v2",
+				"INSERT INTO foo VALUES (1);",
+			},
+			expectedVersion: "2.0.0",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			e := &engine{}
+
+			WithStrictMode()(e)
+
+			_, err := e.ParseLines(tc.lines)
+
+			if tc.expectedVersion == "" {
+				if err != nil {
+					t.Errorf("expected error: <nil>; got error: %v\n", err)
+				}
+
+				return
+			}
+
+			var missingErr *MissingDownMigrationError
+
+			if !errors.As(err, &missingErr) || !errors.Is(err, ErrMissingDownMigration) {
+				t.Fatalf("expected *MissingDownMigrationError; got error: %v\n", err)
+			}
+
+			if got := missingErr.Version.ToString(); got != tc.expectedVersion {
+				t.Errorf("expected version: %s; got: %s\n", tc.expectedVersion, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package dbmigrator
+
+import "strings"
+
+const (
+	tagCommand string = "#[TAG]"
+)
+
+// WithTagFilter makes the engine include only the versions, which are
+// tagged with at least one of the given tags via `#[TAG] name`. This way
+// multiple services sharing a database can apply only their own subset.
+//
+// The current version is shared by every subset, so the version ranges
+// of the subsets must not interleave: once a subset records a version,
+// the lower versions of the other subsets are skipped.
+func WithTagFilter(tags ...string) EngineOptFunc {
+	return func(e *engine) {
+		e.tagFilter = append(e.tagFilter, tags...)
+	}
+}
+
+// parseTagCommand returns the comma separated tags of the given `#[TAG] name` marker.
+func parseTagCommand(line string) []string {
+	tags := make([]string, 0)
+
+	for _, tag := range strings.Split(strings.TrimPrefix(line, tagCommand), envSeparator) {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			tags = append(tags, tag)
+		}
+	}
+
+	return tags
+}
+
+// filterByTags returns the commands, whose version has at least
+// one of the tags of the filter. Without filter, every command is kept.
+func (e *engine) filterByTags(commands []Command) []Command {
+	if len(e.tagFilter) == 0 {
+		return commands
+	}
+
+	filtered := make([]Command, 0, len(commands))
+
+	for _, c := range commands {
+		if hasAnyTag(c.GetTags(), e.tagFilter) {
+			filtered = append(filtered, c)
+		}
+	}
+
+	return filtered
+}
+
+// hasAnyTag returns whether any of the tags is in the filter.
+func hasAnyTag(tags, filter []string) bool {
+	for _, tag := range tags {
+		for _, f := range filter {
+			if tag == f {
+				return true
+			}
+		}
+	}
+
+	return false
+}
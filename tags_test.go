@@ -0,0 +1,118 @@
+package dbmigrator
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/balazskvancz/dbmigrator/models"
+	"github.com/balazskvancz/dbmigrator/repositories"
+)
+
+func TestWithTagFilter(t *testing.T) {
+	type testCase struct {
+		name     string
+		filter   []string
+		expected []string
+	}
+
+	lines := MigrationLines{
+		"#v1",
+		"#[TAG] service-a",
+		"CREATE TABLE foo (id INTEGER NOT NULL);",
+		"#v2",
+		"CREATE TABLE bar (id INTEGER NOT NULL);",
+		"#[TAG] service-b, shared",
+		"#v3",
+		"CREATE TABLE baz (id INTEGER NOT NULL);",
+	}
+
+	tt := []testCase{
+		{
+			name:     "keeps every version without filter",
+			filter:   nil,
+			expected: []string{"1.0.0", "2.0.0", "3.0.0"},
+		},
+		{
+			name:     "keeps only the tagged versions",
+			filter:   []string{"service-a"},
+			expected: []string{"1.0.0"},
+		},
+		{
+			name:     "keeps the versions with any of the tags",
+			filter:   []string{"shared", "service-a"},
+			expected: []string{"1.0.0", "2.0.0"},
+		},
+		{
+			name:     "keeps nothing in case of unknown tag",
+			filter:   []string{"service-c"},
+			expected: []string{},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			e := &engine{}
+
+			WithTagFilter(tc.filter...)(e)
+
+			commands, err := e.ParseLines(lines)
+			if err != nil {
+				t.Fatalf("expected error: <nil>; got error: %v\n", err)
+			}
+
+			got := make([]string, 0)
+			for _, c := range commands {
+				got = append(got, c.Semver().ToString())
+			}
+
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected versions: %v; got: %v\n", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestWithTagFilterInterleavedVersions(t *testing.T) {
+	content := `#v1.5.0
+#[TAG] service-b
+CREATE TABLE bar (id INT);
+#v2.0.0
+#[TAG] service-a
+CREATE TABLE foo (id INT);
+`
+
+	path := filepath.Join(t.TempDir(), "migrations.sql")
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("could not write the migration file: %v\n", err)
+	}
+
+	var (
+		db   = &mockRecordingDatabase{}
+		repo = &mockMigrationsRepository{
+			doesExists: true,
+			all:        []*models.Migration{{Version: "2.0.0"}},
+			latest:     &models.Migration{Version: "2.0.0"},
+		}
+	)
+
+	e := &engine{
+		conf:         &Config{MigrationsFilePath: path},
+		db:           db,
+		repositories: &repositories.Repositories{Migrations: repo},
+	}
+
+	WithTagFilter("service-b")(e)
+
+	// The current version is global, so once service-a recorded 2.0.0,
+	// the lower version of service-b is never applied.
+	if err := e.Process(); !errors.Is(err, ErrNothingToRun) {
+		t.Errorf("expected error: %v; got error: %v\n", ErrNothingToRun, err)
+	}
+
+	if len(db.executed) != 0 {
+		t.Errorf("expected no executed statements; got: %v\n", db.executed)
+	}
+}
@@ -0,0 +1,8 @@
+// Package testintegration holds the integration tests of dbmigrator,
+// which run the engine against real databases started by testcontainers-go.
+//
+// The tests are guarded by the `integration` build tag and need a running
+// docker daemon:
+//
+//	go test -tags integration ./...
+package testintegration
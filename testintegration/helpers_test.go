@@ -0,0 +1,306 @@
+//go:build integration
+
+package testintegration
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/balazskvancz/dbmigrator"
+)
+
+const migrationsContent string = `
+#v1
+#[UP]
+CREATE TABLE foo (
+	id INTEGER NOT NULL,
+
+	PRIMARY KEY (id)
+);
+
+#[DOWN]
+DROP TABLE foo;
+
+#v1.1
+#[UP]
+ALTER TABLE foo ADD COLUMN bar VARCHAR (10) DEFAULT NULL;
+
+#[DOWN]
+ALTER TABLE foo DROP COLUMN bar;
+`
+
+// testEnv is a started database, along with the config of the
+// engine and a plain connection to check the results.
+type testEnv struct {
+	conf *dbmigrator.Config
+	db   *sql.DB
+
+	// columnQuery selects the given column of the table foo,
+	// so it has exactly one driver specific placeholder.
+	columnQuery string
+}
+
+// writeMigrationsFile writes the shared migrations content
+// into a temporary file and returns its path.
+func writeMigrationsFile(t *testing.T) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "migrations.sql")
+
+	if err := os.WriteFile(path, []byte(migrationsContent), 0o644); err != nil {
+		t.Fatalf("could not write migrations file: %v\n", err)
+	}
+
+	return path
+}
+
+// newEngine creates an engine with the config of the environment.
+func (env *testEnv) newEngine(t *testing.T) dbmigrator.Engine {
+	t.Helper()
+
+	e, err := dbmigrator.New(env.conf)
+	if err != nil {
+		t.Fatalf("could not create engine: %v\n", err)
+	}
+
+	t.Cleanup(e.CloseDatabase)
+
+	return e
+}
+
+// latestVersion returns the latest version stored in the migrations table.
+func (env *testEnv) latestVersion(t *testing.T) string {
+	t.Helper()
+
+	var version string
+
+	row := env.db.QueryRow("SELECT version FROM __migrations__ ORDER BY id DESC LIMIT 1")
+	if err := row.Scan(&version); err != nil {
+		t.Fatalf("could not query the latest version: %v\n", err)
+	}
+
+	return version
+}
+
+// hasColumn returns whether the given column of the table foo exists.
+func (env *testEnv) hasColumn(t *testing.T, column string) bool {
+	t.Helper()
+
+	var name string
+
+	return env.db.QueryRow(env.columnQuery, column).Scan(&name) == nil
+}
+
+func testSetupDatabase(t *testing.T, env *testEnv) {
+	e := env.newEngine(t)
+
+	// Calling it twice makes sure, that an already existing
+	// migrations table is detected and not created again.
+	for i := 0; i < 2; i++ {
+		if err := e.SetupDatabase(); err != nil {
+			t.Fatalf("expected error: <nil>; got error: %v\n", err)
+		}
+	}
+}
+
+func testProcess(t *testing.T, env *testEnv) {
+	e := env.newEngine(t)
+
+	lines, err := e.GetLines()
+	if err != nil {
+		t.Fatalf("could not read lines: %v\n", err)
+	}
+
+	commands, err := e.ParseLines(lines)
+	if err != nil {
+		t.Fatalf("could not parse lines: %v\n", err)
+	}
+
+	if len(commands) != 4 {
+		t.Fatalf("expected commands: %d; got: %d\n", 4, len(commands))
+	}
+
+	if err := e.Process(); err != nil {
+		t.Fatalf("expected error: <nil>; got error: %v\n", err)
+	}
+
+	if got := env.latestVersion(t); got != "1.1.0" {
+		t.Errorf("expected version: %s; got: %s\n", "1.1.0", got)
+	}
+
+	if !env.hasColumn(t, "bar") {
+		t.Error("expected column bar to exist")
+	}
+
+	// Running it again must not find anything to do.
+	if err := e.Process(); !errors.Is(err, dbmigrator.ErrNothingToRun) {
+		t.Errorf("expected error: %v; got error: %v\n", dbmigrator.ErrNothingToRun, err)
+	}
+}
+
+func testProcessWithDirection(t *testing.T, env *testEnv) {
+	e := env.newEngine(t)
+
+	if err := e.Process(); err != nil {
+		t.Fatalf("expected error: <nil>; got error: %v\n", err)
+	}
+
+	if err := e.ProcessWithDirection(dbmigrator.DirectionDown); err != nil {
+		t.Fatalf("expected error: <nil>; got error: %v\n", err)
+	}
+
+	if got := env.latestVersion(t); got != "1.0.0" {
+		t.Errorf("expected version: %s; got: %s\n", "1.0.0", got)
+	}
+
+	if env.hasColumn(t, "bar") {
+		t.Error("expected column bar to be dropped")
+	}
+}
+
+func testProcessWithTargetVersion(t *testing.T, env *testEnv) {
+	e := env.newEngine(t)
+
+	if err := e.ProcessWithTargetVersion("1"); err != nil {
+		t.Fatalf("expected error: <nil>; got error: %v\n", err)
+	}
+
+	if got := env.latestVersion(t); got != "1.0.0" {
+		t.Errorf("expected version: %s; got: %s\n", "1.0.0", got)
+	}
+
+	if env.hasColumn(t, "bar") {
+		t.Error("expected column bar not to exist")
+	}
+}
+
+// processVersionByVersion applies the versions in separate runs,
+// so each of them is stored in the migration history.
+func processVersionByVersion(t *testing.T, e dbmigrator.Engine) {
+	t.Helper()
+
+	if err := e.ProcessWithTargetVersion("1"); err != nil {
+		t.Fatalf("expected error: <nil>; got error: %v\n", err)
+	}
+
+	if err := e.Process(); err != nil {
+		t.Fatalf("expected error: <nil>; got error: %v\n", err)
+	}
+}
+
+func testGetHistory(t *testing.T, env *testEnv) {
+	e := env.newEngine(t)
+
+	processVersionByVersion(t, e)
+
+	if err := e.Rollback(1); err != nil {
+		t.Fatalf("expected error: <nil>; got error: %v\n", err)
+	}
+
+	history, err := e.GetHistory()
+	if err != nil {
+		t.Fatalf("expected error: <nil>; got error: %v\n", err)
+	}
+
+	expected := []string{"1.0.0", "1.1.0", "1.0.0"}
+
+	if len(history) != len(expected) {
+		t.Fatalf("expected history length: %d; got: %d\n", len(expected), len(history))
+	}
+
+	for i, m := range history {
+		if m.Version != expected[i] {
+			t.Errorf("expected version: %s; got: %s\n", expected[i], m.Version)
+		}
+
+		if m.BatchId == "" {
+			t.Errorf("expected batch id of version %s\n", m.Version)
+		}
+
+		if m.CreatedAt.IsZero() {
+			t.Errorf("expected creation time of version %s\n", m.Version)
+		}
+	}
+
+	if history[1].BatchId == history[2].BatchId {
+		t.Errorf("expected different batch ids; got: %s\n", history[1].BatchId)
+	}
+}
+
+func testRollbackLast(t *testing.T, env *testEnv) {
+	e := env.newEngine(t)
+
+	processVersionByVersion(t, e)
+
+	if err := e.Rollback(1); err != nil {
+		t.Fatalf("expected error: <nil>; got error: %v\n", err)
+	}
+
+	if got := env.latestVersion(t); got != "1.0.0" {
+		t.Errorf("expected version: %s; got: %s\n", "1.0.0", got)
+	}
+
+	if env.hasColumn(t, "bar") {
+		t.Error("expected column bar to be dropped")
+	}
+
+	if !env.hasColumn(t, "id") {
+		t.Error("expected table foo to be kept")
+	}
+}
+
+func testRollbackAll(t *testing.T, env *testEnv) {
+	e := env.newEngine(t)
+
+	processVersionByVersion(t, e)
+
+	if err := e.Rollback(2); err != nil {
+		t.Fatalf("expected error: <nil>; got error: %v\n", err)
+	}
+
+	if got := env.latestVersion(t); got != "0.0.0" {
+		t.Errorf("expected version: %s; got: %s\n", "0.0.0", got)
+	}
+
+	if env.hasColumn(t, "id") {
+		t.Error("expected table foo to be dropped")
+	}
+
+	// Everything is rolled back, so there is nothing left to roll back.
+	if err := e.Rollback(1); !errors.Is(err, dbmigrator.ErrNothingToRun) {
+		t.Errorf("expected error: %v; got error: %v\n", dbmigrator.ErrNothingToRun, err)
+	}
+}
+
+func testRecreateMigrationsTable(t *testing.T, env *testEnv) {
+	e := env.newEngine(t)
+
+	if err := e.ProcessWithTargetVersion("1"); err != nil {
+		t.Fatalf("expected error: <nil>; got error: %v\n", err)
+	}
+
+	if err := e.ForceMigrationsTableRecreate(); err != nil {
+		t.Fatalf("expected error: <nil>; got error: %v\n", err)
+	}
+
+	// The insert after the recreation must not collide with the restored ids.
+	if err := e.Process(); err != nil {
+		t.Fatalf("expected error: <nil>; got error: %v\n", err)
+	}
+
+	if got := env.latestVersion(t); got != "1.1.0" {
+		t.Errorf("expected version: %s; got: %s\n", "1.1.0", got)
+	}
+
+	history, err := e.GetHistory()
+	if err != nil {
+		t.Fatalf("expected error: <nil>; got error: %v\n", err)
+	}
+
+	if len(history) != 2 {
+		t.Errorf("expected history length: %d; got: %d\n", 2, len(history))
+	}
+}
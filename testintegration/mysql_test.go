@@ -0,0 +1,100 @@
+//go:build integration
+
+package testintegration
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/balazskvancz/dbmigrator"
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/testcontainers/testcontainers-go/modules/mysql"
+)
+
+const (
+	mysqlImage    string = "mysql:8.0.36"
+	mysqlDatabase string = "dbmigrator"
+	mysqlUsername string = "dbmigrator"
+	mysqlPassword string = "dbmigrator"
+)
+
+func startMySQL(t *testing.T) *testEnv {
+	t.Helper()
+
+	ctx := context.Background()
+
+	container, err := mysql.Run(ctx, mysqlImage,
+		mysql.WithDatabase(mysqlDatabase),
+		mysql.WithUsername(mysqlUsername),
+		mysql.WithPassword(mysqlPassword),
+	)
+	if err != nil {
+		t.Fatalf("could not start mysql container: %v\n", err)
+	}
+
+	t.Cleanup(func() {
+		if err := container.Terminate(context.Background()); err != nil {
+			t.Errorf("could not terminate mysql container: %v\n", err)
+		}
+	})
+
+	host, err := container.Host(ctx)
+	if err != nil {
+		t.Fatalf("could not get mysql host: %v\n", err)
+	}
+
+	port, err := container.MappedPort(ctx, "3306/tcp")
+	if err != nil {
+		t.Fatalf("could not get mysql port: %v\n", err)
+	}
+
+	dsn, err := container.ConnectionString(ctx)
+	if err != nil {
+		t.Fatalf("could not get mysql connection string: %v\n", err)
+	}
+
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		t.Fatalf("could not open mysql connection: %v\n", err)
+	}
+
+	t.Cleanup(func() { db.Close() })
+
+	return &testEnv{
+		conf: &dbmigrator.Config{
+			Host:               host,
+			Port:               port.Int(),
+			Database:           mysqlDatabase,
+			Username:           mysqlUsername,
+			Password:           mysqlPassword,
+			DriverName:         "mysql",
+			MigrationsFilePath: writeMigrationsFile(t),
+		},
+		db: db,
+		columnQuery: `
+			SELECT
+				COLUMN_NAME
+			FROM INFORMATION_SCHEMA.COLUMNS
+			WHERE TABLE_SCHEMA = DATABASE()
+			AND TABLE_NAME = 'foo'
+			AND COLUMN_NAME = ?
+		`,
+	}
+}
+
+func TestMySQLSetupDatabase(t *testing.T) { testSetupDatabase(t, startMySQL(t)) }
+
+func TestMySQLProcess(t *testing.T) { testProcess(t, startMySQL(t)) }
+
+func TestMySQLProcessWithDirection(t *testing.T) { testProcessWithDirection(t, startMySQL(t)) }
+
+func TestMySQLProcessWithTargetVersion(t *testing.T) { testProcessWithTargetVersion(t, startMySQL(t)) }
+
+func TestMySQLGetHistory(t *testing.T) { testGetHistory(t, startMySQL(t)) }
+
+func TestMySQLRollbackLast(t *testing.T) { testRollbackLast(t, startMySQL(t)) }
+
+func TestMySQLRollbackAll(t *testing.T) { testRollbackAll(t, startMySQL(t)) }
+
+func TestMySQLRecreateMigrationsTable(t *testing.T) { testRecreateMigrationsTable(t, startMySQL(t)) }
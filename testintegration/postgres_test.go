@@ -0,0 +1,165 @@
+//go:build integration
+
+package testintegration
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/balazskvancz/dbmigrator"
+	"github.com/balazskvancz/dbmigrator/database"
+	_ "github.com/lib/pq"
+	"github.com/testcontainers/testcontainers-go/modules/postgres"
+)
+
+const (
+	postgresImage    string = "postgres:16-alpine"
+	postgresDatabase string = "dbmigrator"
+	postgresUsername string = "dbmigrator"
+	postgresPassword string = "dbmigrator"
+)
+
+func startPostgres(t *testing.T) *testEnv {
+	t.Helper()
+
+	ctx := context.Background()
+
+	container, err := postgres.Run(ctx, postgresImage,
+		postgres.WithDatabase(postgresDatabase),
+		postgres.WithUsername(postgresUsername),
+		postgres.WithPassword(postgresPassword),
+		postgres.BasicWaitStrategies(),
+	)
+	if err != nil {
+		t.Fatalf("could not start postgres container: %v\n", err)
+	}
+
+	t.Cleanup(func() {
+		if err := container.Terminate(context.Background()); err != nil {
+			t.Errorf("could not terminate postgres container: %v\n", err)
+		}
+	})
+
+	host, err := container.Host(ctx)
+	if err != nil {
+		t.Fatalf("could not get postgres host: %v\n", err)
+	}
+
+	port, err := container.MappedPort(ctx, "5432/tcp")
+	if err != nil {
+		t.Fatalf("could not get postgres port: %v\n", err)
+	}
+
+	// The container does not support SSL.
+	dsn, err := container.ConnectionString(ctx, "sslmode=disable")
+	if err != nil {
+		t.Fatalf("could not get postgres connection string: %v\n", err)
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("could not open postgres connection: %v\n", err)
+	}
+
+	t.Cleanup(func() { db.Close() })
+
+	return &testEnv{
+		conf: &dbmigrator.Config{
+			Host:               host,
+			Port:               port.Int(),
+			Database:           postgresDatabase,
+			Username:           postgresUsername,
+			Password:           postgresPassword,
+			DriverName:         "postgres",
+			DSNOptions:         map[string]string{"sslmode": "disable"},
+			MigrationsFilePath: writeMigrationsFile(t),
+		},
+		db: db,
+		columnQuery: `
+			SELECT
+				column_name
+			FROM information_schema.columns
+			WHERE table_schema = current_schema()
+			AND table_name = 'foo'
+			AND column_name = $1
+		`,
+	}
+}
+
+func TestPostgresSetupDatabase(t *testing.T) { testSetupDatabase(t, startPostgres(t)) }
+
+func TestPostgresProcess(t *testing.T) { testProcess(t, startPostgres(t)) }
+
+func TestPostgresProcessWithDirection(t *testing.T) { testProcessWithDirection(t, startPostgres(t)) }
+
+func TestPostgresProcessWithTargetVersion(t *testing.T) {
+	testProcessWithTargetVersion(t, startPostgres(t))
+}
+
+func TestPostgresGetHistory(t *testing.T) { testGetHistory(t, startPostgres(t)) }
+
+func TestPostgresRollbackLast(t *testing.T) { testRollbackLast(t, startPostgres(t)) }
+
+func TestPostgresRollbackAll(t *testing.T) { testRollbackAll(t, startPostgres(t)) }
+
+func TestPostgresRecreateMigrationsTable(t *testing.T) {
+	testRecreateMigrationsTable(t, startPostgres(t))
+}
+
+func TestPostgresAdvisoryLock(t *testing.T) {
+	env := startPostgres(t)
+
+	newLocker := func() database.Locker {
+		db, err := database.New(context.Background(), database.DatabaseConfig{
+			Driver:     env.conf.DriverName,
+			Host:       env.conf.Host,
+			Port:       env.conf.Port,
+			Database:   env.conf.Database,
+			Username:   env.conf.Username,
+			Password:   env.conf.Password,
+			DSNOptions: env.conf.DSNOptions,
+		})
+		if err != nil {
+			t.Fatalf("could not connect: %v\n", err)
+		}
+
+		t.Cleanup(db.Close)
+
+		return db.(database.Locker)
+	}
+
+	first, second := newLocker(), newLocker()
+
+	if err := first.Lock("foo", time.Second); err != nil {
+		t.Fatalf("expected error: <nil>; got error: %v\n", err)
+	}
+
+	timeout := 200 * time.Millisecond
+	start := time.Now()
+
+	if err := second.Lock("foo", timeout); !errors.Is(err, database.ErrLockNotAcquired) {
+		t.Fatalf("expected error: %v; got error: %v\n", database.ErrLockNotAcquired, err)
+	}
+
+	// The lock must be waited for, instead of giving up right away.
+	if elapsed := time.Since(start); elapsed < timeout {
+		t.Errorf("expected to wait at least %v; waited: %v\n", timeout, elapsed)
+	}
+
+	// Releasing the lock in the background makes the waiting attempt succeed.
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		first.Unlock("foo")
+	}()
+
+	if err := second.Lock("foo", 5*time.Second); err != nil {
+		t.Fatalf("expected error: <nil>; got error: %v\n", err)
+	}
+
+	if err := second.Unlock("foo"); err != nil {
+		t.Errorf("expected error: <nil>; got error: %v\n", err)
+	}
+}
@@ -0,0 +1,75 @@
+package dbmigrator
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/balazskvancz/dbmigrator/database"
+)
+
+// WithMigrationTimeout bounds the whole run of the commands selected by
+// the process: once the given duration is exceeded, the command being
+// executed is cancelled, no more commands are executed, the transaction
+// – if any – is rolled back, and the error of the context,
+// context.DeadlineExceeded is returned.
+func WithMigrationTimeout(d time.Duration) EngineOptFunc {
+	return func(e *engine) {
+		e.migrationTimeout = d
+	}
+}
+
+// contextDatabase is a database, which executes the statements
+// bound to its context, so they are cancelled, once it is done.
+type contextDatabase struct {
+	ctx context.Context
+
+	database.Database
+}
+
+// Exec executes the given command bound to the context,
+// if the context is not done yet.
+func (cd *contextDatabase) Exec(query string, values ...any) (sql.Result, error) {
+	if err := cd.ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return cd.Database.ExecContext(cd.ctx, query, values...)
+}
+
+// ExecMulti works like database.Database.ExecMulti, but the
+// context is checked before executing each of the queries.
+func (cd *contextDatabase) ExecMulti(queries []string) error {
+	for i, query := range queries {
+		if _, err := cd.Exec(query); err != nil {
+			return &database.ExecMultiError{Index: i, Err: err}
+		}
+	}
+
+	return nil
+}
+
+// withContextDatabase returns the copies of the given commands,
+// which run against the database of the engine bound to the context.
+// The copies share the same database, so they can be still batched.
+func (e *engine) withContextDatabase(ctx context.Context, commands []Command) []Command {
+	var (
+		db     = &contextDatabase{ctx: ctx, Database: e.db}
+		copies = make([]Command, len(commands))
+	)
+
+	for i, c := range commands {
+		copies[i] = c.Clone(db)
+	}
+
+	return copies
+}
+
+// isAbortingError returns whether the given error makes
+// neither retrying, nor continuing the commands sensible.
+func isAbortingError(err error) bool {
+	return errors.Is(err, ErrTransactionLostOnReconnect) ||
+		errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, context.Canceled)
+}
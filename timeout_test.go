@@ -0,0 +1,86 @@
+package dbmigrator
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/balazskvancz/dbmigrator/database"
+)
+
+// mockSlowDatabase is a database, whose statements take the given
+// delay, unless their context is done sooner.
+type mockSlowDatabase struct {
+	delay     time.Duration
+	execs     int
+	cancelled int
+
+	database.Database
+}
+
+func (md *mockSlowDatabase) ExecContext(ctx context.Context, _ string, _ ...any) (sql.Result, error) {
+	md.execs++
+
+	select {
+	case <-time.After(md.delay):
+		return nil, nil
+	case <-ctx.Done():
+		md.cancelled++
+
+		return nil, ctx.Err()
+	}
+}
+
+func TestWithMigrationTimeout(t *testing.T) {
+	db := &mockSlowDatabase{delay: time.Second}
+
+	e := &engine{
+		conf:             &Config{},
+		db:               db,
+		dir:              DirectionUp,
+		migrationTimeout: 10 * time.Millisecond,
+		repositories:     newMockRepo(true, nil),
+	}
+
+	start := time.Now()
+
+	err := e.ApplyMigrations("1.0.0", []string{"CREATE TABLE foo (id INT);", "CREATE TABLE bar (id INT);"}, nil)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected error: %v; got error: %v\n", context.DeadlineExceeded, err)
+	}
+
+	if db.execs != 1 {
+		t.Errorf("expected executed commands: 1; got: %d\n", db.execs)
+	}
+
+	// The command in progress must be cancelled, instead of waiting for it.
+	if db.cancelled != 1 {
+		t.Errorf("expected cancelled commands: 1; got: %d\n", db.cancelled)
+	}
+
+	if elapsed := time.Since(start); elapsed >= db.delay {
+		t.Errorf("expected the run to stop before the command finishes; took: %v\n", elapsed)
+	}
+}
+
+func TestContextDatabase(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	db := &contextDatabase{ctx: ctx, Database: &mockDatabase{}}
+
+	if err := db.ExecMulti([]string{"SELECT 1"}); err != nil {
+		t.Fatalf("expected error: <nil>; got error: %v\n", err)
+	}
+
+	cancel()
+
+	err := db.ExecMulti([]string{"SELECT 1"})
+
+	var multiErr *database.ExecMultiError
+
+	if !errors.As(err, &multiErr) || !errors.Is(err, context.Canceled) {
+		t.Errorf("expected error: %v; got error: %v\n", context.Canceled, err)
+	}
+}
@@ -0,0 +1,42 @@
+package dbmigrator
+
+// GetUpgradePath returns the versions of the migration file, which would be
+// traversed to get from the given version to the other one, in execution order.
+// Upgrading traverses the versions greater than from and at most to in ascending
+// order, downgrading the versions at most from and greater than to in descending
+// order. It does not query the database.
+func (e *engine) GetUpgradePath(from, to string) ([]Semver, error) {
+	fromVersion := newSemver(from)
+	toVersion := newSemver(to)
+
+	if fromVersion == nil || toVersion == nil {
+		return nil, ErrBadVersioning
+	}
+
+	commands, err := e.getCommands()
+	if err != nil {
+		return nil, err
+	}
+
+	return getUpgradePath(commands, fromVersion, toVersion), nil
+}
+
+// getUpgradePath returns the versions of the commands
+// traversed from the given version to the other one.
+func getUpgradePath(commands []Command, from, to Semver) []Semver {
+	if !from.GreaterThan(to) {
+		return getUniqueVersions(commands, func(sv Semver) bool {
+			return sv.GreaterThan(from) && !sv.GreaterThan(to)
+		})
+	}
+
+	versions := getUniqueVersions(commands, func(sv Semver) bool {
+		return !sv.GreaterThan(from) && sv.GreaterThan(to)
+	})
+
+	for i, j := 0, len(versions)-1; i < j; i, j = i+1, j-1 {
+		versions[i], versions[j] = versions[j], versions[i]
+	}
+
+	return versions
+}
@@ -0,0 +1,67 @@
+package dbmigrator
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestGetUpgradePath(t *testing.T) {
+	commands := []Command{
+		mustNewCommand(nil, "", newSemver("1.0.0"), DirectionUp),
+		mustNewCommand(nil, "", newSemver("1.0.0"), DirectionDown),
+		mustNewCommand(nil, "", newSemver("1.1.0"), DirectionUp),
+		mustNewCommand(nil, "", newSemver("1.2.0"), DirectionUp),
+		mustNewCommand(nil, "", newSemver("2.0.0"), DirectionUp),
+	}
+
+	type testCase struct {
+		name     string
+		from     Semver
+		to       Semver
+		expected []string
+	}
+
+	tt := []testCase{
+		{
+			name:     "returns the versions in ascending order in case of upgrade",
+			from:     newSemver("1.0.0"),
+			to:       newSemver("1.2.0"),
+			expected: []string{"1.1.0", "1.2.0"},
+		},
+		{
+			name:     "returns the versions in descending order in case of downgrade",
+			from:     newSemver("2.0.0"),
+			to:       newSemver("1.0.0"),
+			expected: []string{"2.0.0", "1.2.0", "1.1.0"},
+		},
+		{
+			name:     "returns empty path in case of equal versions",
+			from:     newSemver("1.1.0"),
+			to:       newSemver("1.1.0"),
+			expected: []string{},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			got := make([]string, 0)
+
+			for _, sv := range getUpgradePath(commands, tc.from, tc.to) {
+				got = append(got, sv.ToString())
+			}
+
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected path: %v; got: %v\n", tc.expected, got)
+			}
+		})
+	}
+}
+
+func TestGetUpgradePathInvalidVersion(t *testing.T) {
+	e := &engine{}
+
+	if _, err := e.GetUpgradePath("foo", "1.0.0"); !errors.Is(err, ErrBadVersioning) {
+		t.Errorf("expected error: %v; got error: %v\n", ErrBadVersioning, err)
+	}
+}
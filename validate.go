@@ -0,0 +1,155 @@
+package dbmigrator
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The privileges needed to create and fill the migrations table.
+var requiredPrivileges = []string{"CREATE", "INSERT"}
+
+// PrivilegeError is returned by ValidateConnection, if the
+// user of the connection lacks some of the needed privileges.
+type PrivilegeError struct {
+	Missing []string
+}
+
+func (e *PrivilegeError) Error() string {
+	return fmt.Sprintf("insufficient privileges: missing %s", strings.Join(e.Missing, ", "))
+}
+
+// ValidateConnection checks, that the database is reachable, the credentials
+// are valid and the user has the privileges needed to run the migrations.
+// It returns *PrivilegeError in case of insufficient privileges. The privilege
+// check is only supported for mysql and postgres.
+func (e *engine) ValidateConnection() error {
+	if err := e.db.Ping(); err != nil {
+		return fmt.Errorf("database is not reachable: %w", err)
+	}
+
+	var one int
+
+	if err := e.db.QueryRow("SELECT 1").Scan(&one); err != nil {
+		return fmt.Errorf("could not query the database: %w", err)
+	}
+
+	var (
+		missing []string
+		err     error
+	)
+
+	switch e.db.GetDriverName() {
+	case "mysql":
+		missing, err = e.getMissingMySQLPrivileges()
+	case "postgres", "pgx":
+		missing, err = e.getMissingPostgresPrivileges()
+	default:
+		return ErrUnsupportedDriver
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if len(missing) > 0 {
+		return &PrivilegeError{Missing: missing}
+	}
+
+	return nil
+}
+
+// getMissingMySQLPrivileges returns the needed privileges,
+// which are not granted to the current user.
+func (e *engine) getMissingMySQLPrivileges() ([]string, error) {
+	grants, err := queryStrings(e.db, "SHOW GRANTS FOR CURRENT_USER()")
+	if err != nil {
+		return nil, err
+	}
+
+	return getMissingGrants(grants, e.db.GetDatabaseName(), e.repositories.Migrations.GetTableName()), nil
+}
+
+// getMissingPostgresPrivileges returns the needed privileges,
+// which are not granted to the current user.
+func (e *engine) getMissingPostgresPrivileges() ([]string, error) {
+	missing := make([]string, 0)
+
+	var canCreate bool
+
+	if err := e.db.QueryRow("SELECT has_schema_privilege(current_schema(), 'CREATE')").Scan(&canCreate); err != nil {
+		return nil, err
+	}
+
+	if !canCreate {
+		missing = append(missing, "CREATE")
+	}
+
+	// Without the table, the user is going to be its owner.
+	if !e.repositories.Migrations.DoesExists() {
+		return missing, nil
+	}
+
+	var canInsert bool
+
+	if err := e.db.QueryRow("SELECT has_table_privilege($1, 'INSERT')", e.repositories.Migrations.GetTableName()).Scan(&canInsert); err != nil {
+		return nil, err
+	}
+
+	if !canInsert {
+		missing = append(missing, "INSERT")
+	}
+
+	return missing, nil
+}
+
+// getMissingGrants returns the required privileges, which are not given by
+// the mysql grants, eg. "GRANT SELECT, INSERT ON `db`.* TO `user`@`%`".
+// Only the grants on every database, the given database or the
+// given table are taken into account.
+func getMissingGrants(grants []string, database, table string) []string {
+	granted := make(map[string]bool)
+
+	if idx := strings.LastIndex(table, "."); idx != -1 {
+		table = table[idx+1:]
+	}
+
+	scopes := map[string]bool{
+		"*.*":                  true,
+		database + ".*":        true,
+		database + "." + table: true,
+	}
+
+	for _, grant := range grants {
+		grant = strings.TrimPrefix(strings.TrimSpace(grant), "GRANT ")
+
+		privileges, rest, ok := strings.Cut(grant, " ON ")
+		if !ok {
+			// Eg. a granted role.
+			continue
+		}
+
+		scope, _, _ := strings.Cut(rest, " TO ")
+
+		if !scopes[strings.ReplaceAll(strings.TrimSpace(scope), "`", "")] {
+			continue
+		}
+
+		for _, p := range strings.Split(privileges, ",") {
+			granted[strings.ToUpper(strings.TrimSpace(p))] = true
+		}
+	}
+
+	missing := make([]string, 0)
+
+	if granted["ALL"] || granted["ALL PRIVILEGES"] {
+		return missing
+	}
+
+	for _, p := range requiredPrivileges {
+		if !granted[p] {
+			missing = append(missing, p)
+		}
+	}
+
+	return missing
+}
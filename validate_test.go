@@ -0,0 +1,68 @@
+package dbmigrator
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGetMissingGrants(t *testing.T) {
+	type testCase struct {
+		name     string
+		grants   []string
+		expected []string
+	}
+
+	tt := []testCase{
+		{
+			name:     "returns every privilege in case of no grants",
+			grants:   []string{"GRANT USAGE ON *.* TO `user`@`%`"},
+			expected: []string{"CREATE", "INSERT"},
+		},
+		{
+			name:     "returns no privilege in case of all privileges",
+			grants:   []string{"GRANT ALL PRIVILEGES ON *.* TO `user`@`%`"},
+			expected: []string{},
+		},
+		{
+			name: "returns no privilege in case of grants on the database",
+			grants: []string{
+				"GRANT USAGE ON *.* TO `user`@`%`",
+				"GRANT SELECT, INSERT, CREATE ON `db`.* TO `user`@`%`",
+			},
+			expected: []string{},
+		},
+		{
+			name: "returns the missing privilege",
+			grants: []string{
+				"GRANT CREATE ON `db`.* TO `user`@`%`",
+				"GRANT INSERT ON `other`.* TO `user`@`%`",
+			},
+			expected: []string{"INSERT"},
+		},
+		{
+			name: "takes grants on the migrations table into account",
+			grants: []string{
+				"GRANT CREATE ON `db`.* TO `user`@`%`",
+				"GRANT INSERT ON `db`.`__migrations__` TO `user`@`%`",
+			},
+			expected: []string{},
+		},
+		{
+			name: "skips granted roles",
+			grants: []string{
+				"GRANT `admin`@`%` TO `user`@`%`",
+			},
+			expected: []string{"CREATE", "INSERT"},
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			got := getMissingGrants(tc.grants, "db", "__migrations__")
+
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("expected missing: %v; got: %v\n", tc.expected, got)
+			}
+		})
+	}
+}
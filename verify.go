@@ -0,0 +1,51 @@
+package dbmigrator
+
+import "fmt"
+
+// VersionMismatchError is returned by Verify, if the version stored in
+// the database differs from the latest version of the migration file.
+type VersionMismatchError struct {
+	DB   Semver
+	File Semver
+}
+
+func (e *VersionMismatchError) Error() string {
+	return fmt.Sprintf("version mismatch: database is at %s, file is at %s", e.DB.ToString(), e.File.ToString())
+}
+
+// Verify makes sure, that the database is at the latest version of the
+// migration file, and returns *VersionMismatchError if it is not.
+func (e *engine) Verify() error {
+	dbVersion, err := e.GetCurrentVersion()
+	if err != nil {
+		return err
+	}
+
+	fileVersion, err := e.GetFileVersion()
+	if err != nil {
+		return err
+	}
+
+	return compareVersions(dbVersion, fileVersion)
+}
+
+// compareVersions returns *VersionMismatchError, if the given versions
+// differ. A missing version is treated as the bottom version.
+func compareVersions(dbVersion, fileVersion Semver) error {
+	if dbVersion == nil {
+		dbVersion = bottomVersion
+	}
+
+	if fileVersion == nil {
+		fileVersion = bottomVersion
+	}
+
+	if !dbVersion.Equals(fileVersion) {
+		return &VersionMismatchError{
+			DB:   dbVersion,
+			File: fileVersion,
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,54 @@
+package dbmigrator
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCompareVersions(t *testing.T) {
+	type testCase struct {
+		name        string
+		dbVersion   Semver
+		fileVersion Semver
+		isMismatch  bool
+	}
+
+	tt := []testCase{
+		{
+			name:        "returns <nil>, if both versions are missing",
+			dbVersion:   nil,
+			fileVersion: nil,
+			isMismatch:  false,
+		},
+		{
+			name:        "returns <nil>, if the versions are equal",
+			dbVersion:   newSemver("1.2.0"),
+			fileVersion: newSemver("1.2"),
+			isMismatch:  false,
+		},
+		{
+			name:        "returns error, if the database has no history",
+			dbVersion:   nil,
+			fileVersion: newSemver("1.2.0"),
+			isMismatch:  true,
+		},
+		{
+			name:        "returns error, if the versions differ",
+			dbVersion:   newSemver("1.1.0"),
+			fileVersion: newSemver("1.2.0"),
+			isMismatch:  true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			err := compareVersions(tc.dbVersion, tc.fileVersion)
+
+			var mismatchErr *VersionMismatchError
+
+			if got := errors.As(err, &mismatchErr); got != tc.isMismatch {
+				t.Errorf("expected mismatch: %t; got error: %v\n", tc.isMismatch, err)
+			}
+		})
+	}
+}
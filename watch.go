@@ -0,0 +1,71 @@
+package dbmigrator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+var (
+	ErrInvalidPollInterval error = errors.New("poll interval must be greater than zero")
+	ErrWatchNotSupported   error = errors.New("watching is only supported for local migration files")
+)
+
+// WatchAndMigrate runs Migrate at start, then every time the modification
+// time of the migration file changes, checked in every pollInterval.
+// The errors of the runs are logged, but do not stop the watcher.
+// It blocks until the given context is cancelled, and returns its error.
+// Files on S3 have no modification time to poll, so they are rejected
+// with ErrWatchNotSupported.
+func (e *engine) WatchAndMigrate(ctx context.Context, pollInterval time.Duration) error {
+	if e.conf.MigrationsFilePath == "" {
+		return ErrNoFilePath
+	}
+
+	if pollInterval <= 0 {
+		return ErrInvalidPollInterval
+	}
+
+	if isS3Path(e.conf.MigrationsFilePath) {
+		return ErrWatchNotSupported
+	}
+
+	var lastModTime time.Time
+
+	check := func() {
+		stat, err := os.Stat(e.conf.MigrationsFilePath)
+		if err != nil {
+			e.Error(fmt.Sprintf("could not stat the migration file: %v", err))
+
+			return
+		}
+
+		if stat.ModTime().Equal(lastModTime) {
+			return
+		}
+
+		lastModTime = stat.ModTime()
+
+		e.Info("-- migration file changed, processing --")
+
+		if err := e.Migrate(ctx); err != nil && !errors.Is(err, ErrNothingToRun) {
+			e.Error(fmt.Sprintf("processing error: %v", err))
+		}
+	}
+
+	check()
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			check()
+		}
+	}
+}
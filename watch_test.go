@@ -0,0 +1,116 @@
+package dbmigrator
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatchAndMigrateValidation(t *testing.T) {
+	type testCase struct {
+		name          string
+		path          string
+		pollInterval  time.Duration
+		expectedError error
+	}
+
+	tt := []testCase{
+		{
+			name:          "returns error without file path",
+			path:          "",
+			pollInterval:  time.Second,
+			expectedError: ErrNoFilePath,
+		},
+		{
+			name:          "returns error in case of zero poll interval",
+			path:          "migrations.sql",
+			pollInterval:  0,
+			expectedError: ErrInvalidPollInterval,
+		},
+		{
+			name:          "returns error in case of negative poll interval",
+			path:          "migrations.sql",
+			pollInterval:  -time.Second,
+			expectedError: ErrInvalidPollInterval,
+		},
+		{
+			name:          "returns error in case of s3 path",
+			path:          "s3://bucket/migrations.sql",
+			pollInterval:  time.Second,
+			expectedError: ErrWatchNotSupported,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			e := &engine{conf: &Config{MigrationsFilePath: tc.path}}
+
+			if err := e.WatchAndMigrate(context.Background(), tc.pollInterval); !errors.Is(err, tc.expectedError) {
+				t.Errorf("expected error: %v; got error: %v\n", tc.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestWatchAndMigrate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "migrations.sql")
+	if err := os.WriteFile(path, []byte("#v1.0.0\n#[UP]\nCREATE TABLE foo (id INT);\n"), 0o644); err != nil {
+		t.Fatalf("could not write the migration file: %v\n", err)
+	}
+
+	runs := make(chan struct{}, 10)
+
+	e := &engine{conf: &Config{MigrationsFilePath: path}}
+
+	// The failing hook stops every run right away, and
+	// its error must not stop the watcher either.
+	WithBeforeProcess(func() error {
+		runs <- struct{}{}
+
+		return errors.New("mock-error")
+	})(e)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	done := make(chan error)
+
+	go func() { done <- e.WatchAndMigrate(ctx, 10*time.Millisecond) }()
+
+	waitForRun := func() {
+		t.Helper()
+
+		select {
+		case <-runs:
+		case <-time.After(time.Second):
+			t.Fatalf("expected a run of the migrations\n")
+		}
+	}
+
+	// The first run happens at start.
+	waitForRun()
+
+	// Without changes, there must be no more runs.
+	select {
+	case <-runs:
+		t.Fatalf("expected no run without changes\n")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	modTime := time.Now().Add(time.Hour)
+
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatalf("could not change the modification time: %v\n", err)
+	}
+
+	waitForRun()
+
+	cancel()
+
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("expected error: %v; got error: %v\n", context.Canceled, err)
+	}
+}
@@ -0,0 +1,106 @@
+package dbmigrator
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// yamlVersion is a single version of the YAML migration file.
+type yamlVersion struct {
+	Version     string   `yaml:"version"`
+	Description string   `yaml:"description"`
+	Parallel    bool     `yaml:"parallel"`
+	Tags        []string `yaml:"tags"`
+	Up          []string `yaml:"up"`
+	Down        []string `yaml:"down"`
+}
+
+// isYAMLFile returns whether the given migration file is in YAML format.
+func isYAMLFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+
+	return ext == ".yaml" || ext == ".yml"
+}
+
+// ParseYAMLLines creates the commands based upon the YAML migration file,
+// which is a list of versions, each with its up and down statements.
+// The result is the same as the one of ParseLines for the equivalent file.
+func (e *engine) ParseYAMLLines(content []byte) ([]Command, error) {
+	var root yaml.Node
+
+	if err := yaml.Unmarshal(content, &root); err != nil {
+		return nil, err
+	}
+
+	commands := make([]Command, 0)
+
+	// Empty document.
+	if len(root.Content) == 0 {
+		return commands, nil
+	}
+
+	list := root.Content[0]
+	if list.Kind != yaml.SequenceNode {
+		return nil, newParseError(list.Line, list.Column, "the migration file must be a list of versions", ErrBadVersioning)
+	}
+
+	for _, node := range list.Content {
+		var v yamlVersion
+
+		if err := node.Decode(&v); err != nil {
+			return nil, newParseError(node.Line, node.Column, err.Error(), err)
+		}
+
+		sv := newSemver(v.Version)
+		if sv == nil {
+			return nil, newParseError(node.Line, node.Column, fmt.Sprintf("bad version `%s`", v.Version), ErrBadVersioning)
+		}
+
+		if e.versionValidator != nil {
+			if err := e.versionValidator(sv); err != nil {
+				return nil, newParseError(node.Line, node.Column, fmt.Sprintf("invalid version `%s`: %v", v.Version, err), err)
+			}
+		}
+
+		for _, stmts := range []struct {
+			dir     direction
+			queries []string
+		}{
+			{dir: DirectionUp, queries: v.Up},
+			{dir: DirectionDown, queries: v.Down},
+		} {
+			for _, query := range stmts.queries {
+				query = strings.TrimSpace(query)
+				if query == "" {
+					continue
+				}
+
+				cmd, err := newCommand(e.db, query, sv, stmts.dir)
+				if err != nil {
+					return nil, newParseError(node.Line, node.Column, err.Error(), err)
+				}
+
+				if c, ok := cmd.(*command); ok {
+					c.description = strings.TrimSpace(v.Description)
+					c.parallel = v.Parallel
+					c.tags = v.Tags
+				}
+
+				commands = append(commands, cmd)
+			}
+		}
+	}
+
+	commands = e.filterByTags(commands)
+
+	if e.strictMode {
+		if err := checkDownMigrations(commands); err != nil {
+			return nil, err
+		}
+	}
+
+	return commands, nil
+}
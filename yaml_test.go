@@ -0,0 +1,91 @@
+package dbmigrator
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParseYAMLLines(t *testing.T) {
+	type testCase struct {
+		name    string
+		content string
+
+		expectedError error
+	}
+
+	tt := []testCase{
+		{
+			name: "returns error in case of bad version",
+			content: `
+- version: foo
+  up:
+    - CREATE TABLE foo (id INT);
+`,
+			expectedError: ErrBadVersioning,
+		},
+		{
+			name:          "returns error, if the file is not a list",
+			content:       `version: "1.0.0"`,
+			expectedError: ErrBadVersioning,
+		},
+		{
+			name:          "returns no error in case of empty file",
+			content:       "",
+			expectedError: nil,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			e := &engine{}
+
+			if _, err := e.ParseYAMLLines([]byte(tc.content)); !errors.Is(err, tc.expectedError) {
+				t.Errorf("expected error: %v; got error: %v\n", tc.expectedError, err)
+			}
+		})
+	}
+}
+
+func TestParseYAMLLinesEquivalence(t *testing.T) {
+	yamlContent := `
+- version: "1.0.0"
+  description: creation of foo
+  up:
+    - CREATE TABLE foo (id INT);
+  down:
+    - DROP TABLE foo;
+- version: "1.1.0"
+  parallel: true
+  up:
+    - ALTER TABLE foo ADD COLUMN bar INT;
+`
+
+	lines := MigrationLines{
+		"#v1.0.0",
+		"#[DESC] creation of foo",
+		"#[UP]",
+		"CREATE TABLE foo (id INT);",
+		"#[DOWN]",
+		"DROP TABLE foo;",
+		"#v1.1.0",
+		"#[PARALLEL]",
+		"ALTER TABLE foo ADD COLUMN bar INT;",
+	}
+
+	e := &engine{}
+
+	fromYAML, err := e.ParseYAMLLines([]byte(yamlContent))
+	if err != nil {
+		t.Fatalf("expected error: <nil>; got error: %v\n", err)
+	}
+
+	fromLines, err := e.ParseLines(lines)
+	if err != nil {
+		t.Fatalf("expected error: <nil>; got error: %v\n", err)
+	}
+
+	if !reflect.DeepEqual(fromYAML, fromLines) {
+		t.Errorf("expected commands: %v; got: %v\n", fromLines, fromYAML)
+	}
+}